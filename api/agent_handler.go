@@ -0,0 +1,191 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type AgentHandler struct {
+	Service         *services.AgentService
+	PropertyService *services.PropertyService
+	Validator       *validator.Validate
+}
+
+func NewAgentHandler(service *services.AgentService, propertyService *services.PropertyService) *AgentHandler {
+	return &AgentHandler{
+		Service:         service,
+		PropertyService: propertyService,
+		Validator:       validator.New(),
+	}
+}
+
+// CreateAgent handles POST /agents
+func (h *AgentHandler) CreateAgent(c *fiber.Ctx) error {
+	var req schema.AgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	agent, err := h.Service.WithContext(c.UserContext()).CreateAgent(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(agent)
+}
+
+// GetAgents handles GET /agents
+func (h *AgentHandler) GetAgents(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.AgentFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	agents, totalItems, err := h.Service.WithContext(c.UserContext()).GetAgents(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(agents, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetAgentByID handles GET /agents/:id
+func (h *AgentHandler) GetAgentByID(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "agent")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	agent, err := h.Service.WithContext(c.UserContext()).GetAgentByID(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(agent)
+}
+
+// UpdateAgent handles PUT /agents/:id
+func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "agent")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.AgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	// Without agents:manage only the user's own profile can be edited
+	if user := currentUser(c); user != nil && !services.HasPermission(user.Role, services.PermManageAgents) {
+		if agentID := currentAgentID(c); agentID == nil || *agentID != id {
+			return utils.HandleError(c, forbidden("You can only edit your own agent profile"))
+		}
+		if req.UserID != user.ID {
+			return utils.HandleError(c, forbidden("Your agent profile must stay linked to your account"))
+		}
+		// The agreement and the agent type are set by agent managers
+		if req.IsAgreementSigned != nil {
+			return utils.HandleError(c, forbidden("Only agent managers can change the agreement"))
+		}
+		current, err := h.Service.WithContext(c.UserContext()).GetAgentByID(id)
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		if !strings.EqualFold(strings.TrimSpace(req.AgentType), current.AgentType) {
+			return utils.HandleError(c, forbidden("Only agent managers can change the agent type"))
+		}
+		req.AgentType = current.AgentType
+	}
+
+	agent, err := h.Service.WithContext(c.UserContext()).UpdateAgent(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(agent)
+}
+
+// GetAgentProperties handles GET /agents/:id/properties. It is the property listing limited to
+// the agent, so the listing's filters, sorting and fields all work here too.
+func (h *AgentHandler) GetAgentProperties(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "agent")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	if _, err := h.Service.WithContext(c.UserContext()).GetAgentByID(id); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.PropertyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+	filterParams.AgentID = &id
+
+	properties, totalItems, err := h.PropertyService.WithContext(c.UserContext()).GetAllProperties(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	propertyResponses := services.MapPropertiesToResponse(properties)
+	services.AttachDistances(propertyResponses, filterParams)
+	data, err := trimPropertyResponses(propertyResponses, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(data, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetAgentStats handles GET /agents/:id/stats
+func (h *AgentHandler) GetAgentStats(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "agent")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	stats, err := h.Service.WithContext(c.UserContext()).GetAgentStats(id, from, to)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(stats)
+}
+
+// GetAgentLeaderboard handles GET /agents/leaderboard
+func (h *AgentHandler) GetAgentLeaderboard(c *fiber.Ctx) error {
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	leaderboard, err := h.Service.WithContext(c.UserContext()).GetAgentLeaderboard(c.Query("sortBy"), from, to, c.Query("agentType"), c.QueryInt("limit", services.DefaultAgentLeaderboardLimit))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(leaderboard)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type APIKeyHandler struct {
+	Service   *services.APIKeyService
+	Validator *validator.Validate
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+// CreateAPIKey handles POST /api-keys. The response is the only place the key is shown.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	user := currentUser(c)
+	if user == nil {
+		return utils.HandleError(c, forbidden("API keys cannot issue other API keys"))
+	}
+
+	var req schema.APIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	key, err := h.Service.CreateAPIKey(&req, user.ID)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+// GetAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.APIKeyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	keys, totalItems, err := h.Service.GetAPIKeys(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(keys, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetAPIKeyByID handles GET /api-keys/:id
+func (h *APIKeyHandler) GetAPIKeyByID(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "API key")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	key, err := h.Service.GetAPIKeyByID(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(key)
+}
+
+// UpdateAPIKey handles PUT /api-keys/:id
+func (h *APIKeyHandler) UpdateAPIKey(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "API key")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.APIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	key, err := h.Service.UpdateAPIKey(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(key)
+}
+
+// RevokeAPIKey handles POST /api-keys/:id/revoke
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "API key")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	key, err := h.Service.RevokeAPIKey(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(key)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type AuthHandler struct {
+	Service   *services.AuthService
+	Validator *validator.Validate
+}
+
+func NewAuthHandler(service *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req schema.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	tokens, err := h.Service.Login(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// Refresh handles POST /auth/refresh
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req schema.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	tokens, err := h.Service.Refresh(req.RefreshToken)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// Me handles GET /auth/me
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	user := currentUser(c)
+	if user == nil {
+		return utils.HandleError(c, forbidden("API keys are not linked to a user"))
+	}
+	return c.JSON(schema.UserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Phone:           user.Phone,
+		Role:            user.Role,
+		ProfileImageURL: user.ProfileImageStorageURL,
+	})
+}
+
+// ChangePassword handles POST /auth/password. Every other session of the user ends.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	user := currentUser(c)
+	if user == nil {
+		return utils.HandleError(c, forbidden("API keys are not linked to a user"))
+	}
+
+	var req schema.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	tokens, err := h.Service.ChangePassword(user, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// SetUserPassword handles PUT /users/:id/password
+func (h *AuthHandler) SetUserPassword(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "user")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.SetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	if err := h.Service.SetUserPassword(id, req.Password); err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type AVMHandler struct {
+	Service   *services.AVMService
+	Validator *validator.Validate
+}
+
+func NewAVMHandler(service *services.AVMService) *AVMHandler {
+	return &AVMHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+// TrainModel handles POST /avm/train
+func (h *AVMHandler) TrainModel(c *fiber.Ctx) error {
+	summary, err := h.Service.Train()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(summary)
+}
+
+// GetModels handles GET /avm/models
+func (h *AVMHandler) GetModels(c *fiber.Ctx) error {
+	models, err := h.Service.ListModels()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(models)
+}
+
+// Estimate handles POST /avm/estimate
+func (h *AVMHandler) Estimate(c *fiber.Ctx) error {
+	var req schema.AVMEstimateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err.(validator.ValidationErrors))
+	}
+
+	estimate, err := h.Service.WithContext(c.UserContext()).Estimate(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(estimate)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type ComparableHandler struct {
+	Service *services.ComparableService
+}
+
+func NewComparableHandler(service *services.ComparableService) *ComparableHandler {
+	return &ComparableHandler{Service: service}
+}
+
+// GetComparables handles GET /properties/:id/comparables
+func (h *ComparableHandler) GetComparables(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid property ID format"))
+	}
+
+	limit := c.QueryInt("limit", services.DefaultComparablesLimit)
+	radiusKm := c.QueryFloat("radiusKm", 0) // 0 falls back to the configured radius
+	if radiusKm < 0 {
+		return utils.HandleError(c, utils.NewBadRequestError("radiusKm must be positive"))
+	}
+
+	matches, err := h.Service.WithContext(c.UserContext()).FindComparables(uint(id), limit, radiusKm)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(services.MapComparablesToResponse(uint(id), matches))
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type DuplicateHandler struct {
+	Service   *services.DuplicateService
+	Validator *validator.Validate
+}
+
+func NewDuplicateHandler(service *services.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+func parseCandidateID(c *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, utils.NewBadRequestError("Invalid duplicate candidate ID format")
+	}
+	return uint(id), nil
+}
+
+// Scan handles POST /duplicates/scan
+func (h *DuplicateHandler) Scan(c *fiber.Ctx) error {
+	result, err := h.Service.WithContext(c.UserContext()).Scan()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// GetCandidates handles GET /duplicates
+func (h *DuplicateHandler) GetCandidates(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.DuplicateCandidateFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	candidates, totalItems, err := h.Service.WithContext(c.UserContext()).GetCandidates(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(candidates, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// ReviewCandidate handles PATCH /duplicates/:id
+func (h *DuplicateHandler) ReviewCandidate(c *fiber.Ctx) error {
+	id, err := parseCandidateID(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.DuplicateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err.(validator.ValidationErrors))
+	}
+
+	candidate, err := h.Service.WithContext(c.UserContext()).ReviewCandidate(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(candidate)
+}
+
+// MergeCandidate handles POST /duplicates/:id/merge
+func (h *DuplicateHandler) MergeCandidate(c *fiber.Ctx) error {
+	id, err := parseCandidateID(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	// The body is optional, without one the older property is kept
+	var req schema.DuplicateMergeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+		}
+	}
+
+	result, err := h.Service.WithContext(c.UserContext()).MergeCandidate(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
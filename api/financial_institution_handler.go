@@ -0,0 +1,104 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type FinancialInstitutionHandler struct {
+	Service   *services.FinancialInstitutionService
+	Validator *validator.Validate
+}
+
+func NewFinancialInstitutionHandler(service *services.FinancialInstitutionService) *FinancialInstitutionHandler {
+	return &FinancialInstitutionHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+// CreateInstitution handles POST /financial-institutions
+func (h *FinancialInstitutionHandler) CreateInstitution(c *fiber.Ctx) error {
+	var req schema.FinancialInstitutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	institution, err := h.Service.CreateInstitution(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(institution)
+}
+
+// SetUserInstitution handles PUT /users/:id/financial-institution
+func (h *FinancialInstitutionHandler) SetUserInstitution(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "user")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.SetUserInstitutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+
+	if err := h.Service.SetUserInstitution(id, req.FinancialInstitutionID); err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetInstitutions handles GET /financial-institutions
+func (h *FinancialInstitutionHandler) GetInstitutions(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	institutions, totalItems, err := h.Service.GetInstitutions(paginationParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(institutions, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetInstitutionByID handles GET /financial-institutions/:id
+func (h *FinancialInstitutionHandler) GetInstitutionByID(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "financial institution")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	institution, err := h.Service.GetInstitutionByID(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(institution)
+}
+
+// UpdateInstitution handles PUT /financial-institutions/:id
+func (h *FinancialInstitutionHandler) UpdateInstitution(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "financial institution")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.FinancialInstitutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	institution, err := h.Service.UpdateInstitution(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(institution)
+}
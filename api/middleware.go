@@ -0,0 +1,121 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+// Keys for what RequireAuth stores on the request
+const (
+	localUser    = "user"
+	localAgentID = "agentID"
+	localAPIKey  = "apiKey"
+)
+
+const headerAPIKey = "X-API-Key"
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer <access token>" or
+// "X-API-Key: <key>" header and stores the user or key for the handlers. For agents it also
+// stores their agent profile ID. API key requests are rate limited per key. The principal's
+// tenant goes on the user context, services given c.UserContext() only see its data.
+func RequireAuth(auth *services.AuthService, apiKeys *services.APIKeyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if raw := strings.TrimSpace(c.Get(headerAPIKey)); raw != "" {
+			key, err := apiKeys.Authenticate(raw)
+			if err != nil {
+				return utils.HandleError(c, err)
+			}
+			if !applyRateLimit(c, apiKeys.TakeToken(key)) {
+				return utils.HandleError(c, utils.NewAPIError(http.StatusTooManyRequests, "Too Many Requests", "Rate limit exceeded for this API key"))
+			}
+			c.Locals(localAPIKey, key)
+			c.SetUserContext(services.WithTenant(c.UserContext(), services.TenantForAPIKey(key)))
+			return c.Next()
+		}
+
+		header := c.Get(fiber.HeaderAuthorization)
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			return utils.HandleError(c, utils.NewAPIError(http.StatusUnauthorized, "Unauthorized", "Missing bearer token or API key"))
+		}
+
+		user, err := auth.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		c.Locals(localUser, user)
+		c.SetUserContext(services.WithTenant(c.UserContext(), services.TenantForUser(user)))
+
+		if services.HasPermission(user.Role, services.PermWriteOwnListings) || services.HasPermission(user.Role, services.PermEditOwnAgent) {
+			agentID, err := auth.AgentIDForUser(user.ID)
+			if err != nil {
+				return utils.HandleError(c, err)
+			}
+			c.Locals(localAgentID, agentID)
+		}
+		return c.Next()
+	}
+}
+
+// applyRateLimit sets the X-RateLimit-* headers and reports whether the request may go ahead.
+func applyRateLimit(c *fiber.Ctx, limit services.RateLimitResult) bool {
+	c.Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+	c.Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(limit.Reset.Seconds())))) // Seconds until the bucket is full
+	if !limit.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+	}
+	return limit.Allowed
+}
+
+// RequirePermission lets the request through when the user's role, or the API key's scopes,
+// grant any of the permissions. It must run after RequireAuth.
+func RequirePermission(perms ...services.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, key := currentUser(c), currentAPIKey(c)
+		if user == nil && key == nil {
+			return utils.HandleError(c, utils.NewAPIError(http.StatusUnauthorized, "Unauthorized", "Missing bearer token or API key"))
+		}
+		for _, perm := range perms {
+			if user != nil && services.HasPermission(user.Role, perm) {
+				return c.Next()
+			}
+			if key != nil && services.ScopesGrant(key.Scopes, perm) {
+				return c.Next()
+			}
+		}
+		if key != nil {
+			return utils.HandleError(c, forbidden("This API key's scopes do not allow this action"))
+		}
+		return utils.HandleError(c, forbidden("Your role does not allow this action"))
+	}
+}
+
+func forbidden(details string) error {
+	return utils.NewAPIError(http.StatusForbidden, "Forbidden", details)
+}
+
+// currentUser returns the user stored by RequireAuth, nil on public routes and for API keys.
+func currentUser(c *fiber.Ctx) *models.User {
+	user, _ := c.Locals(localUser).(*models.User)
+	return user
+}
+
+// currentAPIKey returns the API key stored by RequireAuth, nil for logged in users.
+func currentAPIKey(c *fiber.Ctx) *models.APIKey {
+	key, _ := c.Locals(localAPIKey).(*models.APIKey)
+	return key
+}
+
+// currentAgentID returns the agent profile of the user, nil when they have none.
+func currentAgentID(c *fiber.Ctx) *uint {
+	agentID, _ := c.Locals(localAgentID).(*uint)
+	return agentID
+}
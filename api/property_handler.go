@@ -3,6 +3,7 @@ package api
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -37,8 +38,20 @@ func (h *PropertyHandler) CreateProperty(c *fiber.Ctx) error {
 		return utils.HandleError(c, err.(validator.ValidationErrors)) // Let HandleError format validation errors
 	}
 
+	// Agents can only list properties under their own profile
+	if user := currentUser(c); user != nil && !services.HasPermission(user.Role, services.PermWriteListings) {
+		agentID := currentAgentID(c)
+		if agentID == nil {
+			return utils.HandleError(c, forbidden("Your account has no agent profile to list properties under"))
+		}
+		if req.AgentID != nil && *req.AgentID != *agentID {
+			return utils.HandleError(c, forbidden("Agents can only create listings for themselves"))
+		}
+		req.AgentID = agentID
+	}
+
 	// Call the service
-	createdProperty, err := h.Service.CreateProperty(&req)
+	createdProperty, err := h.Service.WithContext(c.UserContext()).CreateProperty(&req)
 	if err != nil {
 		return utils.HandleError(c, err) // Use centralized error handler
 	}
@@ -63,7 +76,7 @@ func (h *PropertyHandler) CreateMultipleProperties(c *fiber.Ctx) error {
 	}
 
 	// Call the service
-	successful, errorsReport := h.Service.CreateMultipleProperties(bulkReq.Properties)
+	successful, errorsReport := h.Service.WithContext(c.UserContext()).CreateMultipleProperties(bulkReq.Properties)
 
 	// Prepare response
 	response := schema.BulkCreatePropertyResponse{
@@ -90,7 +103,7 @@ func (h *PropertyHandler) GetPropertyByID(c *fiber.Ctx) error {
 		return utils.HandleError(c, utils.NewBadRequestError("Invalid property ID format"))
 	}
 
-	property, err := h.Service.GetPropertyByID(uint(id))
+	property, err := h.Service.WithContext(c.UserContext()).GetPropertyByID(uint(id))
 	if err != nil {
 		return utils.HandleError(c, err)
 	}
@@ -100,6 +113,46 @@ func (h *PropertyHandler) GetPropertyByID(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// UpdateProperty handles PUT /properties/:id
+func (h *PropertyHandler) UpdateProperty(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "property")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.UpdatePropertyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	// Agents can only edit their own listings and can't hand them to another agent
+	if user := currentUser(c); user != nil && !services.HasPermission(user.Role, services.PermWriteListings) {
+		agentID := currentAgentID(c)
+		if agentID == nil {
+			return utils.HandleError(c, forbidden("Your account has no agent profile to edit listings under"))
+		}
+		current, err := h.Service.WithContext(c.UserContext()).GetPropertyByID(id)
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		if current.AgentID == nil || *current.AgentID != *agentID {
+			return utils.HandleError(c, forbidden("Agents can only edit their own listings"))
+		}
+		if req.AgentID != nil && *req.AgentID != *agentID {
+			return utils.HandleError(c, forbidden("Agents can't move listings to another agent"))
+		}
+	}
+
+	property, err := h.Service.WithContext(c.UserContext()).UpdateProperty(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(services.MapPropertyToResponse(property))
+}
+
 // GetAllProperties handles GET /properties
 func (h *PropertyHandler) GetAllProperties(c *fiber.Ctx) error {
 	// Get pagination params
@@ -111,21 +164,144 @@ func (h *PropertyHandler) GetAllProperties(c *fiber.Ctx) error {
 		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
 	}
 
+	// ids fetches those properties in the order given
+	if c.Query("ids") != "" {
+		ids, err := parseIDList(c.Query("ids"))
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		return h.getPropertiesByIDs(c, ids, filterParams)
+	}
+
+	// cursor or limit switches to keyset pagination
+	if cursorParams, ok := utils.GetCursorParams(c); ok {
+		return h.getPropertiesByCursor(c, cursorParams, filterParams)
+	}
+
 	// Call service
-	properties, totalItems, err := h.Service.GetAllProperties(paginationParams, filterParams)
+	properties, totalItems, err := h.Service.WithContext(c.UserContext()).GetAllProperties(paginationParams, filterParams)
 	if err != nil {
 		return utils.HandleError(c, err)
 	}
 
 	// Map models to response DTOs
 	propertyResponses := services.MapPropertiesToResponse(properties)
+	services.AttachDistances(propertyResponses, filterParams)
+	data, err := trimPropertyResponses(propertyResponses, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
 
 	// Create paginated response
-	paginatedResponse := utils.CreatePaginatedResponse(propertyResponses, totalItems, paginationParams.Page, paginationParams.PageSize)
+	paginatedResponse := utils.CreatePaginatedResponse(data, totalItems, paginationParams.Page, paginationParams.PageSize)
+
+	if filterParams.Facets {
+		facets, err := h.Service.WithContext(c.UserContext()).GetFacets(filterParams, "")
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		paginatedResponse.Facets = facets
+	}
 
 	return c.JSON(paginatedResponse)
 }
 
+// BatchGetProperties handles POST /properties/batch-get
+func (h *PropertyHandler) BatchGetProperties(c *fiber.Ctx) error {
+	var req schema.BatchGetPropertyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	// fields and include are read from the query string as on the listing
+	var filterParams schema.PropertyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	return h.getPropertiesByIDs(c, req.IDs, filterParams)
+}
+
+// getPropertiesByIDs serves both GET /properties?ids= and POST /properties/batch-get.
+func (h *PropertyHandler) getPropertiesByIDs(c *fiber.Ctx, ids []uint, filterParams schema.PropertyFilter) error {
+	properties, missing, err := h.Service.WithContext(c.UserContext()).GetPropertiesByIDs(ids, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	data, err := trimPropertyResponses(services.MapPropertiesToResponse(properties), filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(schema.BatchGetPropertyResponse{Data: data, Missing: missing})
+}
+
+// parseIDList reads a comma separated list of IDs such as 1,2,3.
+func parseIDList(raw string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || id == 0 {
+			return nil, utils.NewBadRequestError("Invalid property ID '" + part + "' in ids")
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// getPropertiesByCursor serves GET /properties?cursor=&limit= with keyset pagination.
+func (h *PropertyHandler) getPropertiesByCursor(c *fiber.Ctx, cursorParams schema.CursorRequest, filterParams schema.PropertyFilter) error {
+	properties, nextCursor, err := h.Service.WithContext(c.UserContext()).GetPropertiesByCursor(cursorParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	totalItems, estimated, err := h.Service.WithContext(c.UserContext()).CountProperties(filterParams, cursorParams.Total)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	propertyResponses := services.MapPropertiesToResponse(properties)
+	services.AttachDistances(propertyResponses, filterParams)
+	data, err := trimPropertyResponses(propertyResponses, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	response := schema.CursorPaginatedResponse{
+		Data:           data,
+		Limit:          cursorParams.Limit,
+		NextCursor:     nextCursor,
+		TotalItems:     totalItems,
+		TotalEstimated: estimated,
+	}
+	if filterParams.Facets {
+		facets, err := h.Service.WithContext(c.UserContext()).GetFacets(filterParams, "")
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		response.Facets = facets
+	}
+
+	return c.JSON(response)
+}
+
+// trimPropertyResponses applies the fields and include parameters to a page of responses.
+func trimPropertyResponses(responses []schema.PropertyResponse, filterParams schema.PropertyFilter) (interface{}, error) {
+	view, err := services.ParsePropertyView(filterParams)
+	if err != nil {
+		return nil, err
+	}
+	return view.Apply(responses)
+}
+
 // SearchProperties handles GET /properties/search
 func (h *PropertyHandler) SearchProperties(c *fiber.Ctx) error {
 	// Get search term
@@ -134,17 +310,104 @@ func (h *PropertyHandler) SearchProperties(c *fiber.Ctx) error {
 	// Get pagination params
 	paginationParams := utils.GetPaginationParams(c)
 
+	// The listing filters narrow the search too
+	var filterParams schema.PropertyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
 	// Call service
-	properties, totalItems, err := h.Service.SearchProperties(searchTerm, paginationParams)
+	properties, totalItems, err := h.Service.WithContext(c.UserContext()).SearchProperties(searchTerm, paginationParams, filterParams)
 	if err != nil {
 		return utils.HandleError(c, err)
 	}
 
 	// Map models to response DTOs
 	propertyResponses := services.MapPropertiesToResponse(properties)
+	services.AttachDistances(propertyResponses, filterParams)
+
+	// Attach rank and highlighted fragments for this page
+	ids := make([]uint, len(properties))
+	for i := range properties {
+		ids[i] = properties[i].ID
+	}
+	matches, err := h.Service.WithContext(c.UserContext()).SearchMatches(searchTerm, ids)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	for i := range propertyResponses {
+		if match, ok := matches[propertyResponses[i].ID]; ok {
+			propertyResponses[i].Search = &match
+		}
+	}
+	data, err := trimPropertyResponses(propertyResponses, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
 
 	// Create paginated response
-	paginatedResponse := utils.CreatePaginatedResponse(propertyResponses, totalItems, paginationParams.Page, paginationParams.PageSize)
+	paginatedResponse := utils.CreatePaginatedResponse(data, totalItems, paginationParams.Page, paginationParams.PageSize)
+
+	if filterParams.Facets {
+		facets, err := h.Service.WithContext(c.UserContext()).GetFacets(filterParams, searchTerm)
+		if err != nil {
+			return utils.HandleError(c, err)
+		}
+		paginatedResponse.Facets = facets
+	}
 
 	return c.JSON(paginatedResponse)
 }
+
+// GetUnitIssues handles GET /properties/unit-issues
+func (h *PropertyHandler) GetUnitIssues(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	issues, totalItems, err := h.Service.WithContext(c.UserContext()).GetUnitIssues(paginationParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(issues, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetAttributes handles GET /attributes
+func (h *PropertyHandler) GetAttributes(c *fiber.Ctx) error {
+	var filterParams schema.PropertyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	counts, err := h.Service.WithContext(c.UserContext()).GetAttributeCounts(filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(counts)
+}
+
+// SuggestCompletions handles GET /properties/suggest
+func (h *PropertyHandler) SuggestCompletions(c *fiber.Ctx) error {
+	suggestions, err := h.Service.WithContext(c.UserContext()).SuggestCompletions(c.Query("q", ""), c.QueryInt("limit", services.DefaultSuggestLimit))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(suggestions)
+}
+
+// RefreshSearchIndex handles POST /properties/search-index
+func (h *PropertyHandler) RefreshSearchIndex(c *fiber.Ctx) error {
+	result, err := h.Service.WithContext(c.UserContext()).RefreshSearchIndex(c.QueryBool("all", false))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// NormaliseSizes handles POST /properties/normalise-sizes
+func (h *PropertyHandler) NormaliseSizes(c *fiber.Ctx) error {
+	result, err := h.Service.WithContext(c.UserContext()).NormaliseStoredSizes(c.QueryBool("all", false))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
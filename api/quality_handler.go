@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type QualityHandler struct {
+	Service *services.QualityService
+}
+
+func NewQualityHandler(service *services.QualityService) *QualityHandler {
+	return &QualityHandler{Service: service}
+}
+
+// GetIssues handles GET /quality/issues
+func (h *QualityHandler) GetIssues(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.QualityIssueFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	issues, totalItems, err := h.Service.WithContext(c.UserContext()).GetIssues(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(issues, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// RunChecks handles POST /quality/scan
+func (h *QualityHandler) RunChecks(c *fiber.Ctx) error {
+	result, err := h.Service.WithContext(c.UserContext()).RunChecks()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
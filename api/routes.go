@@ -4,33 +4,177 @@ package api
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/hopekali04/valuations/services" 
+	"github.com/hopekali04/valuations/services"
 )
 
-func SetupRoutes(app *fiber.App, propertyService *services.PropertyService, syncService *services.SyncService) { // Add syncService
+// Services are the services the routes hand requests to.
+type Services struct {
+	Property             *services.PropertyService
+	Sync                 *services.SyncService
+	Comparable           *services.ComparableService
+	Valuation            *services.ValuationService
+	CostApproach         *services.CostApproachService
+	IncomeApproach       *services.IncomeApproachService
+	Stats                *services.StatsService
+	PriceIndex           *services.PriceIndexService
+	AVM                  *services.AVMService
+	Quality              *services.QualityService
+	Duplicate            *services.DuplicateService
+	SavedSearch          *services.SavedSearchService
+	Agent                *services.AgentService
+	Auth                 *services.AuthService
+	APIKey               *services.APIKeyService
+	FinancialInstitution *services.FinancialInstitutionService
+}
+
+func SetupRoutes(app *fiber.App, svc Services) {
 	// Middleware
 	app.Use(logger.New()) // Basic request logger
 
 	// Create handlers
-	propertyHandler := NewPropertyHandler(propertyService)
-	syncHandler := NewSyncHandler(syncService) // Create sync handler
+	propertyHandler := NewPropertyHandler(svc.Property)
+	syncHandler := NewSyncHandler(svc.Sync) // Create sync handler
+	comparableHandler := NewComparableHandler(svc.Comparable)
+	valuationHandler := NewValuationHandler(svc.Valuation, svc.CostApproach, svc.IncomeApproach)
+	statsHandler := NewStatsHandler(svc.Stats, svc.PriceIndex)
+	avmHandler := NewAVMHandler(svc.AVM)
+	qualityHandler := NewQualityHandler(svc.Quality)
+	duplicateHandler := NewDuplicateHandler(svc.Duplicate)
+	savedSearchHandler := NewSavedSearchHandler(svc.SavedSearch)
+	agentHandler := NewAgentHandler(svc.Agent, svc.Property)
+	authHandler := NewAuthHandler(svc.Auth)
+	apiKeyHandler := NewAPIKeyHandler(svc.APIKey)
+	financialInstitutionHandler := NewFinancialInstitutionHandler(svc.FinancialInstitution)
 
 	// Group API routes
 	api := app.Group("/api/v1") // versioning of the API
 
+	// --- Auth Routes ---
+	// Login and refresh are the only routes open without a token, so they come before RequireAuth
+	authGroup := api.Group("/auth")
+
+	authGroup.Post("/login", authHandler.Login)
+	authGroup.Post("/refresh", authHandler.Refresh)
+
+	api.Use(RequireAuth(svc.Auth, svc.APIKey))
+
+	authGroup.Get("/me", authHandler.Me)
+	authGroup.Post("/password", authHandler.ChangePassword)
+	api.Put("/users/:id/password", RequirePermission(services.PermManageUsers), authHandler.SetUserPassword)
+
+	// Shorthands for the permissions most routes need
+	read := RequirePermission(services.PermReadData)
+	readStats := RequirePermission(services.PermReadStats)
+	manage := RequirePermission(services.PermManageData)
+	writeValuations := RequirePermission(services.PermWriteValuations)
+	savedSearches := RequirePermission(services.PermUseSavedSearches)
+
 	// --- Property Routes ---
 	propGroup := api.Group("/properties")
 
-	propGroup.Post("/", propertyHandler.CreateProperty)
-	propGroup.Post("/bulk", propertyHandler.CreateMultipleProperties)
+	propGroup.Post("/", RequirePermission(services.PermWriteListings, services.PermWriteOwnListings), propertyHandler.CreateProperty)
+	propGroup.Post("/bulk", manage, propertyHandler.CreateMultipleProperties)
+	propGroup.Post("/batch-get", read, propertyHandler.BatchGetProperties)
+	propGroup.Post("/normalise-sizes", manage, propertyHandler.NormaliseSizes)
+	propGroup.Post("/search-index", manage, propertyHandler.RefreshSearchIndex)
+
+	propGroup.Get("/", read, propertyHandler.GetAllProperties)
+	propGroup.Get("/search", read, propertyHandler.SearchProperties)
+	propGroup.Get("/suggest", read, propertyHandler.SuggestCompletions)
+	propGroup.Get("/unit-issues", read, propertyHandler.GetUnitIssues)
+
+	propGroup.Get("/:id", read, propertyHandler.GetPropertyByID)
+	propGroup.Put("/:id", RequirePermission(services.PermWriteListings, services.PermWriteOwnListings), propertyHandler.UpdateProperty)
+	propGroup.Get("/:id/comparables", read, comparableHandler.GetComparables)
+	propGroup.Get("/:id/valuations", read, valuationHandler.GetPropertyValuations)
+	propGroup.Post("/:id/valuations/cost-approach", writeValuations, valuationHandler.CreateCostApproachValuation)
+	propGroup.Post("/:id/valuations/income-approach", writeValuations, valuationHandler.CreateIncomeApproachValuation)
+
+	// --- Valuation Routes ---
+	valGroup := api.Group("/valuations")
+
+	valGroup.Post("/", writeValuations, valuationHandler.CreateValuation)
+	valGroup.Get("/", read, valuationHandler.GetAllValuations)
+	valGroup.Get("/:id", read, valuationHandler.GetValuationByID)
+	valGroup.Put("/:id", writeValuations, valuationHandler.UpdateValuation)
+	valGroup.Delete("/:id", writeValuations, valuationHandler.DeleteValuation)
+
+	// --- Stats Routes ---
+	statsGroup := api.Group("/stats")
+
+	statsGroup.Get("/market", readStats, statsHandler.GetMarketStats)
+	statsGroup.Get("/price-index", readStats, statsHandler.GetPriceIndex)
+	statsGroup.Post("/price-index/refresh", manage, statsHandler.RefreshPriceIndex)
+
+	// --- AVM Routes ---
+	avmGroup := api.Group("/avm")
+
+	avmGroup.Post("/train", manage, avmHandler.TrainModel)
+	avmGroup.Post("/estimate", read, avmHandler.Estimate)
+	avmGroup.Get("/models", read, avmHandler.GetModels)
+
+	// --- Attribute Routes ---
+	api.Get("/attributes", read, propertyHandler.GetAttributes)
+
+	// --- Data Quality Routes ---
+	qualityGroup := api.Group("/quality")
+
+	qualityGroup.Get("/issues", read, qualityHandler.GetIssues)
+	qualityGroup.Post("/scan", manage, qualityHandler.RunChecks)
+
+	// --- Duplicate Detection Routes ---
+	duplicateGroup := api.Group("/duplicates")
+
+	duplicateGroup.Get("/", read, duplicateHandler.GetCandidates)
+	duplicateGroup.Post("/scan", manage, duplicateHandler.Scan)
+	duplicateGroup.Patch("/:id", manage, duplicateHandler.ReviewCandidate)
+	duplicateGroup.Post("/:id/merge", manage, duplicateHandler.MergeCandidate)
+
+	// --- Saved Search Routes ---
+	savedSearchGroup := api.Group("/saved-searches")
+
+	savedSearchGroup.Post("/", savedSearches, savedSearchHandler.CreateSavedSearch)
+	savedSearchGroup.Get("/", savedSearches, savedSearchHandler.GetSavedSearches)
+	savedSearchGroup.Get("/:id", savedSearches, savedSearchHandler.GetSavedSearchByID)
+	savedSearchGroup.Put("/:id", savedSearches, savedSearchHandler.UpdateSavedSearch)
+	savedSearchGroup.Delete("/:id", savedSearches, savedSearchHandler.DeleteSavedSearch)
+
+	// --- Notification Routes ---
+	notificationGroup := api.Group("/notifications")
+
+	notificationGroup.Get("/", savedSearches, savedSearchHandler.GetNotifications)
+	notificationGroup.Post("/deliver", manage, savedSearchHandler.DeliverNotifications)
+
+	// --- Agent Routes ---
+	agentGroup := api.Group("/agents")
+
+	agentGroup.Post("/", RequirePermission(services.PermManageAgents), agentHandler.CreateAgent)
+	agentGroup.Get("/", read, agentHandler.GetAgents)
+	agentGroup.Get("/leaderboard", readStats, agentHandler.GetAgentLeaderboard)
+	agentGroup.Get("/:id", read, agentHandler.GetAgentByID)
+	agentGroup.Put("/:id", RequirePermission(services.PermManageAgents, services.PermEditOwnAgent), agentHandler.UpdateAgent)
+	agentGroup.Get("/:id/properties", read, agentHandler.GetAgentProperties)
+	agentGroup.Get("/:id/stats", readStats, agentHandler.GetAgentStats)
+
+	// --- API Key Routes ---
+	apiKeyGroup := api.Group("/api-keys", RequirePermission(services.PermManageAPIKeys))
+
+	apiKeyGroup.Post("/", apiKeyHandler.CreateAPIKey)
+	apiKeyGroup.Get("/", apiKeyHandler.GetAPIKeys)
+	apiKeyGroup.Get("/:id", apiKeyHandler.GetAPIKeyByID)
+	apiKeyGroup.Put("/:id", apiKeyHandler.UpdateAPIKey)
+	apiKeyGroup.Post("/:id/revoke", apiKeyHandler.RevokeAPIKey)
 
-	propGroup.Get("/", propertyHandler.GetAllProperties)
-	propGroup.Get("/search", propertyHandler.SearchProperties)
-	
-	propGroup.Get("/:id", propertyHandler.GetPropertyByID)
+	// --- Financial Institution Routes ---
+	institutionGroup := api.Group("/financial-institutions", RequirePermission(services.PermManageUsers))
 
+	institutionGroup.Post("/", financialInstitutionHandler.CreateInstitution)
+	institutionGroup.Get("/", financialInstitutionHandler.GetInstitutions)
+	institutionGroup.Get("/:id", financialInstitutionHandler.GetInstitutionByID)
+	institutionGroup.Put("/:id", financialInstitutionHandler.UpdateInstitution)
+	api.Put("/users/:id/financial-institution", RequirePermission(services.PermManageUsers), financialInstitutionHandler.SetUserInstitution)
 
 	// --- Sync Route ---
 	// This will automatically fetch from an API endpoint and sync the data with our Database
-	api.Post("/sync", syncHandler.TriggerSync) // Add the sync endpoint
-}
\ No newline at end of file
+	api.Post("/sync", RequirePermission(services.PermSync), syncHandler.TriggerSync) // Add the sync endpoint
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type SavedSearchHandler struct {
+	Service   *services.SavedSearchService
+	Validator *validator.Validate
+}
+
+func NewSavedSearchHandler(service *services.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		Service:   service,
+		Validator: validator.New(),
+	}
+}
+
+// savedSearchOwner is whose saved searches the caller may use: their own, or nil for everyone's
+// when they manage data. API keys have no user to own a search.
+func savedSearchOwner(c *fiber.Ctx) (*uint, error) {
+	user := currentUser(c)
+	if user == nil {
+		return nil, forbidden("Saved searches belong to users, log in to use them")
+	}
+	if services.HasPermission(user.Role, services.PermManageData) {
+		return nil, nil
+	}
+	return &user.ID, nil
+}
+
+// CreateSavedSearch handles POST /saved-searches
+func (h *SavedSearchHandler) CreateSavedSearch(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.SavedSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+	if err := setSavedSearchUser(&req, owner); err != nil {
+		return utils.HandleError(c, err)
+	}
+	if req.UserID == 0 {
+		req.UserID = currentUser(c).ID
+	}
+
+	search, err := h.Service.CreateSavedSearch(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(search)
+}
+
+// setSavedSearchUser makes the caller the owner of the search. Managers may name another user,
+// leaving user_id out keeps the current owner.
+func setSavedSearchUser(req *schema.SavedSearchRequest, owner *uint) error {
+	if owner == nil {
+		return nil
+	}
+	if req.UserID != 0 && req.UserID != *owner {
+		return forbidden("Saved searches can only be kept for yourself")
+	}
+	req.UserID = *owner
+	return nil
+}
+
+// GetSavedSearches handles GET /saved-searches
+func (h *SavedSearchHandler) GetSavedSearches(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.SavedSearchFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	searches, totalItems, err := h.Service.GetSavedSearches(paginationParams, filterParams, owner)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(searches, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetSavedSearchByID handles GET /saved-searches/:id
+func (h *SavedSearchHandler) GetSavedSearchByID(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	id, err := parseIDParam(c, "id", "saved search")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	search, err := h.Service.GetSavedSearchByID(id, owner)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(search)
+}
+
+// UpdateSavedSearch handles PUT /saved-searches/:id
+func (h *SavedSearchHandler) UpdateSavedSearch(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	id, err := parseIDParam(c, "id", "saved search")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.SavedSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+	if err := setSavedSearchUser(&req, owner); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	search, err := h.Service.UpdateSavedSearch(id, owner, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(search)
+}
+
+// DeleteSavedSearch handles DELETE /saved-searches/:id
+func (h *SavedSearchHandler) DeleteSavedSearch(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	id, err := parseIDParam(c, "id", "saved search")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	if err := h.Service.DeleteSavedSearch(id, owner); err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetNotifications handles GET /notifications
+func (h *SavedSearchHandler) GetNotifications(c *fiber.Ctx) error {
+	owner, err := savedSearchOwner(c)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.NotificationFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	notifications, totalItems, err := h.Service.GetNotifications(paginationParams, filterParams, owner)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(notifications, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// DeliverNotifications handles POST /notifications/deliver, retrying anything still pending
+func (h *SavedSearchHandler) DeliverNotifications(c *fiber.Ctx) error {
+	result, err := h.Service.DeliverPending()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
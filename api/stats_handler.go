@@ -0,0 +1,83 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type StatsHandler struct {
+	Service           *services.StatsService
+	PriceIndexService *services.PriceIndexService
+}
+
+func NewStatsHandler(service *services.StatsService, priceIndexService *services.PriceIndexService) *StatsHandler {
+	return &StatsHandler{Service: service, PriceIndexService: priceIndexService}
+}
+
+// GetMarketStats handles GET /stats/market
+func (h *StatsHandler) GetMarketStats(c *fiber.Ctx) error {
+	var filterParams schema.PropertyFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	groupBy, err := services.ParseMarketStatsGroupBy(c.Query("groupBy"))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	priceBuckets, err := services.ParsePriceBuckets(c.Query("priceBuckets"))
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	stats, err := h.Service.WithContext(c.UserContext()).GetMarketStats(filterParams, groupBy, priceBuckets)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(stats)
+}
+
+// GetPriceIndex handles GET /stats/price-index
+func (h *StatsHandler) GetPriceIndex(c *fiber.Ctx) error {
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	index, err := h.PriceIndexService.GetPriceIndex(c.Query("district"), c.Query("interval"), from, to)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(index)
+}
+
+// RefreshPriceIndex handles POST /stats/price-index/refresh
+func (h *StatsHandler) RefreshPriceIndex(c *fiber.Ctx) error {
+	result, err := h.PriceIndexService.Refresh()
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// parseDateQuery reads an optional YYYY-MM-DD or YYYY-MM query parameter.
+func parseDateQuery(c *fiber.Ctx, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, utils.NewBadRequestError("Invalid " + name + " date, use YYYY-MM-DD or YYYY-MM")
+}
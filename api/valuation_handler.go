@@ -0,0 +1,183 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/services"
+	"github.com/hopekali04/valuations/utils"
+)
+
+type ValuationHandler struct {
+	Service       *services.ValuationService
+	CostService   *services.CostApproachService
+	IncomeService *services.IncomeApproachService
+	Validator     *validator.Validate
+}
+
+func NewValuationHandler(service *services.ValuationService, costService *services.CostApproachService, incomeService *services.IncomeApproachService) *ValuationHandler {
+	return &ValuationHandler{
+		Service:       service,
+		CostService:   costService,
+		IncomeService: incomeService,
+		Validator:     validator.New(),
+	}
+}
+
+// parseIDParam reads a numeric route parameter.
+func parseIDParam(c *fiber.Ctx, name, resource string) (uint, error) {
+	id, err := strconv.ParseUint(c.Params(name), 10, 32)
+	if err != nil {
+		return 0, utils.NewBadRequestError("Invalid " + resource + " ID format")
+	}
+	return uint(id), nil
+}
+
+// CreateValuation handles POST /valuations
+func (h *ValuationHandler) CreateValuation(c *fiber.Ctx) error {
+	var req schema.CreateValuationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	valuation, err := h.Service.WithContext(c.UserContext()).CreateValuation(&req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(valuation)
+}
+
+// GetAllValuations handles GET /valuations
+func (h *ValuationHandler) GetAllValuations(c *fiber.Ctx) error {
+	paginationParams := utils.GetPaginationParams(c)
+
+	var filterParams schema.ValuationFilter
+	if err := c.QueryParser(&filterParams); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid filter parameters: "+err.Error()))
+	}
+
+	valuations, totalItems, err := h.Service.WithContext(c.UserContext()).GetAllValuations(paginationParams, filterParams)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.JSON(utils.CreatePaginatedResponse(valuations, totalItems, paginationParams.Page, paginationParams.PageSize))
+}
+
+// GetValuationByID handles GET /valuations/:id
+func (h *ValuationHandler) GetValuationByID(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "valuation")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	valuation, err := h.Service.WithContext(c.UserContext()).GetValuationByID(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(valuation)
+}
+
+// UpdateValuation handles PUT /valuations/:id
+func (h *ValuationHandler) UpdateValuation(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "valuation")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.UpdateValuationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	valuation, err := h.Service.WithContext(c.UserContext()).UpdateValuation(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(valuation)
+}
+
+// DeleteValuation handles DELETE /valuations/:id
+func (h *ValuationHandler) DeleteValuation(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "valuation")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	if err := h.Service.WithContext(c.UserContext()).DeleteValuation(id); err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPropertyValuations handles GET /properties/:id/valuations
+func (h *ValuationHandler) GetPropertyValuations(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "property")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	tree, err := h.Service.WithContext(c.UserContext()).GetValuationTree(id)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+	return c.JSON(tree)
+}
+
+// CreateCostApproachValuation handles POST /properties/:id/valuations/cost-approach
+func (h *ValuationHandler) CreateCostApproachValuation(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "property")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.CostApproachRequest
+	if len(c.Body()) > 0 { // All fields are optional, an empty body uses the property's own data
+		if err := c.BodyParser(&req); err != nil {
+			return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+		}
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	valuation, err := h.CostService.WithContext(c.UserContext()).CalculateAndSave(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(valuation)
+}
+
+// CreateIncomeApproachValuation handles POST /properties/:id/valuations/income-approach
+func (h *ValuationHandler) CreateIncomeApproachValuation(c *fiber.Ctx) error {
+	id, err := parseIDParam(c, "id", "property")
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	var req schema.IncomeApproachRequest
+	if len(c.Body()) > 0 { // All fields are optional, defaults come from district comparables
+		if err := c.BodyParser(&req); err != nil {
+			return utils.HandleError(c, utils.NewBadRequestError("Invalid request body: "+err.Error()))
+		}
+	}
+	if err := h.Validator.Struct(req); err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	valuation, err := h.IncomeService.WithContext(c.UserContext()).CalculateAndSave(id, &req)
+	if err != nil {
+		return utils.HandleError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(valuation)
+}
@@ -21,9 +21,149 @@ type ExternalAPIConfig struct { // New struct
 	PropertiesURL string `yaml:"properties_url"`
 }
 
+// ComparableWeights controls how much each factor counts towards a comparable's similarity score.
+type ComparableWeights struct {
+	Distance     float64 `yaml:"distance"`
+	PropertyType float64 `yaml:"property_type"`
+	Design       float64 `yaml:"design"`
+	Rooms        float64 `yaml:"rooms"`
+	Bathrooms    float64 `yaml:"bathrooms"`
+	BuildingSize float64 `yaml:"building_size"`
+	LandSize     float64 `yaml:"land_size"`
+	Age          float64 `yaml:"age"`
+	Recency      float64 `yaml:"recency"`
+}
+
+type ComparablesConfig struct {
+	MaxRadiusKm       float64           `yaml:"max_radius_km"`       // Candidates further than this score 0 on distance
+	MaxCandidates     int               `yaml:"max_candidates"`      // Rows pulled from the DB before scoring
+	RecencyWindowDays int               `yaml:"recency_window_days"` // Listings older than this score 0 on recency
+	Weights           ComparableWeights `yaml:"weights"`
+}
+
+// RebuildRate is a per-square-metre replacement cost. Empty PropertyType or District match anything,
+// the most specific matching entry wins.
+type RebuildRate struct {
+	PropertyType string  `yaml:"property_type"`
+	District     string  `yaml:"district"`
+	RatePerSqm   float64 `yaml:"rate_per_sqm"`
+}
+
+type LandRate struct {
+	District   string  `yaml:"district"`
+	RatePerSqm float64 `yaml:"rate_per_sqm"`
+}
+
+type CostApproachConfig struct {
+	DepreciationMethod    string        `yaml:"depreciation_method"` // straight_line or age_life
+	DefaultRatePerSqm     float64       `yaml:"default_rate_per_sqm"`
+	DefaultLandRatePerSqm float64       `yaml:"default_land_rate_per_sqm"`
+	RebuildRates          []RebuildRate `yaml:"rebuild_rates"`
+	LandRates             []LandRate    `yaml:"land_rates"`
+}
+
+type IncomeApproachConfig struct {
+	RentListingType              string   `yaml:"rent_listing_type"`     // ListingType value used for rentals, defaults to Rent
+	SaleListingType              string   `yaml:"sale_listing_type"`     // ListingType value used for sales, defaults to Sale
+	RentPeriodsPerYear           int      `yaml:"rent_periods_per_year"` // 12 if listed rents are monthly
+	MinComparables               int      `yaml:"min_comparables"`       // Fewer district rows than this and we fall back to defaults
+	DefaultVacancyRate           *float64 `yaml:"default_vacancy_rate"`  // Pointers so a configured 0 is kept
+	DefaultOperatingExpenseRatio *float64 `yaml:"default_operating_expense_ratio"`
+	DefaultCapRate               float64  `yaml:"default_cap_rate"`
+}
+
+type ValuationConfig struct {
+	CostApproach   CostApproachConfig   `yaml:"cost_approach"`
+	IncomeApproach IncomeApproachConfig `yaml:"income_approach"`
+}
+
+type PriceIndexConfig struct {
+	ListingType           string `yaml:"listing_type"`             // Only listings of this type feed the index, defaults to Sale
+	MinListingsPerPeriod  int    `yaml:"min_listings_per_period"`  // Thinner periods get no median index
+	MinListingsForHedonic int    `yaml:"min_listings_for_hedonic"` // Districts with fewer listings get no hedonic index
+}
+
+type StatsConfig struct {
+	PriceBuckets []float64        `yaml:"price_buckets"` // Ascending bucket edges for the market price distribution
+	PriceIndex   PriceIndexConfig `yaml:"price_index"`
+}
+
+type AVMConfig struct {
+	ModelDir         string  `yaml:"model_dir"`          // Where trained model artefacts are written, defaults to data/avm
+	ListingType      string  `yaml:"listing_type"`       // Only listings of this type are used for training, defaults to Sale
+	MinTrainingRows  int     `yaml:"min_training_rows"`  // Refuse to train on fewer listings than this
+	MinCategoryCount int     `yaml:"min_category_count"` // Districts, types and designs rarer than this share the base level
+	MaxAttributes    int     `yaml:"max_attributes"`     // Most common attributes used as features
+	ConfidenceLevel  float64 `yaml:"confidence_level"`   // Width of the interval returned with an estimate, e.g. 0.9
+}
+
+type QualityConfig struct {
+	IQRMultiplier float64 `yaml:"iqr_multiplier"` // Values beyond Q1/Q3 -/+ this many IQRs are outliers
+	MinGroupSize  int     `yaml:"min_group_size"` // District and type groups smaller than this are not scanned
+}
+
+type DuplicateWeights struct {
+	OwnerName float64 `yaml:"owner_name"`
+	Location  float64 `yaml:"location"`
+	Size      float64 `yaml:"size"`
+	Design    float64 `yaml:"design"`
+}
+
+type DuplicatesConfig struct {
+	MinScore      float64          `yaml:"min_score"`       // Pairs scoring below this are not stored
+	MaxDistanceKm float64          `yaml:"max_distance_km"` // Pairs further apart than this are never compared
+	MaxPairs      int              `yaml:"max_pairs"`       // Most pairs scored per scan
+	Weights       DuplicateWeights `yaml:"weights"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"` // Email alerts are off when empty
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"` // Leave empty for servers without auth, e.g. a local relay
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type WebhookConfig struct {
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+	Secret         string `yaml:"secret"` // Signs each payload with HMAC-SHA256 in the X-Signature header
+	// Lets webhooks reach loopback and private addresses, for local development only
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+type NotificationsConfig struct {
+	MaxAttempts       int           `yaml:"max_attempts"`        // Deliveries are retried until they have failed this many times
+	RetryDelaySeconds int           `yaml:"retry_delay_seconds"` // Wait before the first retry, doubled after each failed attempt
+	SMTP              SMTPConfig    `yaml:"smtp"`
+	Webhook           WebhookConfig `yaml:"webhook"`
+}
+
+// APIKeysConfig holds the rate limit given to new API keys when none is asked for.
+type APIKeysConfig struct {
+	RateLimitPerMinute int `yaml:"rate_limit_per_minute"`
+	Burst              int `yaml:"burst"`
+}
+
+type AuthConfig struct {
+	JWTSecret          string        `yaml:"jwt_secret"`           // Signs access and refresh tokens, a random one is used per run when empty
+	AccessTokenMinutes int           `yaml:"access_token_minutes"` // Lifetime of an access token
+	RefreshTokenHours  int           `yaml:"refresh_token_hours"`  // Lifetime of a refresh token
+	AdminEmail         string        `yaml:"admin_email"`          // Set with admin_password to create the first admin on startup
+	AdminPassword      string        `yaml:"admin_password"`
+	APIKeys            APIKeysConfig `yaml:"api_keys"`
+}
+
 type Config struct {
-	Database    DatabaseConfig    `yaml:"database"`
-	ExternalAPI ExternalAPIConfig `yaml:"external_api"` 
+	Database      DatabaseConfig      `yaml:"database"`
+	ExternalAPI   ExternalAPIConfig   `yaml:"external_api"`
+	Comparables   ComparablesConfig   `yaml:"comparables"`
+	Valuation     ValuationConfig     `yaml:"valuation"`
+	Stats         StatsConfig         `yaml:"stats"`
+	AVM           AVMConfig           `yaml:"avm"`
+	Quality       QualityConfig       `yaml:"quality"`
+	Duplicates    DuplicatesConfig    `yaml:"duplicates"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Auth          AuthConfig          `yaml:"auth"`
 }
 
 var Cfg *Config 
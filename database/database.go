@@ -51,15 +51,91 @@ func MigrateDB(db *gorm.DB) error {
 
 	// database models
 	err := db.AutoMigrate(
+		&models.FinancialInstitution{},
 		&models.User{},
 		&models.Agent{},
 		&models.Location{},
 		&models.CoverPhoto{},
 		&models.Property{},
+		&models.Valuation{},
+		&models.CostValuation{},
+		&models.IncomeValuation{},
+		&models.PriceIndexPoint{},
+		&models.QualityIssue{},
+		&models.DuplicateCandidate{},
+		&models.PropertyMerge{},
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.APIKey{},
 	)
 	if err != nil {
 		return fmt.Errorf("database migration failed: %w", err)
 	}
+
+	// Trigram indexes back the typo-tolerant search fallback and /properties/suggest
+	trigramIndexes := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_locations_district_trgm ON locations USING gin (district gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_locations_area_trgm ON locations USING gin (area gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_locations_sub_area_trgm ON locations USING gin (sub_area gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_properties_owner_name_trgm ON properties USING gin (owner_name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_properties_property_design_trgm ON properties USING gin (property_design gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops)",
+	}
+	for _, stmt := range trigramIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to set up trigram search: %w", err)
+		}
+	}
+	// The duplicate scan pairs up locations by their normalised district
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_locations_district_upper ON locations (upper(trim(district)))").Error
+	if err != nil {
+		return fmt.Errorf("failed to create the location district index: %w", err)
+	}
+
+	// Backfill parsed coordinates for locations synced before the lat/lng columns existed, with
+	// the same rules as utils.ParseLatLng. The casts sit behind CASE as Postgres may evaluate
+	// them before the regex checks.
+	err = db.Exec(`UPDATE locations
+		SET lat = parsed.lat, lng = parsed.lng
+		FROM (
+			SELECT id,
+				CASE WHEN latitude ~ '^\s*-?[0-9]+(\.[0-9]+)?\s*$' THEN CAST(trim(latitude) AS double precision) END AS lat,
+				CASE WHEN longitude ~ '^\s*-?[0-9]+(\.[0-9]+)?\s*$' THEN CAST(trim(longitude) AS double precision) END AS lng
+			FROM locations
+			WHERE lat IS NULL
+		) parsed
+		WHERE locations.id = parsed.id
+		  AND parsed.lat BETWEEN -90 AND 90
+		  AND parsed.lng BETWEEN -180 AND 180
+		  AND NOT (parsed.lat = 0 AND parsed.lng = 0)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill location coordinates: %w", err)
+	}
+	if err := backfillAttributeNames(db); err != nil {
+		return err
+	}
 	fmt.Println("Database migration completed.")
 	return nil
 }
+
+// backfillAttributeNames fills the attribute keys for properties stored before the column existed.
+func backfillAttributeNames(db *gorm.DB) error {
+	var batch []models.Property
+	err := db.Model(&models.Property{}).Select("id", "attributes").
+		Where("attribute_names IS NULL").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, p := range batch {
+				err := db.Model(&models.Property{}).Where("id = ?", p.ID).
+					Update("attribute_names", models.AttributeKeys(p.Attributes)).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill attribute names: %w", err)
+	}
+	return nil
+}
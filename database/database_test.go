@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackfillAttributeNames(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer conn.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn, PreferSimpleProtocol: true}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT "id","attributes" FROM "properties" WHERE attribute_names IS NULL ORDER BY "properties"."id" LIMIT \$1`).
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attributes"}).
+			AddRow(1, `{"attributes": [{"name": "Water Tank"}, {"name": "water  tank "}, {"name": "Parking"}]}`).
+			AddRow(2, `"[\"Borehole\"]"`). // Stored as a quoted string
+			AddRow(3, nil))
+	for _, want := range []struct {
+		id   int
+		keys string
+	}{{1, `["water tank","parking"]`}, {2, `["borehole"]`}, {3, `[]`}} {
+		// An empty list rather than NULL, so the row is not picked up again
+		mock.ExpectExec(`UPDATE "properties" SET "attribute_names"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+			WithArgs(want.keys, sqlmock.AnyArg(), want.id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	require.NoError(t, backfillAttributeNames(db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
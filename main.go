@@ -28,15 +28,69 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// Rows created here must not take IDs the sync uses, see services.LocalIDStart
+	if err := services.ReserveLocalIDs(db); err != nil {
+		log.Fatalf("Failed to reserve local IDs: %v", err)
+	}
+
+	// Limit queries to the caller's financial institution, see services.WithTenant
+	if err := services.RegisterTenantScope(db); err != nil {
+		log.Fatalf("Failed to set up tenant scoping: %v", err)
+	}
+
 	// 4. Initialize Services
 	propertyService := services.NewPropertyService(db)
+	// Index rows created before full-text search existed, search still works for the rest if this fails
+	if result, err := propertyService.RefreshSearchIndex(false); err != nil {
+		log.Printf("Warning: failed to build search index: %v", err)
+	} else if result.Updated > 0 {
+		log.Printf("Indexed %d properties for search.", result.Updated)
+	}
+	priceIndexService := services.NewPriceIndexService(db)
 	syncService := services.NewSyncService(db) // Initialize SyncService
+	syncService.PriceIndex = priceIndexService
+	qualityService := services.NewQualityService(db)
+	syncService.Quality = qualityService
+	comparableService := services.NewComparableService(db)
+	valuationService := services.NewValuationService(db)
+	costApproachService := services.NewCostApproachService(db)
+	incomeApproachService := services.NewIncomeApproachService(db)
+	statsService := services.NewStatsService(db)
+	avmService := services.NewAVMService(db)
+	duplicateService := services.NewDuplicateService(db)
+	savedSearchService := services.NewSavedSearchService(db)
+	propertyService.Alerts = savedSearchService
+	syncService.Alerts = savedSearchService
+	agentService := services.NewAgentService(db)
+	authService := services.NewAuthService(db)
+	if err := authService.EnsureAdmin(); err != nil {
+		log.Fatalf("Failed to set up the admin user: %v", err)
+	}
+	apiKeyService := services.NewAPIKeyService(db)
+	financialInstitutionService := services.NewFinancialInstitutionService(db)
 
 	// 5. Create Fiber App
 	app := fiber.New()
 
 	// 6. Setup Routes
-	api.SetupRoutes(app, propertyService, syncService) // Pass both services
+	api.SetupRoutes(app, api.Services{
+		Property:             propertyService,
+		Sync:                 syncService,
+		Comparable:           comparableService,
+		Valuation:            valuationService,
+		CostApproach:         costApproachService,
+		IncomeApproach:       incomeApproachService,
+		Stats:                statsService,
+		PriceIndex:           priceIndexService,
+		AVM:                  avmService,
+		Quality:              qualityService,
+		Duplicate:            duplicateService,
+		SavedSearch:          savedSearchService,
+		Agent:                agentService,
+		Auth:                 authService,
+		APIKey:               apiKeyService,
+		FinancialInstitution: financialInstitutionService,
+	})
 
 	// 7. Start Server
 	serverAddr := ":3000" // Make port configurable later
@@ -6,6 +6,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// Roles understood by the API. User.Role comes from the upstream API and is compared ignoring case,
+// unless it was set here (LocalRole), then this side owns it.
+const (
+	RoleAdmin  = "admin"
+	RoleValuer = "valuer"
+	RoleAgent  = "agent"
+	RoleViewer = "viewer"
+)
+
+// UserStatusActive is the only User.Status that may log in, the status comes from upstream.
+const UserStatusActive = "active"
+
 type User struct {
 	ID                      uint           `gorm:"primaryKey" json:"id"`
 	Name                    string         `json:"name"`
@@ -13,12 +25,15 @@ type User struct {
 	Status                  string         `json:"status"`
 	EmailVerifiedAt         *time.Time     `json:"email_verified_at"` 
 	Phone                   string         `json:"phone"`
-	PasswordLastUpdatedAt   *time.Time     `json:"password_last_updated_at"`
+	PasswordHash            string         `json:"-"`                        // bcrypt, empty until a password is set here
+	PasswordLastUpdatedAt   *time.Time     `json:"password_last_updated_at"` // Tokens issued before this are rejected
 	FinancialInstitutionID  *uint          `json:"financial_institution_id"` 
+	LocalInstitution        bool           `gorm:"not null;default:false" json:"-"` // Institution was set here, the sync no longer changes it
 	DeletedAt               gorm.DeletedAt `gorm:"index" json:"-"`           
 	CreatedAt               time.Time      `json:"created_at"`
 	UpdatedAt               time.Time      `json:"updated_at"`
 	Role                    string         `json:"role"`
+	LocalRole               bool           `gorm:"not null;default:false" json:"-"` // Role was set here, the sync no longer changes it
 	SignatureStorageURL     *string        `json:"signature_storage_url"`
 	ProfileImageStorageURL  *string        `json:"profile_image_storage_url"`
 
@@ -22,6 +22,7 @@ type Agent struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	CoverageArea *string   `json:"coverage_area"`
+	LocalEdit    bool      `gorm:"not null;default:false" json:"-"` // Edited here, the sync no longer changes it
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID"` // Belongs To User
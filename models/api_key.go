@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Scopes an API key can carry
+const (
+	ScopePropertiesRead  = "properties:read"
+	ScopePropertiesWrite = "properties:write"
+	ScopeSyncRun         = "sync:run"
+	ScopeStatsRead       = "stats:read"
+)
+
+// APIKey lets a server call the API without logging in. Only a SHA-256 hash of the key is
+// stored, Prefix is the public part of the key used to find it.
+type APIKey struct {
+	ID                     uint                        `gorm:"primaryKey" json:"id"`
+	Name                   string                      `gorm:"size:100;not null" json:"name"`
+	Prefix                 string                      `gorm:"size:16;uniqueIndex;not null" json:"prefix"`
+	KeyHash                string                      `gorm:"size:64;not null" json:"-"`
+	Scopes                 datatypes.JSONSlice[string] `json:"scopes"`
+	RateLimitPerMinute     int                         `json:"rate_limit_per_minute"` // Tokens added to the key's bucket each minute
+	Burst                  int                         `json:"burst"`                 // Bucket size, the most requests allowed at once
+	ExpiresAt              *time.Time                  `json:"expires_at"`            // nil never expires
+	Revoked                bool                        `gorm:"index" json:"revoked"`
+	RevokedAt              *time.Time                  `json:"revoked_at"`
+	LastUsedAt             *time.Time                  `json:"last_used_at"`
+	CreatedByID            uint                        `json:"created_by_id"`
+	FinancialInstitutionID *uint                       `gorm:"index" json:"financial_institution_id"` // The institution whose private data the key may read, nil for shared data only
+	CreatedAt              time.Time                   `json:"created_at"`
+	UpdatedAt              time.Time                   `json:"updated_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// CostValuation stores the result of a cost-approach (depreciated replacement cost) calculation.
+type CostValuation struct {
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	PropertyID         uint           `gorm:"index" json:"property_id"`
+	ValuationID        *uint          `gorm:"index" json:"valuation_id"` // The Valuation record this calculation produced
+	ValuerID           *uint          `json:"valuer_id"`
+	DepreciationMethod string         `json:"depreciation_method"` // straight_line or age_life
+	BuildingAreaSqm    float64        `json:"building_area_sqm"`
+	RatePerSqm         float64        `json:"rate_per_sqm"`
+	RateSource         string         `json:"rate_source"` // Where the rebuild rate came from
+	Age                *int           `json:"age"`
+	Eul                *int           `json:"eul"`
+	Rel                *int           `json:"rel"`
+	ReplacementCost    float64        `json:"replacement_cost"`
+	DepreciationRate   float64        `json:"depreciation_rate"` // 0-1
+	Depreciation       float64        `json:"depreciation"`
+	DepreciatedCost    float64        `json:"depreciated_cost"`
+	LandAreaSqm        *float64       `json:"land_area_sqm"`
+	LandRatePerSqm     *float64       `json:"land_rate_per_sqm"`
+	LandValue          float64        `json:"land_value"`
+	TotalValue         float64        `json:"total_value"`
+	LineItems          datatypes.JSON `gorm:"type:jsonb" json:"line_items"`
+	Notes              string         `gorm:"type:text" json:"notes"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
+
+// CostLineItem is one row of the cost-approach breakdown.
+type CostLineItem struct {
+	Label  string  `json:"label"`
+	Basis  string  `json:"basis"`
+	Amount float64 `json:"amount"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusConfirmed = "confirmed"
+	DuplicateStatusDismissed = "dismissed"
+	DuplicateStatusMerged    = "merged"
+	DuplicateStatusObsolete  = "obsolete" // One side of the pair was merged into a third property
+)
+
+// DuplicateCandidate is a pair of properties that look like the same physical house.
+// PropertyID is always the lower of the two IDs so each pair is stored once. There are no
+// foreign keys, a merged pair keeps pointing at the property that was removed.
+type DuplicateCandidate struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	PropertyID  uint           `gorm:"uniqueIndex:idx_duplicate_pair;not null" json:"property_id"`
+	DuplicateID uint           `gorm:"uniqueIndex:idx_duplicate_pair;index;not null" json:"duplicate_id"`
+	Score       float64        `gorm:"index" json:"score"`
+	Breakdown   datatypes.JSON `gorm:"type:jsonb" json:"breakdown"`
+	Status      string         `gorm:"index;size:16" json:"status"`
+	ReviewNote  string         `gorm:"type:text" json:"review_note"`
+	ReviewedAt  *time.Time     `json:"reviewed_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// PropertyMerge records a property that was merged into another and then removed. The sync
+// checks it so the upstream ID is not imported again.
+type PropertyMerge struct {
+	ID                  uint           `gorm:"primaryKey" json:"id"`
+	MergedPropertyID    uint           `gorm:"uniqueIndex;not null" json:"merged_property_id"`
+	CanonicalPropertyID uint           `gorm:"index;not null" json:"canonical_property_id"`
+	CandidateID         *uint          `json:"candidate_id"`
+	Snapshot            datatypes.JSON `gorm:"type:jsonb" json:"snapshot"` // The merged property as it was before removal
+	CreatedAt           time.Time      `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// FinancialInstitution is a bank or lender using the API. Properties and valuations that carry
+// its ID are private to its users and API keys, those without one are shared market data.
+type FinancialInstitution struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:150;not null" json:"name"`
+	Code      string    `gorm:"size:32;uniqueIndex;not null" json:"code"` // Short identifier, e.g. a bank's SWIFT prefix
+	Active    bool      `json:"active"`                                   // Users and API keys of an inactive institution are refused
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
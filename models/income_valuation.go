@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// IncomeValuation stores the result of an income-approach (direct capitalisation) calculation.
+type IncomeValuation struct {
+	ID                     uint      `gorm:"primaryKey" json:"id"`
+	PropertyID             uint      `gorm:"index" json:"property_id"`
+	ValuationID            *uint     `gorm:"index" json:"valuation_id"` // The Valuation record this calculation produced
+	ValuerID               *uint     `json:"valuer_id"`
+	GrossAnnualRent        float64   `json:"gross_annual_rent"`
+	GrossRentSource        string    `json:"gross_rent_source"`
+	VacancyRate            float64   `json:"vacancy_rate"`
+	VacancyLoss            float64   `json:"vacancy_loss"`
+	EffectiveGrossIncome   float64   `json:"effective_gross_income"`
+	OperatingExpenses      float64   `json:"operating_expenses"`
+	OperatingExpenseSource string    `json:"operating_expense_source"`
+	NetOperatingIncome     float64   `json:"net_operating_income"`
+	CapRate                float64   `json:"cap_rate"`
+	CapRateSource          string    `json:"cap_rate_source"`
+	CapitalisedValue       float64   `json:"capitalised_value"`
+	ComparableRentals      int64     `json:"comparable_rentals"` // District rentals used for defaults
+	ComparableSales        int64     `json:"comparable_sales"`   // District sales used for the implied cap rate
+	Notes                  string    `gorm:"type:text" json:"notes"`
+	CreatedAt              time.Time `json:"created_at"`
+}
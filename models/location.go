@@ -4,7 +4,7 @@ import "time"
 
 type Location struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
-	PropertyID    uint      `gorm:json:"property_id"` // Foreign Key & Unique ensures One-to-One
+	PropertyID    uint      `json:"property_id"` // Foreign Key & Unique ensures One-to-One
 	Region        string    `json:"region"`
 	District      string    `json:"district"`
 	Area          string    `json:"area"`
@@ -13,6 +13,8 @@ type Location struct {
 	GoogleMapLink *string   `json:"google_map_link"`
 	Latitude      *string   `json:"latitude"`
 	Longitude     *string   `json:"longitude"`
+	Lat           *float64  `gorm:"index" json:"lat"` // Parsed from Latitude, nil if unusable
+	Lng           *float64  `gorm:"index" json:"lng"` // Parsed from Longitude, nil if unusable
 	ZoneCategory  string    `json:"zone_category"`
 	Zoning        string    `json:"zoning"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	PriceIndexMonthly   = "month"
+	PriceIndexQuarterly = "quarter"
+)
+
+// PriceIndexPoint is one period of a district's price index. The table is rebuilt from
+// the listings on every refresh, so rows are never edited in place.
+type PriceIndexPoint struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	District          string    `gorm:"uniqueIndex:idx_price_index_period" json:"district"`                       // Upper-cased so spellings group together
+	Interval          string    `gorm:"column:index_interval;uniqueIndex:idx_price_index_period" json:"interval"` // month or quarter, "interval" is a SQL keyword
+	PeriodStart       time.Time `gorm:"uniqueIndex:idx_price_index_period" json:"period_start"`
+	ListingCount      int64     `json:"listing_count"`
+	MedianPrice       *float64  `json:"median_price"`
+	MedianPricePerSqm *float64  `json:"median_price_per_sqm"`
+	MedianIndex       *float64  `json:"median_index"`  // Median price relative to the base period, base = 100
+	HedonicIndex      *float64  `json:"hedonic_index"` // Quality-adjusted index from the time-dummy regression, base = 100
+	RefreshedAt       time.Time `json:"refreshed_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"gorm.io/datatypes"
@@ -14,6 +16,72 @@ type AttributeList struct {
 	Attributes []Attribute `json:"attributes"`
 }
 
+// ParseAttributeNames returns the distinct attribute names in an attributes payload. It accepts
+// the {"attributes": [{"name": ...}]} shape we get from upstream as well as bare lists of
+// objects or strings, and skips anything it cannot read.
+func ParseAttributeNames(raw []byte) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var names []string
+	var list AttributeList
+	var objects []Attribute
+	var plain []string
+	switch {
+	case json.Unmarshal(raw, &list) == nil && len(list.Attributes) > 0:
+		for _, a := range list.Attributes {
+			names = append(names, a.Name)
+		}
+	case json.Unmarshal(raw, &objects) == nil:
+		for _, a := range objects {
+			names = append(names, a.Name)
+		}
+	case json.Unmarshal(raw, &plain) == nil:
+		names = plain
+	default:
+		// Some upstream rows hold the JSON as a quoted string
+		var inner string
+		if json.Unmarshal(raw, &inner) == nil && inner != "" {
+			return ParseAttributeNames([]byte(inner))
+		}
+	}
+
+	seen := map[string]bool{}
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		key := strings.ToLower(n)
+		if n == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, n)
+	}
+	return result
+}
+
+// AttributeKey is the form attribute names are stored and filtered in, so "Water Tank" and
+// "water tank " are the same attribute.
+func AttributeKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// AttributeKeys returns the attribute keys of an attributes payload as a JSON array, the form
+// kept in Property.AttributeNames.
+func AttributeKeys(raw []byte) datatypes.JSON {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, n := range ParseAttributeNames(raw) {
+		if key := AttributeKey(n); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	data, _ := json.Marshal(keys) // A []string always marshals
+	return data
+}
+
 type Property struct {
 	ID                            uint           `gorm:"primaryKey" json:"id"`
 	ValuerID                      *uint          `json:"valuer_id"`        // Nullable uint
@@ -34,6 +102,7 @@ type Property struct {
 	Occupancy                     *string        `json:"occupancy"`
 
 	Attributes                    datatypes.JSON `gorm:"type:jsonb" json:"attributes"` // Use jsonb for PSQL efficiency
+	AttributeNames                datatypes.JSON `gorm:"type:jsonb;index:idx_properties_attribute_names,type:gin" json:"-"` // AttributeKeys of Attributes, for filtering
 	TitleDeedsAvailable           string         `json:"title_deeds_available"` // Consider bool or enum
 	CertificateOfSearchAvailable  *string        `json:"certificate_of_search_available"`
 	EncumbrancesAvailable         *string        `json:"encumbrances_available"`
@@ -44,6 +113,10 @@ type Property struct {
 	BuildingSizeUnit              string         `json:"bulding_size_unit"`
 	LandSize                      *float64       `json:"land_size"`
 	LandSizeUnit                  string         `json:"land_size_unit"`
+	BuildingSizeSqm               *float64       `gorm:"index" json:"building_size_sqm"` // BuildingSize converted to square metres, nil if the unit is unknown
+	LandSizeSqm                   *float64       `gorm:"index" json:"land_size_sqm"`     // LandSize converted to square metres, nil if the unit is unknown
+	BuildingPricePerSqm           *float64       `json:"building_price_per_sqm"`
+	LandPricePerSqm               *float64       `json:"land_price_per_sqm"`
 	EntryType                     string         `json:"entry_type"`
 	Price                         *float64       `json:"price"` // Use float for price/currency
 	ListingType                   string         `json:"listing_type"`
@@ -58,6 +131,8 @@ type Property struct {
 	ApprovedAt                    *time.Time     `json:"approved_at"` // Nullable timestamp
 	Visibility                    string         `json:"visibility"`
 	Views                         int            `json:"views"`
+	FinancialInstitutionID        *uint          `gorm:"index" json:"financial_institution_id"` // Private to this institution, nil for shared listings
+	SearchVector                  *string        `gorm:"type:tsvector;index:idx_properties_search_vector,type:gin;->:false;<-:false" json:"-"` // Weighted full-text document, maintained by the services
 
 	Location Location 
 
@@ -67,5 +142,7 @@ type Property struct {
 
 	CoverPhoto CoverPhoto  
 
+	QualityIssues []QualityIssue `gorm:"foreignKey:PropertyID" json:"quality_issues,omitempty"`
+
 
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	QualityKindRule    = "rule"    // An inconsistency within one property's own fields
+	QualityKindOutlier = "outlier" // Unusual compared with similar listings
+
+	QualitySeverityWarning = "warning"
+	QualitySeverityError   = "error"
+)
+
+// QualityIssue flags a suspected data problem on a property. There is at most one issue
+// per code per property, re-running the checks replaces them.
+type QualityIssue struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PropertyID uint      `gorm:"uniqueIndex:idx_quality_issue_code;not null" json:"property_id"`
+	Code       string    `gorm:"uniqueIndex:idx_quality_issue_code;size:64" json:"code"`
+	Kind       string    `gorm:"index" json:"kind"`
+	Severity   string    `gorm:"index" json:"severity"`
+	Field      string    `json:"field"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Channels a saved search can deliver its alerts through
+const (
+	AlertChannelEmail   = "email"
+	AlertChannelWebhook = "webhook"
+)
+
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed" // Gave up after notifications.max_attempts
+)
+
+// SavedSearch is a user's property filter and search term, checked against new and changed
+// listings. Filter holds a schema.PropertyFilter.
+type SavedSearch struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	UserID        uint           `gorm:"index;not null" json:"user_id"`
+	Name          string         `gorm:"size:100;not null" json:"name"`
+	SearchTerm    string         `json:"search_term"`
+	Filter        datatypes.JSON `gorm:"type:jsonb" json:"filter"`
+	Channel       string         `gorm:"size:16" json:"channel"`
+	Target        string         `json:"target"` // Email address or webhook URL
+	Active        bool           `gorm:"index" json:"active"`
+	LastMatchedAt *time.Time     `json:"last_matched_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Notification is a property that matched a saved search, and the state of telling the user.
+// A property is only ever notified once per saved search.
+type Notification struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SavedSearchID uint       `gorm:"uniqueIndex:idx_notification_match;not null" json:"saved_search_id"`
+	PropertyID    uint       `gorm:"uniqueIndex:idx_notification_match;index;not null" json:"property_id"`
+	Channel       string     `gorm:"size:16" json:"channel"`
+	Target        string     `json:"target"`
+	Status        string     `gorm:"index;size:16" json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"` // Set after a failed attempt, the retry waits until then
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	ValuationMethodCost       = "cost"
+	ValuationMethodComparison = "comparison"
+	ValuationMethodIncome     = "income"
+)
+
+// Valuation is a dated opinion of value for a property. Revaluations point at the
+// valuation they replace through ParentValuationID, forming a tree per property.
+type Valuation struct {
+	ID                     uint      `gorm:"primaryKey" json:"id"`
+	PropertyID             uint      `gorm:"index" json:"property_id"`
+	ValuerID               *uint     `gorm:"index" json:"valuer_id"`
+	ValuationDate          time.Time `json:"valuation_date"`
+	Method                 string    `json:"method"` // cost, comparison or income
+	MarketValue            *float64  `json:"market_value"`
+	ForcedSaleValue        *float64  `json:"forced_sale_value"`
+	InsuranceValue         *float64  `json:"insurance_value"`
+	Notes                  string    `gorm:"type:text" json:"notes"`
+	ParentValuationID      *uint     `gorm:"index" json:"parent_valuation_id"`
+	FinancialInstitutionID *uint     `gorm:"index" json:"financial_institution_id"` // Private to this institution, nil for valuations everyone may see
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
@@ -0,0 +1,101 @@
+package schema
+
+import "time"
+
+// AgentRequest creates or replaces an agent. The bank details are stored for payouts but never
+// returned by the API. The agreement is left as stored when not sent, unsigned on create.
+type AgentRequest struct {
+	UserID            uint    `json:"user_id" validate:"required"`
+	Phone1            string  `json:"phone_1" validate:"required,max=30"`
+	Phone2            *string `json:"phone_2" validate:"omitempty,max=30"`
+	Headline1         *string `json:"headline1"`
+	Headline2         *string `json:"headline2"`
+	About             *string `json:"about"`
+	IsAgreementSigned *bool   `json:"is_agreement_signed"`                   // Agent managers only
+	AgentType         string  `json:"agent_type" validate:"required,max=50"` // Agents editing their own profile must keep the stored one
+	CoverageArea      *string `json:"coverage_area"`
+	Linkedin          *string `json:"linkedin"`
+	Address           *string `json:"address"`
+	BankName          *string `json:"bank_name"`
+	AccountName       *string `json:"account_name"`
+	AccountNumber     *string `json:"account_number"`
+	AccountType       *string `json:"account_type"`
+	AccountBranch     *string `json:"account_branch"`
+}
+
+// AgentFilter defines the query parameters for GET /agents.
+type AgentFilter struct {
+	AgentType       *string `query:"agentType"`
+	CoverageArea    *string `query:"coverageArea"` // Partial match, e.g. Lilongwe
+	AgreementSigned *bool   `query:"agreementSigned"`
+	Name            *string `query:"name"` // Partial match on the user's name
+}
+
+// AgentDetailResponse is the public view of an agent, without the bank details.
+type AgentDetailResponse struct {
+	ID                uint          `json:"id"`
+	UserID            uint          `json:"user_id"`
+	Name              string        `json:"name"` // Name from User
+	Phone1            string        `json:"phone_1"`
+	Phone2            *string       `json:"phone_2"`
+	Headline1         *string       `json:"headline1"`
+	Headline2         *string       `json:"headline2"`
+	About             *string       `json:"about"`
+	IsAgreementSigned bool          `json:"is_agreement_signed"`
+	AgentType         string        `json:"agent_type"`
+	CoverageArea      *string       `json:"coverage_area"`
+	Linkedin          *string       `json:"linkedin"`
+	Address           *string       `json:"address"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	User              *UserResponse `json:"user,omitempty"`
+}
+
+// AgentPerformance holds an agent's figures for the listings submitted in the requested period.
+type AgentPerformance struct {
+	Listings             int64    `json:"listings"`
+	Approved             int64    `json:"approved"`
+	ApprovalRate         *float64 `json:"approvalRate"`         // Share of listings approved, 0 to 1
+	AvgDaysToApproval    *float64 `json:"avgDaysToApproval"`    // From submission to approval, approved listings only
+	MedianDaysToApproval *float64 `json:"medianDaysToApproval"` // nil when nothing was approved
+	CompletedSales       int64    `json:"completedSales"`
+	AcceptedOffers       int64    `json:"acceptedOffers"`
+	TotalViews           int64    `json:"totalViews"`
+}
+
+// AgentDistrictStats is an agent's performance within one district.
+type AgentDistrictStats struct {
+	District       *string `json:"district"`       // nil for listings without a location
+	InCoverageArea bool    `json:"inCoverageArea"` // Whether the agent's coverage area names the district
+	AgentPerformance
+}
+
+// AgentStatsResponse is returned by GET /agents/:id/stats.
+type AgentStatsResponse struct {
+	AgentID                 uint                 `json:"agentId"`
+	Name                    string               `json:"name"`
+	AgentType               string               `json:"agentType"`
+	CoverageAreas           []string             `json:"coverageAreas"`
+	From                    *string              `json:"from"` // YYYY-MM-DD, nil when open
+	To                      *string              `json:"to"`
+	Totals                  AgentPerformance     `json:"totals"`
+	ListingsOutsideCoverage int64                `json:"listingsOutsideCoverage"`
+	Districts               []AgentDistrictStats `json:"districts"` // Coverage districts without listings are included with zeros
+}
+
+// AgentLeaderboardEntry is one agent's row on the leaderboard.
+type AgentLeaderboardEntry struct {
+	Rank      int    `json:"rank"`
+	AgentID   uint   `json:"agentId"`
+	Name      string `json:"name"`
+	AgentType string `json:"agentType"`
+	AgentPerformance
+}
+
+// AgentLeaderboardResponse is returned by GET /agents/leaderboard.
+type AgentLeaderboardResponse struct {
+	SortBy string                  `json:"sortBy"`
+	From   *string                 `json:"from"`
+	To     *string                 `json:"to"`
+	Agents []AgentLeaderboardEntry `json:"agents"`
+}
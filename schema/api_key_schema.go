@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+)
+
+// APIKeyRequest issues or changes an API key. The rate limit defaults come from
+// auth.api_keys in config.yaml.
+type APIKeyRequest struct {
+	Name                   string     `json:"name" validate:"required,max=100"`
+	Scopes                 []string   `json:"scopes" validate:"required,min=1,dive,oneof=properties:read properties:write sync:run stats:read"`
+	RateLimitPerMinute     *int       `json:"rate_limit_per_minute" validate:"omitempty,min=1,max=100000"`
+	Burst                  *int       `json:"burst" validate:"omitempty,min=1,max=100000"`
+	ExpiresAt              *time.Time `json:"expires_at"`               // RFC 3339, leave out for a key that does not expire
+	FinancialInstitutionID *uint      `json:"financial_institution_id"` // Whose private data the key may read, leave out for shared data only
+}
+
+// APIKeyFilter defines the query parameters for GET /api-keys.
+type APIKeyFilter struct {
+	Revoked *bool `query:"revoked"`
+}
+
+// CreatedAPIKeyResponse is the only response that holds the key itself, it cannot be shown again.
+type CreatedAPIKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,30 @@
+package schema
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72"` // bcrypt ignores anything past 72 bytes
+}
+
+// SetPasswordRequest is used by admins to set another user's password.
+type SetPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// TokenResponse is returned by login and refresh. Send the access token as
+// "Authorization: Bearer <token>", and swap the refresh token for a new pair before it expires.
+type TokenResponse struct {
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	TokenType    string       `json:"token_type"`
+	ExpiresIn    int          `json:"expires_in"` // Seconds until the access token expires
+	User         UserResponse `json:"user"`
+}
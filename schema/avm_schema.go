@@ -0,0 +1,53 @@
+package schema
+
+import "time"
+
+// AVMEstimateRequest describes the property to estimate. Pass property_id to use a stored
+// property, any other fields override what is stored. Sizes are converted to sqm.
+type AVMEstimateRequest struct {
+	PropertyID       *uint    `json:"property_id"`
+	ModelVersion     string   `json:"model_version"` // Defaults to the latest trained model
+	District         *string  `json:"district"`
+	PropertyType     *string  `json:"property_type"`
+	PropertyDesign   *string  `json:"property_design"`
+	NoRooms          *int     `json:"no_rooms" validate:"omitempty,min=0"`
+	NoOfBathrooms    *int     `json:"no_of_bathrooms" validate:"omitempty,min=0"`
+	BuildingSize     *float64 `json:"building_size" validate:"omitempty,gt=0"`
+	BuildingSizeUnit string   `json:"building_size_unit"` // Defaults to sqm
+	LandSize         *float64 `json:"land_size" validate:"omitempty,gt=0"`
+	LandSizeUnit     string   `json:"land_size_unit"` // Defaults to sqm
+	Age              *int     `json:"age" validate:"omitempty,min=0"`
+	Attributes       []string `json:"attributes"`
+	ConfidenceLevel  *float64 `json:"confidence_level" validate:"omitempty,gt=0,lt=1"`
+}
+
+// AVMContribution is how far one feature moves the estimate away from an average listing.
+type AVMContribution struct {
+	Feature       string  `json:"feature"`
+	Value         float64 `json:"value"`
+	LogEffect     float64 `json:"logEffect"`
+	PercentEffect float64 `json:"percentEffect"` // e.g. 12.5 means 12.5% above an average listing
+}
+
+// AVMEstimateResponse is returned by POST /avm/estimate.
+type AVMEstimateResponse struct {
+	ModelVersion     string            `json:"modelVersion"`
+	PropertyID       *uint             `json:"propertyId,omitempty"`
+	Estimate         float64           `json:"estimate"`
+	Low              float64           `json:"low"`
+	High             float64           `json:"high"`
+	ConfidenceLevel  float64           `json:"confidenceLevel"`
+	TopContributions []AVMContribution `json:"topContributions"`
+	Warnings         []string          `json:"warnings,omitempty"`
+}
+
+// AVMModelSummary describes a trained model artefact.
+type AVMModelSummary struct {
+	Version          string    `json:"version"`
+	TrainedAt        time.Time `json:"trainedAt"`
+	ListingType      string    `json:"listingType"`
+	Observations     int       `json:"observations"`
+	FeatureCount     int       `json:"featureCount"`
+	RSquared         float64   `json:"rSquared"`
+	ResidualStdError float64   `json:"residualStdError"` // On log price
+}
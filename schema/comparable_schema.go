@@ -0,0 +1,24 @@
+package schema
+
+// ComparableScoreComponent explains how one factor contributed to a comparable's score.
+type ComparableScoreComponent struct {
+	Factor       string  `json:"factor"`
+	Weight       float64 `json:"weight"`
+	Similarity   float64 `json:"similarity"`   // 0-1, how close the candidate is on this factor
+	Contribution float64 `json:"contribution"` // Share of the final score coming from this factor
+	Available    bool    `json:"available"`    // False if the candidate had no data for this factor
+}
+
+// ComparableResponse is a single ranked comparable.
+type ComparableResponse struct {
+	Property   PropertyResponse           `json:"property"`
+	Score      float64                    `json:"score"` // 0-1, higher is more similar
+	DistanceKm *float64                   `json:"distanceKm,omitempty"`
+	Breakdown  []ComparableScoreComponent `json:"breakdown"`
+}
+
+// ComparablesResponse wraps the ranked comparables for a subject property.
+type ComparablesResponse struct {
+	SubjectID   uint                 `json:"subjectId"`
+	Comparables []ComparableResponse `json:"comparables"`
+}
@@ -0,0 +1,52 @@
+package schema
+
+import "time"
+
+// DuplicateCandidateFilter defines the query parameters for GET /duplicates.
+type DuplicateCandidateFilter struct {
+	Status     *string  `query:"status"` // pending, confirmed, dismissed, merged or obsolete
+	MinScore   *float64 `query:"minScore"`
+	PropertyID *uint    `query:"propertyId"` // Pairs involving this property on either side
+}
+
+// DuplicateReviewRequest confirms or dismisses a candidate pair.
+type DuplicateReviewRequest struct {
+	Status string `json:"status" validate:"required,oneof=confirmed dismissed"`
+	Note   string `json:"note"`
+}
+
+// DuplicateMergeRequest picks which side of the pair survives. Defaults to the older (lower) ID.
+type DuplicateMergeRequest struct {
+	CanonicalID *uint `json:"canonical_id"`
+}
+
+// DuplicateCandidateResponse is a candidate pair with both properties. A side that has
+// since been merged away is null.
+type DuplicateCandidateResponse struct {
+	ID         uint                       `json:"id"`
+	Score      float64                    `json:"score"`
+	Status     string                     `json:"status"`
+	ReviewNote string                     `json:"review_note,omitempty"`
+	ReviewedAt *time.Time                 `json:"reviewed_at,omitempty"`
+	Breakdown  []ComparableScoreComponent `json:"breakdown"`
+	Property   *PropertyResponse          `json:"property"`
+	Duplicate  *PropertyResponse          `json:"duplicate"`
+	CreatedAt  time.Time                  `json:"created_at"`
+}
+
+// DuplicateScanResult summarises a duplicate detection run.
+type DuplicateScanResult struct {
+	Properties    int  `json:"properties"` // Properties in at least one compared pair
+	PairsCompared int  `json:"pairsCompared"`
+	Candidates    int  `json:"candidates"`    // Pairs at or above the minimum score
+	NewCandidates int  `json:"newCandidates"` // Pairs not seen in an earlier scan
+	Removed       int  `json:"removed"`       // Pending pairs that no longer score high enough
+	Truncated     bool `json:"truncated"`     // More pairs than duplicates.max_pairs, the rest were not scored
+}
+
+// DuplicateMergeResult is returned by POST /duplicates/:id/merge.
+type DuplicateMergeResult struct {
+	CanonicalPropertyID uint `json:"canonicalPropertyId"`
+	MergedPropertyID    uint `json:"mergedPropertyId"`
+	MovedValuations     int  `json:"movedValuations"` // Valuation, cost and income records moved to the canonical property
+}
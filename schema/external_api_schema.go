@@ -133,12 +133,15 @@ type PropertiesAPIResponse struct {
 
 // --- Sync Operation Response ---
 type SyncResult struct {
-	Status            string   `json:"status"`
-	TotalPagesFetched int      `json:"totalPagesFetched"`
-	TotalProperties   int      `json:"totalProperties"` // Total from API meta
-	FetchedCount      int      `json:"fetchedCount"`    // Actual items processed from API pages
-	SyncedCount       int      `json:"syncedCount"`     // Successfully inserted
-	SkippedCount      int      `json:"skippedCount"`    // Duplicates skipped
-	ErrorCount        int      `json:"errorCount"`
-	Errors            []string `json:"errors,omitempty"` // List of specific errors encountered
+	Status              string   `json:"status"`
+	TotalPagesFetched   int      `json:"totalPagesFetched"`
+	TotalProperties     int      `json:"totalProperties"`   // Total from API meta
+	FetchedCount        int      `json:"fetchedCount"`      // Actual items processed from API pages
+	SyncedCount         int      `json:"syncedCount"`       // Successfully inserted
+	SkippedCount        int      `json:"skippedCount"`      // Duplicates skipped
+	UnrecognisedUnits   int      `json:"unrecognisedUnits"` // Synced properties with a size unit we could not convert
+	ErrorCount          int      `json:"errorCount"`
+	PriceIndexRefreshed bool     `json:"priceIndexRefreshed"` // Whether the price index was rebuilt after the sync
+	AlertMatches        int      `json:"alertMatches"`        // Saved search matches recorded for the synced listings
+	Errors              []string `json:"errors,omitempty"`    // List of specific errors encountered
 }
@@ -0,0 +1,14 @@
+package schema
+
+// FinancialInstitutionRequest creates or changes a financial institution.
+type FinancialInstitutionRequest struct {
+	ID     *uint  `json:"id"` // Create only, the upstream ID that synced users' financial_institution_id refer to
+	Name   string `json:"name" validate:"required,max=150"`
+	Code   string `json:"code" validate:"required,max=32"`
+	Active *bool  `json:"active"` // Defaults to true on create, unchanged on update when left out
+}
+
+// SetUserInstitutionRequest moves a user to a financial institution, null for none.
+type SetUserInstitutionRequest struct {
+	FinancialInstitutionID *uint `json:"financial_institution_id"`
+}
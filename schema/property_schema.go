@@ -39,55 +39,61 @@ type CoverPhotoResponse struct {
 // PropertyResponse defines the structure for returning property details.
 // It mirrors the desired output JSON structure more closely.
 type PropertyResponse struct {
-	ID                           uint                `json:"id"`
-	ValuerID                     *uint               `json:"valuer_id"`
-	PropertyNumber               *string             `json:"property_number"`
-	ParentValuation              *uint               `json:"parent_valuation"`
-	ProjectID                    *uint               `json:"project_id"`
-	OwnerName                    string              `json:"owner_name"`
-	PropertyType                 *string             `json:"property_type"`
-	PropertyDesign               string              `json:"property_design"`
-	ConstructionStage            string              `json:"construction_stage"`
-	YearBuilt                    *string             `json:"year_built"`
-	Age                          *int                `json:"age"`
-	Eul                          *int                `json:"eul"`
-	Rel                          *int                `json:"rel"`
-	Measurements                 string              `json:"measurements"`
-	NoRooms                      *int                `json:"no_rooms"`
-	NoOfBathrooms                *int                `json:"no_of_bathrooms"`
-	Occupancy                    *string             `json:"occupancy"`
-	Attributes                   datatypes.JSON      `json:"attributes"` // Keep as JSON or unmarshal to struct
-	TitleDeedsAvailable          string              `json:"title_deeds_available"`
-	CertificateOfSearchAvailable *string             `json:"certificate_of_search_available"`
-	EncumbrancesAvailable        *string             `json:"encumbrances_available"`
-	Defects                      *string             `json:"defects"`
-	Description                  string              `json:"description"`
-	MasterBedroomEnsuite         string              `json:"master_bedroom_ensuite"`
-	BuildingSize                 *float64            `json:"building_size"`
-	BuildingSizeUnit             string              `json:"bulding_size_unit"`
-	LandSize                     *float64            `json:"land_size"`
-	LandSizeUnit                 string              `json:"land_size_unit"`
-	EntryType                    string              `json:"entry_type"`
-	Price                        *float64            `json:"price"`
-	ListingType                  string              `json:"listing_type"`
-	CreatedBy                    *uint               `json:"created_by"`
-	CreatedAt                    time.Time           `json:"created_at"`
-	UpdatedAt                    time.Time           `json:"updated_at"`
-	IsApproved                   string              `json:"is_approved"`
-	IsSubmitted                  string              `json:"is_submitted"`
-	IsSaleCompleted              string              `json:"is_sale_completed"`
-	HasAcceptedOffer             string              `json:"has_accepted_offer"`
-	IsReferred                   string              `json:"is_referred"`
-	ApprovedAt                   *time.Time          `json:"approved_at"`
-	Visibility                   string              `json:"visibility"`
-	Views                        int                 `json:"views"`
-	Location                     *models.Location    `json:"location,omitempty"`    // Embed full location
-	Agent                        *AgentResponse      `json:"agent,omitempty"`       // Embed simplified agent
-	CoverPhoto                   *CoverPhotoResponse `json:"cover_photo,omitempty"` // Embed simplified cover photo
+	ID                           uint                   `json:"id"`
+	ValuerID                     *uint                  `json:"valuer_id"`
+	PropertyNumber               *string                `json:"property_number"`
+	ParentValuation              *uint                  `json:"parent_valuation"`
+	ProjectID                    *uint                  `json:"project_id"`
+	OwnerName                    string                 `json:"owner_name"`
+	PropertyType                 *string                `json:"property_type"`
+	PropertyDesign               string                 `json:"property_design"`
+	ConstructionStage            string                 `json:"construction_stage"`
+	YearBuilt                    *string                `json:"year_built"`
+	Age                          *int                   `json:"age"`
+	Eul                          *int                   `json:"eul"`
+	Rel                          *int                   `json:"rel"`
+	Measurements                 string                 `json:"measurements"`
+	NoRooms                      *int                   `json:"no_rooms"`
+	NoOfBathrooms                *int                   `json:"no_of_bathrooms"`
+	Occupancy                    *string                `json:"occupancy"`
+	Attributes                   datatypes.JSON         `json:"attributes"` // Keep as JSON or unmarshal to struct
+	TitleDeedsAvailable          string                 `json:"title_deeds_available"`
+	CertificateOfSearchAvailable *string                `json:"certificate_of_search_available"`
+	EncumbrancesAvailable        *string                `json:"encumbrances_available"`
+	Defects                      *string                `json:"defects"`
+	Description                  string                 `json:"description"`
+	MasterBedroomEnsuite         string                 `json:"master_bedroom_ensuite"`
+	BuildingSize                 *float64               `json:"building_size"`
+	BuildingSizeUnit             string                 `json:"bulding_size_unit"`
+	LandSize                     *float64               `json:"land_size"`
+	LandSizeUnit                 string                 `json:"land_size_unit"`
+	BuildingSizeSqm              *float64               `json:"building_size_sqm"`
+	LandSizeSqm                  *float64               `json:"land_size_sqm"`
+	BuildingPricePerSqm          *float64               `json:"building_price_per_sqm"`
+	LandPricePerSqm              *float64               `json:"land_price_per_sqm"`
+	EntryType                    string                 `json:"entry_type"`
+	Price                        *float64               `json:"price"`
+	ListingType                  string                 `json:"listing_type"`
+	CreatedBy                    *uint                  `json:"created_by"`
+	CreatedAt                    time.Time              `json:"created_at"`
+	UpdatedAt                    time.Time              `json:"updated_at"`
+	IsApproved                   string                 `json:"is_approved"`
+	IsSubmitted                  string                 `json:"is_submitted"`
+	IsSaleCompleted              string                 `json:"is_sale_completed"`
+	HasAcceptedOffer             string                 `json:"has_accepted_offer"`
+	IsReferred                   string                 `json:"is_referred"`
+	ApprovedAt                   *time.Time             `json:"approved_at"`
+	Visibility                   string                 `json:"visibility"`
+	Views                        int                    `json:"views"`
+	Location                     *models.Location       `json:"location,omitempty"`    // Embed full location
+	Agent                        *AgentResponse         `json:"agent,omitempty"`       // Embed simplified agent
+	CoverPhoto                   *CoverPhotoResponse    `json:"cover_photo,omitempty"` // Embed simplified cover photo
+	QualityIssues                []QualityIssueResponse `json:"quality_issues,omitempty"`
+	Search                       *SearchMatch           `json:"search,omitempty"`      // Only set on /properties/search results
+	DistanceKm                   *float64               `json:"distance_km,omitempty"` // Only set when lat and lng are given
 	// OpenHouses                 []models.OpenHouse `json:"open_houses"` // Embed open houses if needed
 }
 
-
 type CreatePropertyRequest struct {
 	ID                           uint               `json:"id" validate:"required"` // Require ID for duplication check
 	ValuerID                     *uint              `json:"valuer_id"`
@@ -134,6 +140,27 @@ type CreatePropertyRequest struct {
 	CoverPhoto                   *models.CoverPhoto `json:"cover_photo,omitempty"` // For creating/updating nested cover photo
 }
 
+// UpdatePropertyRequest holds the listing fields that can be edited, fields left out keep their value.
+type UpdatePropertyRequest struct {
+	PropertyType      *string         `json:"property_type"`
+	PropertyDesign    *string         `json:"property_design"`
+	ConstructionStage *string         `json:"construction_stage"`
+	NoRooms           *int            `json:"no_rooms" validate:"omitempty,gte=0"`
+	NoOfBathrooms     *int            `json:"no_of_bathrooms" validate:"omitempty,gte=0"`
+	Occupancy         *string         `json:"occupancy"`
+	Attributes        *datatypes.JSON `json:"attributes"`
+	Defects           *string         `json:"defects"`
+	Description       *string         `json:"description"`
+	BuildingSize      *float64        `json:"building_size" validate:"omitempty,gte=0"`
+	BuildingSizeUnit  *string         `json:"bulding_size_unit"` // Same spelling as on create
+	LandSize          *float64        `json:"land_size" validate:"omitempty,gte=0"`
+	LandSizeUnit      *string         `json:"land_size_unit"`
+	Price             *float64        `json:"price" validate:"omitempty,gte=0"`
+	ListingType       *string         `json:"listing_type"`
+	Visibility        *string         `json:"visibility"`
+	AgentID           *uint           `json:"agent_id"`
+}
+
 // BulkCreatePropertyRequest holds an array of properties to create.
 type BulkCreatePropertyRequest struct {
 	Properties []CreatePropertyRequest `json:"properties" validate:"required,dive"` // dive validates each element
@@ -147,21 +174,111 @@ type BulkCreatePropertyResponse struct {
 	Successful   []PropertyResponse `json:"successful,omitempty"` // Optional: return successfully created items
 }
 
+// BatchGetPropertyRequest lists the properties to fetch in one call.
+type BatchGetPropertyRequest struct {
+	IDs []uint `json:"ids" validate:"required,min=1,dive,gt=0"`
+}
+
+// BatchGetPropertyResponse holds the properties found, in request order, and the IDs that were not.
+type BatchGetPropertyResponse struct {
+	Data    interface{} `json:"data"`
+	Missing []uint      `json:"missing"`
+}
+
 // BulkErrorDetail provides info about a failed item in a bulk operation.
 type BulkErrorDetail struct {
 	PropertyID uint   `json:"propertyId"` // ID of the property that failed
 	Error      string `json:"error"`
 }
 
-// PropertyFilter defines available query parameters for filtering properties.
+// PropertyFilter defines available query parameters for filtering properties. The JSON form is
+// what saved searches store.
 type PropertyFilter struct {
-	OwnerName         *string  `query:"ownerName"`
-	PropertyType      *string  `query:"propertyType"`
-	ConstructionStage *string  `query:"constructionStage"`
-	ListingType       *string  `query:"listingType"`
-	MinPrice          *float64 `query:"minPrice"`
-	MaxPrice          *float64 `query:"maxPrice"`
-	District          *string  `query:"district"` // Filter by location district
-	Area              *string  `query:"area"`     // Filter by location area
-	AgentID           *uint    `query:"agentId"`  // Filter by agent
+	OwnerName         *string  `query:"ownerName" json:"ownerName,omitempty"`
+	PropertyType      *string  `query:"propertyType" json:"propertyType,omitempty"`
+	ConstructionStage *string  `query:"constructionStage" json:"constructionStage,omitempty"`
+	ListingType       *string  `query:"listingType" json:"listingType,omitempty"`
+	MinPrice          *float64 `query:"minPrice" json:"minPrice,omitempty"`
+	MaxPrice          *float64 `query:"maxPrice" json:"maxPrice,omitempty"`
+	District          *string  `query:"district" json:"district,omitempty"`             // Filter by location district
+	Area              *string  `query:"area" json:"area,omitempty"`                     // Filter by location area
+	AgentID           *uint    `query:"agentId" json:"agentId,omitempty"`               // Filter by agent
+	MinBuildingSqm    *float64 `query:"minBuildingSqm" json:"minBuildingSqm,omitempty"` // Sizes are compared in square metres
+	MaxBuildingSqm    *float64 `query:"maxBuildingSqm" json:"maxBuildingSqm,omitempty"`
+	MinLandSqm        *float64 `query:"minLandSqm" json:"minLandSqm,omitempty"`
+	MaxLandSqm        *float64 `query:"maxLandSqm" json:"maxLandSqm,omitempty"`
+	Rooms             *int     `query:"rooms" json:"rooms,omitempty"`                     // Exact number of rooms
+	Attributes        *string  `query:"attributes" json:"attributes,omitempty"`           // Comma separated attribute names, e.g. parking,water tank
+	AttributesMatch   *string  `query:"attributesMatch" json:"attributesMatch,omitempty"` // any (default) or all of the attributes
+	Facets            bool     `query:"facets" json:"-"`                                  // Include facet counts in the response
+	Fields            *string  `query:"fields" json:"-"`                                  // Comma separated response keys, e.g. id,price,location.district
+	Include           *string  `query:"include" json:"-"`                                 // Relations to embed: location, agent, cover_photo, quality_issues
+	Lat               *float64 `query:"lat" json:"lat,omitempty"`                         // Centre of a geo search
+	Lng               *float64 `query:"lng" json:"lng,omitempty"`
+	RadiusKm          *float64 `query:"radiusKm" json:"radiusKm,omitempty"` // Only properties within this distance of lat/lng
+	Sort              *string  `query:"sort" json:"-"`                      // Comma separated fields, "-" or ":desc" for descending, e.g. -price,created_at
+	Filter            *string  `query:"filter" json:"filter,omitempty"`     // Filter expression, e.g. price between 200000 and 400000 and no_rooms >= 3
+}
+
+// AttributeCount is an attribute name and how many properties have it.
+type AttributeCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// UnitIssue is a size on a property whose unit we could not convert to square metres.
+type UnitIssue struct {
+	PropertyID uint    `json:"propertyId"`
+	Field      string  `json:"field"` // building_size or land_size
+	Size       float64 `json:"size"`
+	Unit       string  `json:"unit"`
+}
+
+// SearchMatch explains why a property matched a full-text search. Highlights holds the
+// matching fragments keyed by field, with the matched words wrapped in <mark> tags.
+type SearchMatch struct {
+	Rank       float64           `json:"rank"`
+	Fuzzy      bool              `json:"fuzzy"` // Found by trigram similarity rather than the full-text index
+	Highlights map[string]string `json:"highlights"`
+}
+
+// FacetValue is one option of a facet with the number of properties that have it.
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // Display name when the value is an ID
+	Count int64  `json:"count"`
+}
+
+// PropertyFacets counts the properties behind each filter option. Each facet applies every
+// active filter except its own, so picking a district still shows the other districts.
+type PropertyFacets struct {
+	District          []FacetValue         `json:"district"`
+	Area              []FacetValue         `json:"area"`
+	PropertyType      []FacetValue         `json:"property_type"`
+	ConstructionStage []FacetValue         `json:"construction_stage"`
+	ListingType       []FacetValue         `json:"listing_type"`
+	Rooms             []FacetValue         `json:"rooms"`
+	Agent             []FacetValue         `json:"agent"`
+	Price             []DistributionBucket `json:"price"`
+}
+
+// Suggestion is an autocomplete entry for the search box.
+type Suggestion struct {
+	Kind   string  `json:"kind"` // district, area, sub_area, agent or design
+	Text   string  `json:"text"`
+	Count  int64   `json:"count"`  // Listings behind this suggestion
+	Score  float64 `json:"score"`  // Trigram word similarity to the typed text
+	Prefix bool    `json:"prefix"` // Whether the text starts with what was typed
+}
+
+// SearchIndexResult summarises a rebuild of the full-text search index.
+type SearchIndexResult struct {
+	Updated int64 `json:"updated"`
+}
+
+// NormaliseSizesResult summarises a run that recomputes square-metre sizes for stored properties.
+type NormaliseSizesResult struct {
+	Processed    int `json:"processed"`
+	Updated      int `json:"updated"`
+	Unrecognised int `json:"unrecognised"` // Properties with at least one size in an unknown unit
 }
@@ -0,0 +1,29 @@
+package schema
+
+import "time"
+
+// QualityIssueFilter defines the query parameters for GET /quality/issues.
+type QualityIssueFilter struct {
+	PropertyID *uint   `query:"propertyId"`
+	Code       *string `query:"code"`
+	Kind       *string `query:"kind"`     // rule or outlier
+	Severity   *string `query:"severity"` // warning or error
+	District   *string `query:"district"`
+}
+
+// QualityIssueResponse is a quality flag as shown on a property.
+type QualityIssueResponse struct {
+	Code       string    `json:"code"`
+	Kind       string    `json:"kind"`
+	Severity   string    `json:"severity"`
+	Field      string    `json:"field"`
+	Message    string    `json:"message"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// QualityScanResult summarises a full run of the quality checks.
+type QualityScanResult struct {
+	Properties    int `json:"properties"`
+	RuleIssues    int `json:"ruleIssues"`
+	OutlierIssues int `json:"outlierIssues"`
+}
@@ -0,0 +1,65 @@
+package schema
+
+import "time"
+
+// SavedSearchRequest creates or replaces a saved search.
+type SavedSearchRequest struct {
+	UserID     uint           `json:"user_id"` // Only managers may set it, everyone else's searches are their own
+	Name       string         `json:"name" validate:"required,max=100"`
+	SearchTerm string         `json:"search_term"`
+	Filter     PropertyFilter `json:"filter"`
+	Channel    string         `json:"channel" validate:"required,oneof=email webhook"`
+	Target     string         `json:"target" validate:"required"` // Email address or http(s) webhook URL
+	Active     *bool          `json:"active"`                     // Defaults to true
+}
+
+// SavedSearchFilter defines the query parameters for GET /saved-searches.
+type SavedSearchFilter struct {
+	UserID *uint `query:"userId"`
+	Active *bool `query:"active"`
+}
+
+type SavedSearchResponse struct {
+	ID            uint           `json:"id"`
+	UserID        uint           `json:"user_id"`
+	Name          string         `json:"name"`
+	SearchTerm    string         `json:"search_term"`
+	Filter        PropertyFilter `json:"filter"`
+	Channel       string         `json:"channel"`
+	Target        string         `json:"target"`
+	Active        bool           `json:"active"`
+	LastMatchedAt *time.Time     `json:"last_matched_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// NotificationFilter defines the query parameters for GET /notifications.
+type NotificationFilter struct {
+	SavedSearchID *uint   `query:"savedSearchId"`
+	PropertyID    *uint   `query:"propertyId"`
+	Status        *string `query:"status"` // pending, sent or failed
+}
+
+// AlertMessage is what a notifier delivers, and the body of a webhook call.
+type AlertMessage struct {
+	Event           string           `json:"event"` // Always saved_search.match
+	NotificationID  uint             `json:"notificationId"`
+	SavedSearchID   uint             `json:"savedSearchId"`
+	SavedSearchName string           `json:"savedSearchName"`
+	Property        PropertyResponse `json:"property"`
+}
+
+// AlertRunResult summarises checking saved searches against a set of properties.
+type AlertRunResult struct {
+	Properties int                         `json:"properties"` // Properties checked
+	Searches   int                         `json:"searches"`   // Active saved searches
+	Matches    int                         `json:"matches"`    // New notifications recorded
+	Delivery   *NotificationDeliveryResult `json:"delivery,omitempty"`
+}
+
+// NotificationDeliveryResult summarises a pass over the pending notifications.
+type NotificationDeliveryResult struct {
+	Sent   int `json:"sent"`
+	Retry  int `json:"retry"`  // Failed this time, tried again on the next pass
+	Failed int `json:"failed"` // Failed for the last time
+}
@@ -0,0 +1,90 @@
+package schema
+
+import "time"
+
+// PriceSummary holds the spread of a price measure within a group. Values are nil when
+// the group has no priced listings.
+type PriceSummary struct {
+	Avg *float64 `json:"avg"`
+	Min *float64 `json:"min"`
+	P10 *float64 `json:"p10"`
+	P50 *float64 `json:"p50"`
+	P90 *float64 `json:"p90"`
+	Max *float64 `json:"max"`
+}
+
+// DaysOnMarketSummary measures how long listings have been (or were) on the market, from
+// approval (creation when never approved). Active listings are measured to now. There is no
+// sale date, so completed sales are measured to their last update.
+type DaysOnMarketSummary struct {
+	Avg *float64 `json:"avg"`
+	P50 *float64 `json:"p50"`
+}
+
+// DistributionBucket counts listings whose price falls in [Min, Max). A nil bound is open.
+type DistributionBucket struct {
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+// RoomCount counts listings with a given number of rooms, nil for listings without one.
+type RoomCount struct {
+	Rooms *int  `json:"rooms"`
+	Count int64 `json:"count"`
+}
+
+// MarketStatsGroup is the statistics for one combination of the groupBy dimensions.
+type MarketStatsGroup struct {
+	Key                 map[string]*string   `json:"key"`
+	ListingCount        int64                `json:"listingCount"`
+	PricedCount         int64                `json:"pricedCount"`
+	ActiveCount         int64                `json:"activeCount"`
+	SoldCount           int64                `json:"soldCount"`
+	Price               PriceSummary         `json:"price"`
+	BuildingPricePerSqm PriceSummary         `json:"buildingPricePerSqm"`
+	LandPricePerSqm     PriceSummary         `json:"landPricePerSqm"`
+	DaysOnMarket        DaysOnMarketSummary  `json:"daysOnMarket"`
+	PriceDistribution   []DistributionBucket `json:"priceDistribution"`
+	RoomDistribution    []RoomCount          `json:"roomDistribution"`
+}
+
+// MarketStatsResponse is returned by GET /stats/market.
+type MarketStatsResponse struct {
+	GroupBy       []string           `json:"groupBy"`
+	TotalListings int64              `json:"totalListings"`
+	Groups        []MarketStatsGroup `json:"groups"`
+}
+
+// PriceIndexPointResponse is one period of a price index series.
+type PriceIndexPointResponse struct {
+	PeriodStart       string   `json:"periodStart"` // YYYY-MM-DD
+	ListingCount      int64    `json:"listingCount"`
+	MedianPrice       *float64 `json:"medianPrice"`
+	MedianPricePerSqm *float64 `json:"medianPricePerSqm"`
+	MedianIndex       *float64 `json:"medianIndex"`
+	HedonicIndex      *float64 `json:"hedonicIndex"`
+}
+
+// PriceIndexSeries is the index of one district at one interval.
+type PriceIndexSeries struct {
+	District string                    `json:"district"`
+	Interval string                    `json:"interval"`
+	Points   []PriceIndexPointResponse `json:"points"`
+}
+
+// PriceIndexResponse is returned by GET /stats/price-index.
+type PriceIndexResponse struct {
+	Interval    string             `json:"interval"`
+	RefreshedAt *time.Time         `json:"refreshedAt"`
+	Series      []PriceIndexSeries `json:"series"`
+}
+
+// PriceIndexRefreshResult summarises a rebuild of the price index tables.
+type PriceIndexRefreshResult struct {
+	Listings      int       `json:"listings"`
+	Districts     int       `json:"districts"`
+	Points        int       `json:"points"`
+	HedonicSeries int       `json:"hedonicSeries"` // Series where the regression could be fitted
+	RefreshedAt   time.Time `json:"refreshedAt"`
+}
@@ -1,5 +1,7 @@
 package schema
 
+import "fmt"
+
 // CustomError represents a structured API error response.
 type CustomError struct {
 	StatusCode int    `json:"statusCode"`
@@ -9,7 +11,10 @@ type CustomError struct {
 
 // Error implements error.
 func (c *CustomError) Error() string {
-	panic("unimplemented")
+	if c.Details != "" {
+		return fmt.Sprintf("%s: %s", c.Message, c.Details)
+	}
+	return c.Message
 }
 
 // PaginationRequest holds pagination parameters from the request query.
@@ -25,4 +30,22 @@ type PaginatedResponse struct {
 	PageSize   int         `json:"pageSize"`
 	TotalItems int64       `json:"totalItems"`
 	TotalPages int         `json:"totalPages"`
+	Facets     interface{} `json:"facets,omitempty"` // Set when the request asks for facets
+}
+
+// CursorRequest holds keyset pagination parameters. An empty Cursor starts at the first row.
+type CursorRequest struct {
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+	Total  string `query:"total"` // estimate (default), exact or none
+}
+
+// CursorPaginatedResponse wraps list responses fetched with a cursor. NextCursor is null on the last page.
+type CursorPaginatedResponse struct {
+	Data           interface{} `json:"data"`
+	Limit          int         `json:"limit"`
+	NextCursor     *string     `json:"nextCursor"`
+	TotalItems     *int64      `json:"totalItems,omitempty"`     // Left out when total=none
+	TotalEstimated bool        `json:"totalEstimated,omitempty"` // TotalItems is the planner's estimate
+	Facets         interface{} `json:"facets,omitempty"`
 }
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+)
+
+// CreateValuationRequest records a valuation against a property.
+type CreateValuationRequest struct {
+	PropertyID        uint       `json:"property_id" validate:"required"`
+	ValuerID          *uint      `json:"valuer_id"`
+	ValuationDate     *time.Time `json:"valuation_date"` // Defaults to now
+	Method            string     `json:"method" validate:"required,oneof=cost comparison income"`
+	MarketValue       *float64   `json:"market_value" validate:"omitempty,gte=0"`
+	ForcedSaleValue   *float64   `json:"forced_sale_value" validate:"omitempty,gte=0"`
+	InsuranceValue    *float64   `json:"insurance_value" validate:"omitempty,gte=0"`
+	Notes             string     `json:"notes"`
+	ParentValuationID *uint      `json:"parent_valuation_id"`
+}
+
+// UpdateValuationRequest changes only the fields that are present.
+type UpdateValuationRequest struct {
+	ValuerID          *uint      `json:"valuer_id"`
+	ValuationDate     *time.Time `json:"valuation_date"`
+	Method            *string    `json:"method" validate:"omitempty,oneof=cost comparison income"`
+	MarketValue       *float64   `json:"market_value" validate:"omitempty,gte=0"`
+	ForcedSaleValue   *float64   `json:"forced_sale_value" validate:"omitempty,gte=0"`
+	InsuranceValue    *float64   `json:"insurance_value" validate:"omitempty,gte=0"`
+	Notes             *string    `json:"notes"`
+	ParentValuationID *uint      `json:"parent_valuation_id"`
+}
+
+// ValuationFilter defines the query parameters for listing valuations.
+type ValuationFilter struct {
+	PropertyID *uint   `query:"propertyId"`
+	ValuerID   *uint   `query:"valuerId"`
+	Method     *string `query:"method"`
+}
+
+// ValuationNode is a valuation with the revaluations that descend from it.
+type ValuationNode struct {
+	models.Valuation
+	Children []ValuationNode `json:"children"`
+}
+
+// ValuationTreeResponse is the revaluation history of a property, oldest roots first.
+type ValuationTreeResponse struct {
+	PropertyID uint            `json:"propertyId"`
+	Count      int             `json:"count"`
+	Valuations []ValuationNode `json:"valuations"`
+}
+
+// CostApproachRequest holds optional overrides for a cost-approach valuation.
+// Anything left out is taken from the property record and the configured rates.
+type CostApproachRequest struct {
+	DepreciationMethod string   `json:"depreciation_method" validate:"omitempty,oneof=straight_line age_life"`
+	RatePerSqm         *float64 `json:"rate_per_sqm" validate:"omitempty,gt=0"`
+	BuildingAreaSqm    *float64 `json:"building_area_sqm" validate:"omitempty,gt=0"`
+	Age                *int     `json:"age" validate:"omitempty,gte=0"`
+	Eul                *int     `json:"eul" validate:"omitempty,gt=0"`
+	Rel                *int     `json:"rel" validate:"omitempty,gte=0"`
+	LandValue          *float64 `json:"land_value" validate:"omitempty,gte=0"`
+	LandRatePerSqm     *float64 `json:"land_rate_per_sqm" validate:"omitempty,gte=0"`
+	ValuerID           *uint    `json:"valuer_id"`
+	ParentValuationID  *uint    `json:"parent_valuation_id"` // Set when this is a revaluation
+	Notes              string   `json:"notes"`
+}
+
+// IncomeApproachRequest holds the inputs for an income-approach valuation.
+// Rent and cap rate left out are derived from comparables in the district, vacancy and operating
+// expenses from the configured defaults.
+type IncomeApproachRequest struct {
+	GrossAnnualRent       *float64 `json:"gross_annual_rent" validate:"omitempty,gt=0"`
+	VacancyRate           *float64 `json:"vacancy_rate" validate:"omitempty,gte=0,lt=1"`
+	OperatingExpenses     *float64 `json:"operating_expenses" validate:"omitempty,gte=0"`           // Annual amount
+	OperatingExpenseRatio *float64 `json:"operating_expense_ratio" validate:"omitempty,gte=0,lt=1"` // Share of effective gross income
+	CapRate               *float64 `json:"cap_rate" validate:"omitempty,gt=0,lt=1"`
+	ValuerID              *uint    `json:"valuer_id"`
+	ParentValuationID     *uint    `json:"parent_valuation_id"`
+	Notes                 string   `json:"notes"`
+}
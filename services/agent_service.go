@@ -0,0 +1,228 @@
+// services/agent_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+type AgentService struct {
+	DB *gorm.DB
+}
+
+func NewAgentService(db *gorm.DB) *AgentService {
+	return &AgentService{DB: db}
+}
+
+func (s *AgentService) WithContext(ctx context.Context) *AgentService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// agreementFlag stores the agreement the way the upstream API sends it.
+func agreementFlag(signed bool) string {
+	if signed {
+		return "1"
+	}
+	return "0"
+}
+
+// isTruthy is the Go side of sqlTruthy.
+func isTruthy(flag string) bool {
+	switch strings.ToLower(strings.TrimSpace(flag)) {
+	case "1", "true", "yes", "y":
+		return true
+	}
+	return false
+}
+
+// applyAgentRequest checks the user and copies the request onto the model. Bank details are
+// never returned, so they are only changed when sent, leaving them out keeps what is stored.
+// The agent is marked as edited here so the sync leaves it alone from then on.
+func (s *AgentService) applyAgentRequest(agent *models.Agent, req *schema.AgentRequest) error {
+	var user models.User
+	if err := s.DB.Select("id").First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("User")
+		}
+		return fmt.Errorf("database error retrieving user: %w", err)
+	}
+
+	// One agent profile per user
+	var existing int64
+	query := s.DB.Model(&models.Agent{}).Where("user_id = ?", req.UserID)
+	if agent.ID != 0 {
+		query = query.Where("id <> ?", agent.ID)
+	}
+	if err := query.Count(&existing).Error; err != nil {
+		return fmt.Errorf("database error checking agents: %w", err)
+	}
+	if existing > 0 {
+		return utils.NewAPIError(http.StatusConflict, "Agent already exists", fmt.Sprintf("User %d already has an agent profile.", req.UserID))
+	}
+
+	agent.UserID = req.UserID
+	agent.Phone1 = strings.TrimSpace(req.Phone1)
+	agent.Phone2 = req.Phone2
+	agent.Headline1 = req.Headline1
+	agent.Headline2 = req.Headline2
+	agent.About = req.About
+	if req.IsAgreementSigned != nil {
+		agent.IsAgreementSigned = agreementFlag(*req.IsAgreementSigned)
+	} else if agent.ID == 0 {
+		agent.IsAgreementSigned = agreementFlag(false)
+	}
+	agent.AgentType = strings.TrimSpace(req.AgentType)
+	agent.CoverageArea = req.CoverageArea
+	agent.Linkedin = req.Linkedin
+	agent.Address = req.Address
+	if req.BankName != nil {
+		agent.BankName = req.BankName
+	}
+	if req.AccountName != nil {
+		agent.AccountName = req.AccountName
+	}
+	if req.AccountNumber != nil {
+		agent.AccountNumber = req.AccountNumber
+	}
+	if req.AccountType != nil {
+		agent.AccountType = req.AccountType
+	}
+	if req.AccountBranch != nil {
+		agent.AccountBranch = req.AccountBranch
+	}
+	agent.LocalEdit = true
+	return nil
+}
+
+// CreateAgent stores a new agent for an existing user.
+func (s *AgentService) CreateAgent(req *schema.AgentRequest) (*schema.AgentDetailResponse, error) {
+	var agent models.Agent
+	if err := s.applyAgentRequest(&agent, req); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(&agent).Error; err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	return s.GetAgentByID(agent.ID)
+}
+
+// GetAgents retrieves agents with their users, ordered by name.
+func (s *AgentService) GetAgents(pag schema.PaginationRequest, filter schema.AgentFilter) ([]schema.AgentDetailResponse, int64, error) {
+	query := s.DB.Model(&models.Agent{}).
+		Joins("LEFT JOIN users ON users.id = agents.user_id AND users.deleted_at IS NULL")
+	if filter.AgentType != nil && *filter.AgentType != "" {
+		query = query.Where("LOWER(agents.agent_type) = LOWER(?)", strings.TrimSpace(*filter.AgentType))
+	}
+	if filter.CoverageArea != nil && *filter.CoverageArea != "" {
+		query = query.Where("agents.coverage_area ILIKE ?", "%"+escapeLike(strings.TrimSpace(*filter.CoverageArea))+"%")
+	}
+	if filter.AgreementSigned != nil {
+		if *filter.AgreementSigned {
+			query = query.Where(sqlTruthy("agents.is_agreement_signed"))
+		} else {
+			query = query.Where("NOT " + sqlTruthy("agents.is_agreement_signed"))
+		}
+	}
+	if filter.Name != nil && *filter.Name != "" {
+		query = query.Where("users.name ILIKE ?", "%"+escapeLike(strings.TrimSpace(*filter.Name))+"%")
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count agents: %w", err)
+	}
+
+	var agents []models.Agent
+	err := query.Select("agents.*").
+		Order("users.name ASC NULLS LAST, agents.id ASC").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Preload("User").
+		Find(&agents).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve agents: %w", err)
+	}
+
+	responses := make([]schema.AgentDetailResponse, len(agents))
+	for i := range agents {
+		responses[i] = mapAgentToResponse(&agents[i])
+	}
+	return responses, totalItems, nil
+}
+
+func (s *AgentService) findAgent(id uint) (*models.Agent, error) {
+	var agent models.Agent
+	if err := s.DB.Preload("User").First(&agent, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Agent")
+		}
+		return nil, fmt.Errorf("database error retrieving agent: %w", err)
+	}
+	return &agent, nil
+}
+
+// GetAgentByID retrieves a single agent with the user profile.
+func (s *AgentService) GetAgentByID(id uint) (*schema.AgentDetailResponse, error) {
+	agent, err := s.findAgent(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := mapAgentToResponse(agent)
+	return &resp, nil
+}
+
+// UpdateAgent replaces an agent's details.
+func (s *AgentService) UpdateAgent(id uint, req *schema.AgentRequest) (*schema.AgentDetailResponse, error) {
+	agent, err := s.findAgent(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyAgentRequest(agent, req); err != nil {
+		return nil, err
+	}
+	// Omit the preloaded user so a changed user_id is not overwritten by the old association
+	if err := s.DB.Omit("User").Save(agent).Error; err != nil {
+		return nil, fmt.Errorf("failed to update agent: %w", err)
+	}
+	return s.GetAgentByID(agent.ID)
+}
+
+func mapAgentToResponse(a *models.Agent) schema.AgentDetailResponse {
+	resp := schema.AgentDetailResponse{
+		ID:                a.ID,
+		UserID:            a.UserID,
+		Phone1:            a.Phone1,
+		Phone2:            a.Phone2,
+		Headline1:         a.Headline1,
+		Headline2:         a.Headline2,
+		About:             a.About,
+		IsAgreementSigned: isTruthy(a.IsAgreementSigned),
+		AgentType:         a.AgentType,
+		CoverageArea:      a.CoverageArea,
+		Linkedin:          a.Linkedin,
+		Address:           a.Address,
+		CreatedAt:         a.CreatedAt,
+		UpdatedAt:         a.UpdatedAt,
+	}
+	if a.User.ID != 0 {
+		resp.Name = a.User.Name
+		resp.User = &schema.UserResponse{
+			ID:              a.User.ID,
+			Name:            a.User.Name,
+			Email:           a.User.Email,
+			Phone:           a.User.Phone,
+			Role:            a.User.Role,
+			ProfileImageURL: a.User.ProfileImageStorageURL,
+		}
+	}
+	return resp
+}
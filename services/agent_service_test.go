@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapAgentToResponse_NoBankDetails(t *testing.T) {
+	agent := models.Agent{
+		ID:                3,
+		UserID:            8,
+		IsAgreementSigned: "Yes",
+		BankName:          strPtr("National Bank"),
+		AccountName:       strPtr("J Banda"),
+		AccountNumber:     strPtr("100200300"),
+		AccountType:       strPtr("Current"),
+		AccountBranch:     strPtr("Capital City"),
+		User:              models.User{ID: 8, Name: "John Banda", Email: "john@example.com"},
+	}
+
+	resp := mapAgentToResponse(&agent)
+	assert.True(t, resp.IsAgreementSigned)
+	assert.Equal(t, "John Banda", resp.Name)
+
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	for _, key := range []string{"bank_name", "account_name", "account_number", "account_type", "account_branch"} {
+		assert.NotContains(t, fields, key)
+	}
+	for _, secret := range []string{"National Bank", "100200300", "Capital City"} {
+		assert.NotContains(t, string(data), secret)
+	}
+}
+
+func TestApplyAgentRequest_KeepsBankDetailsLeftOut(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT "id" FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(8, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "agents" WHERE user_id = \$1 AND id <> \$2`).
+		WithArgs(8, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	agent := models.Agent{ID: 3, UserID: 8, IsAgreementSigned: "1", BankName: strPtr("National Bank"), AccountNumber: strPtr("100200300")}
+	err := NewAgentService(db).applyAgentRequest(&agent, &schema.AgentRequest{
+		UserID:        8,
+		Phone1:        " 0999 123 456 ",
+		AgentType:     "Broker",
+		AccountNumber: strPtr("400500600"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "National Bank", *agent.BankName, "left out, so kept")
+	assert.Equal(t, "400500600", *agent.AccountNumber)
+	assert.Equal(t, "0999 123 456", agent.Phone1)
+	assert.Equal(t, "1", agent.IsAgreementSigned, "an update without the flag keeps it")
+	assert.True(t, agent.LocalEdit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,276 @@
+// services/agent_stats_service.go
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// agentLeaderboardOrders whitelists the sortBy values. Days to approval ranks the fastest first,
+// everything else the highest first.
+var agentLeaderboardOrders = map[string]string{
+	"listings":         "listings DESC",
+	"approval_rate":    "approval_rate DESC NULLS LAST",
+	"days_to_approval": "days_to_approval_avg ASC NULLS LAST",
+	"completed_sales":  "completed_sales DESC",
+	"accepted_offers":  "accepted_offers DESC",
+	"views":            "total_views DESC",
+}
+
+const (
+	DefaultAgentLeaderboardSort  = "listings"
+	DefaultAgentLeaderboardLimit = 20
+	MaxAgentLeaderboardLimit     = 100
+)
+
+type agentPerformanceRow struct {
+	Listings       int64    `gorm:"column:listings"`
+	Approved       int64    `gorm:"column:approved"`
+	ApprovalRate   *float64 `gorm:"column:approval_rate"`
+	DaysAvg        *float64 `gorm:"column:days_to_approval_avg"`
+	DaysP50        *float64 `gorm:"column:days_to_approval_p50"`
+	CompletedSales int64    `gorm:"column:completed_sales"`
+	AcceptedOffers int64    `gorm:"column:accepted_offers"`
+	TotalViews     int64    `gorm:"column:total_views"`
+}
+
+// Rows hold the aggregates in an exported field tagged embedded, GORM skips unexported embedded structs
+type agentDistrictRow struct {
+	District    *string             `gorm:"column:district"`
+	Performance agentPerformanceRow `gorm:"embedded"`
+}
+
+type agentLeaderboardRow struct {
+	AgentID     uint                `gorm:"column:agent_id"`
+	Name        string              `gorm:"column:name"`
+	AgentType   string              `gorm:"column:agent_type"`
+	Performance agentPerformanceRow `gorm:"embedded"`
+}
+
+// agentPerformanceSelects are the aggregates behind AgentPerformance. Days to approval only
+// counts approved listings with an approval time after submission.
+func agentPerformanceSelects() string {
+	approved := sqlTruthy("properties.is_approved")
+	daysToApproval := "EXTRACT(EPOCH FROM (properties.approved_at - properties.created_at)) / 86400"
+	timed := fmt.Sprintf("FILTER (WHERE %s AND properties.approved_at >= properties.created_at)", approved)
+	return strings.Join([]string{
+		"COUNT(*) AS listings",
+		fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS approved", approved),
+		fmt.Sprintf("(COUNT(*) FILTER (WHERE %s))::float / NULLIF(COUNT(*), 0) AS approval_rate", approved),
+		fmt.Sprintf("AVG(%s) %s AS days_to_approval_avg", daysToApproval, timed),
+		fmt.Sprintf("percentile_cont(0.5) WITHIN GROUP (ORDER BY %s) %s AS days_to_approval_p50", daysToApproval, timed),
+		fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS completed_sales", sqlTruthy("properties.is_sale_completed")),
+		fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS accepted_offers", sqlTruthy("properties.has_accepted_offer")),
+		"COALESCE(SUM(properties.views), 0) AS total_views",
+	}, ", ")
+}
+
+func (r agentPerformanceRow) toResponse() schema.AgentPerformance {
+	return schema.AgentPerformance{
+		Listings:             r.Listings,
+		Approved:             r.Approved,
+		ApprovalRate:         roundPtr(r.ApprovalRate, 4),
+		AvgDaysToApproval:    roundPtr(r.DaysAvg, 1),
+		MedianDaysToApproval: roundPtr(r.DaysP50, 1),
+		CompletedSales:       r.CompletedSales,
+		AcceptedOffers:       r.AcceptedOffers,
+		TotalViews:           r.TotalViews,
+	}
+}
+
+// agentPeriodScope limits listings to those submitted between from and to, both days included.
+func agentPeriodScope(from, to *time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if from != nil {
+			db = db.Where("properties.created_at >= ?", *from)
+		}
+		if to != nil {
+			db = db.Where("properties.created_at < ?", to.AddDate(0, 0, 1))
+		}
+		return db
+	}
+}
+
+func validateAgentPeriod(from, to *time.Time) error {
+	if from != nil && to != nil && to.Before(*from) {
+		return utils.NewBadRequestError("The to date must not be before the from date")
+	}
+	return nil
+}
+
+func formatDatePtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format("2006-01-02")
+	return &s
+}
+
+// parseCoverageAreas splits a coverage area such as "Lilongwe, Dedza / Salima" into districts.
+func parseCoverageAreas(coverage *string) []string {
+	areas := []string{}
+	if coverage == nil {
+		return areas
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.FieldsFunc(*coverage, func(r rune) bool { return r == ',' || r == ';' || r == '/' || r == '|' }) {
+		part = strings.TrimSpace(part)
+		if part != "" && !seen[strings.ToLower(part)] {
+			seen[strings.ToLower(part)] = true
+			areas = append(areas, part)
+		}
+	}
+	return areas
+}
+
+// coverageIndex returns the coverage area naming the district, or -1. Names are compared
+// ignoring case and either may contain the other, so "Lilongwe" covers "Lilongwe Urban".
+func coverageIndex(district string, areas []string) int {
+	d := strings.ToLower(strings.TrimSpace(district))
+	if d == "" {
+		return -1
+	}
+	for i, area := range areas {
+		a := strings.ToLower(area)
+		if strings.Contains(d, a) || strings.Contains(a, d) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetAgentStats computes an agent's performance over a period, overall and per district.
+func (s *AgentService) GetAgentStats(id uint, from, to *time.Time) (*schema.AgentStatsResponse, error) {
+	if err := validateAgentPeriod(from, to); err != nil {
+		return nil, err
+	}
+	agent, err := s.findAgent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	base := func() *gorm.DB {
+		return s.DB.Model(&models.Property{}).
+			Where("properties.agent_id = ?", id).
+			Scopes(agentPeriodScope(from, to))
+	}
+
+	var totals agentPerformanceRow
+	if err := base().Select(agentPerformanceSelects()).Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute agent statistics: %w", err)
+	}
+
+	var rows []agentDistrictRow
+	err = base().Joins("LEFT JOIN locations ON locations.property_id = properties.id").
+		Select("locations.district AS district, " + agentPerformanceSelects()).
+		Group("locations.district").
+		Order("listings DESC, district ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute agent district statistics: %w", err)
+	}
+
+	areas := parseCoverageAreas(agent.CoverageArea)
+	covered := make([]bool, len(areas))
+	resp := &schema.AgentStatsResponse{
+		AgentID:       agent.ID,
+		Name:          agent.User.Name,
+		AgentType:     agent.AgentType,
+		CoverageAreas: areas,
+		From:          formatDatePtr(from),
+		To:            formatDatePtr(to),
+		Totals:        totals.toResponse(),
+		Districts:     make([]schema.AgentDistrictStats, 0, len(rows)+len(areas)),
+	}
+	for _, row := range rows {
+		stats := schema.AgentDistrictStats{District: row.District, AgentPerformance: row.Performance.toResponse()}
+		if row.District != nil {
+			if i := coverageIndex(*row.District, areas); i >= 0 {
+				stats.InCoverageArea = true
+				covered[i] = true
+			}
+		}
+		if !stats.InCoverageArea {
+			resp.ListingsOutsideCoverage += row.Performance.Listings
+		}
+		resp.Districts = append(resp.Districts, stats)
+	}
+	// Coverage districts the agent listed nothing in
+	for i, area := range areas {
+		if !covered[i] {
+			district := area
+			resp.Districts = append(resp.Districts, schema.AgentDistrictStats{District: &district, InCoverageArea: true})
+		}
+	}
+	return resp, nil
+}
+
+// GetAgentLeaderboard ranks agents by one of the agentLeaderboardOrders over a period. Agents
+// without listings in the period are left out.
+func (s *AgentService) GetAgentLeaderboard(sortBy string, from, to *time.Time, agentType string, limit int) (*schema.AgentLeaderboardResponse, error) {
+	if err := validateAgentPeriod(from, to); err != nil {
+		return nil, err
+	}
+	sortBy = strings.ToLower(strings.TrimSpace(sortBy))
+	if sortBy == "" {
+		sortBy = DefaultAgentLeaderboardSort
+	}
+	order, ok := agentLeaderboardOrders[sortBy]
+	if !ok {
+		names := make([]string, 0, len(agentLeaderboardOrders))
+		for name := range agentLeaderboardOrders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Cannot rank agents by '%s', use one of %s", sortBy, strings.Join(names, ", ")))
+	}
+	if limit <= 0 {
+		limit = DefaultAgentLeaderboardLimit
+	}
+	if limit > MaxAgentLeaderboardLimit {
+		limit = MaxAgentLeaderboardLimit
+	}
+
+	query := s.DB.Model(&models.Property{}).
+		Joins("JOIN agents ON agents.id = properties.agent_id").
+		Joins("LEFT JOIN users ON users.id = agents.user_id").
+		Scopes(agentPeriodScope(from, to))
+	if agentType = strings.TrimSpace(agentType); agentType != "" {
+		query = query.Where("LOWER(agents.agent_type) = LOWER(?)", agentType)
+	}
+
+	var rows []agentLeaderboardRow
+	err := query.Select("agents.id AS agent_id, COALESCE(MAX(users.name), '') AS name, MAX(agents.agent_type) AS agent_type, " + agentPerformanceSelects()).
+		Group("agents.id").
+		Order(order).
+		Order("listings DESC, agents.id ASC"). // Ties
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute agent leaderboard: %w", err)
+	}
+
+	resp := &schema.AgentLeaderboardResponse{
+		SortBy: sortBy,
+		From:   formatDatePtr(from),
+		To:     formatDatePtr(to),
+		Agents: make([]schema.AgentLeaderboardEntry, len(rows)),
+	}
+	for i, row := range rows {
+		resp.Agents[i] = schema.AgentLeaderboardEntry{
+			Rank:             i + 1,
+			AgentID:          row.AgentID,
+			Name:             row.Name,
+			AgentType:        row.AgentType,
+			AgentPerformance: row.Performance.toResponse(),
+		}
+	}
+	return resp, nil
+}
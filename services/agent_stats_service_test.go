@@ -0,0 +1,144 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoverageAreas(t *testing.T) {
+	assert.Equal(t, []string{}, parseCoverageAreas(nil))
+	assert.Equal(t, []string{}, parseCoverageAreas(strPtr(" , ; ")))
+	assert.Equal(t, []string{"Lilongwe", "Dedza", "Salima", "Mchinji"},
+		parseCoverageAreas(strPtr("Lilongwe, Dedza / Salima;  Mchinji | lilongwe")))
+}
+
+func TestCoverageIndex(t *testing.T) {
+	areas := []string{"Lilongwe Urban", "Dedza"}
+	tests := []struct {
+		district string
+		want     int
+	}{
+		{"Lilongwe", 0}, // The coverage area names a part of the district
+		{"LILONGWE URBAN", 0},
+		{" dedza ", 1},
+		{"Dedza Boma", 1},
+		{"Zomba", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, coverageIndex(tt.district, areas), tt.district)
+	}
+}
+
+func TestGetAgentLeaderboard_OrderWhitelist(t *testing.T) {
+	for sortBy, order := range agentLeaderboardOrders {
+		t.Run(sortBy, func(t *testing.T) {
+			db, recorder := setupRecordingDB(t)
+			resp, err := NewAgentService(db).GetAgentLeaderboard(" "+sortBy+" ", nil, nil, "", 500)
+			require.NoError(t, err)
+			assert.Equal(t, sortBy, resp.SortBy)
+
+			statements := recorder.touching("GROUP BY")
+			require.Len(t, statements, 1)
+			assert.Contains(t, statements[0], "ORDER BY "+order+",listings DESC, agents.id ASC")
+			assert.Contains(t, statements[0], "LIMIT 100", "the limit is capped")
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		db, recorder := setupRecordingDB(t)
+		resp, err := NewAgentService(db).GetAgentLeaderboard("", nil, nil, "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultAgentLeaderboardSort, resp.SortBy)
+		assert.Contains(t, recorder.touching("GROUP BY")[0], "LIMIT 20")
+	})
+
+	t.Run("anything else is refused before reaching SQL", func(t *testing.T) {
+		db, recorder := setupRecordingDB(t)
+		for _, sortBy := range []string{"total_views DESC", "listings; DROP TABLE agents", "name"} {
+			_, err := NewAgentService(db).GetAgentLeaderboard(sortBy, nil, nil, "", 0)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "Cannot rank agents by")
+		}
+		assert.Empty(t, recorder.touching("agents"))
+	})
+}
+
+func TestGetAgentStats_AggregatesAndCoverage(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "agents" WHERE "agents"."id" = \$1`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "agent_type", "coverage_area"}).
+			AddRow(3, 12, "Company", "Lilongwe, Dedza"))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(12, "Chikondi Phiri"))
+
+	// Days to approval only count approved listings approved after they were submitted
+	performance := regexp.QuoteMeta(`COUNT(*) AS listings, COUNT(*) FILTER (WHERE LOWER(COALESCE(properties.is_approved, '')) IN ('1', 'true', 'yes', 'y')) AS approved, `) +
+		`.*` + regexp.QuoteMeta(`AVG(EXTRACT(EPOCH FROM (properties.approved_at - properties.created_at)) / 86400) FILTER (WHERE LOWER(COALESCE(properties.is_approved, '')) IN ('1', 'true', 'yes', 'y') AND properties.approved_at >= properties.created_at) AS days_to_approval_avg`) +
+		`.*` + regexp.QuoteMeta(`COUNT(*) FILTER (WHERE LOWER(COALESCE(properties.is_sale_completed, '')) IN ('1', 'true', 'yes', 'y')) AS completed_sales`) +
+		`.*` + regexp.QuoteMeta(`COALESCE(SUM(properties.views), 0) AS total_views`)
+	columns := []string{"listings", "approved", "approval_rate", "days_to_approval_avg", "days_to_approval_p50", "completed_sales", "accepted_offers", "total_views"}
+	mock.ExpectQuery(`^SELECT ` + performance + regexp.QuoteMeta(` FROM "properties" WHERE properties.agent_id = $1 AND properties.created_at >= $2 AND properties.created_at < $3`) + `$`).
+		WithArgs(3, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, 4, 0.8, 6.04, 5, 2, 3, 410))
+	mock.ExpectQuery(`^SELECT locations.district AS district, ` + performance +
+		regexp.QuoteMeta(` FROM "properties" LEFT JOIN locations ON locations.property_id = properties.id WHERE properties.agent_id = $1 AND properties.created_at >= $2 AND properties.created_at < $3 GROUP BY "locations"."district" ORDER BY listings DESC, district ASC`) + `$`).
+		WillReturnRows(sqlmock.NewRows(append([]string{"district"}, columns...)).
+			AddRow("LILONGWE", 3, 3, 1.0, 4.56, 4, 2, 2, 300).
+			AddRow("ZOMBA", 1, 0, 0.0, nil, nil, 0, 0, 10).
+			AddRow(nil, 1, 1, 1.0, 12, 12, 0, 1, 100))
+
+	from, to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	stats, err := NewAgentService(db).GetAgentStats(3, &from, &to)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "Chikondi Phiri", stats.Name)
+	assert.Equal(t, "2025-06-30", *stats.To)
+	assert.Equal(t, schema.AgentPerformance{
+		Listings: 5, Approved: 4, ApprovalRate: floatPtr(0.8), AvgDaysToApproval: floatPtr(6), MedianDaysToApproval: floatPtr(5),
+		CompletedSales: 2, AcceptedOffers: 3, TotalViews: 410,
+	}, stats.Totals)
+
+	// Zomba and the listing without a district are outside the coverage, Dedza has no listings
+	require.Len(t, stats.Districts, 4)
+	assert.Equal(t, "LILONGWE", *stats.Districts[0].District)
+	assert.True(t, stats.Districts[0].InCoverageArea)
+	assert.Equal(t, int64(2), stats.Districts[0].CompletedSales)
+	assert.Equal(t, 4.6, *stats.Districts[0].AvgDaysToApproval)
+	assert.False(t, stats.Districts[1].InCoverageArea)
+	assert.Nil(t, stats.Districts[1].AvgDaysToApproval)
+	assert.Nil(t, stats.Districts[2].District)
+	assert.Equal(t, schema.AgentDistrictStats{District: strPtr("Dedza"), InCoverageArea: true}, stats.Districts[3])
+	assert.Equal(t, int64(2), stats.ListingsOutsideCoverage)
+}
+
+func TestGetAgentLeaderboard_MapsRows(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT agents.id AS agent_id, COALESCE(MAX(users.name), '') AS name, MAX(agents.agent_type) AS agent_type, COUNT(*) AS listings`) +
+		`.*` + regexp.QuoteMeta(`WHERE LOWER(agents.agent_type) = LOWER($1) GROUP BY "agents"."id" ORDER BY completed_sales DESC,listings DESC, agents.id ASC LIMIT $2`)).
+		WithArgs("Company", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"agent_id", "name", "agent_type", "listings", "completed_sales", "days_to_approval_avg"}).
+			AddRow(3, "Chikondi Phiri", "Company", 5, 2, 6.04).
+			AddRow(8, "", "Company", 9, 1, nil))
+
+	resp, err := NewAgentService(db).GetAgentLeaderboard("completed_sales", nil, nil, " Company ", 2)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, resp.Agents, 2)
+	assert.Equal(t, schema.AgentLeaderboardEntry{
+		Rank: 1, AgentID: 3, Name: "Chikondi Phiri", AgentType: "Company",
+		AgentPerformance: schema.AgentPerformance{Listings: 5, CompletedSales: 2, AvgDaysToApproval: floatPtr(6)},
+	}, resp.Agents[0])
+	assert.Equal(t, 2, resp.Agents[1].Rank)
+	assert.Equal(t, int64(9), resp.Agents[1].Listings)
+	assert.Nil(t, resp.Agents[1].AvgDaysToApproval)
+}
@@ -0,0 +1,254 @@
+// services/api_key_service.go
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// Keys look like hvk_<prefix>_<secret>. The prefix finds the row, the hash of the whole key
+// checks it.
+const (
+	apiKeyMarker       = "hvk"
+	apiKeyPrefixBytes  = 4  // 8 hex characters
+	apiKeySecretBytes  = 32 // 43 base64url characters
+	apiKeyTouchEvery   = time.Minute
+	apiKeyDefaultLimit = 60
+	apiKeyDefaultBurst = 20
+)
+
+// Defaults used when auth.api_keys is missing from config.yaml
+var defaultAPIKeysConfig = config.APIKeysConfig{
+	RateLimitPerMinute: apiKeyDefaultLimit,
+	Burst:              apiKeyDefaultBurst,
+}
+
+func apiKeysSettings() config.APIKeysConfig {
+	settings := defaultAPIKeysConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Auth.APIKeys
+	if cfg.RateLimitPerMinute > 0 {
+		settings.RateLimitPerMinute = cfg.RateLimitPerMinute
+	}
+	if cfg.Burst > 0 {
+		settings.Burst = cfg.Burst
+	}
+	return settings
+}
+
+type APIKeyService struct {
+	DB      *gorm.DB
+	Limiter *TokenBucketLimiter
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		DB:      db,
+		Limiter: NewTokenBucketLimiter(),
+	}
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey returns a new key and its prefix.
+func generateAPIKey() (string, string, error) {
+	prefix := make([]byte, apiKeyPrefixBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	p := hex.EncodeToString(prefix)
+	return apiKeyMarker + "_" + p + "_" + base64.RawURLEncoding.EncodeToString(secret), p, nil
+}
+
+// applyAPIKeyRequest copies the request onto the key, filling in the default rate limit.
+func (s *APIKeyService) applyAPIKeyRequest(key *models.APIKey, req *schema.APIKeyRequest) error {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return utils.NewBadRequestError("expires_at must be in the future")
+	}
+	if req.FinancialInstitutionID != nil {
+		if _, err := findInstitution(s.DB, *req.FinancialInstitutionID); err != nil {
+			return err
+		}
+	}
+	settings := apiKeysSettings()
+
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	key.Name = strings.TrimSpace(req.Name)
+	key.Scopes = scopes
+	key.RateLimitPerMinute = settings.RateLimitPerMinute
+	if req.RateLimitPerMinute != nil {
+		key.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	key.Burst = settings.Burst
+	if req.Burst != nil {
+		key.Burst = *req.Burst
+	}
+	key.ExpiresAt = req.ExpiresAt
+	key.FinancialInstitutionID = req.FinancialInstitutionID
+	return nil
+}
+
+// CreateAPIKey issues a key. The key is only returned here, only its hash is stored.
+func (s *APIKeyService) CreateAPIKey(req *schema.APIKeyRequest, createdByID uint) (*schema.CreatedAPIKeyResponse, error) {
+	var key models.APIKey
+	if err := s.applyAPIKeyRequest(&key, req); err != nil {
+		return nil, err
+	}
+	raw, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix = prefix
+	key.KeyHash = hashAPIKey(raw)
+	key.CreatedByID = createdByID
+
+	if err := s.DB.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &schema.CreatedAPIKeyResponse{APIKey: key, Key: raw}, nil
+}
+
+// GetAPIKeys retrieves API keys, newest first.
+func (s *APIKeyService) GetAPIKeys(pag schema.PaginationRequest, filter schema.APIKeyFilter) ([]models.APIKey, int64, error) {
+	query := s.DB.Model(&models.APIKey{})
+	if filter.Revoked != nil {
+		query = query.Where("revoked = ?", *filter.Revoked)
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	keys := []models.APIKey{}
+	err := query.Order("created_at DESC, id DESC").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Find(&keys).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	return keys, totalItems, nil
+}
+
+// GetAPIKeyByID retrieves a single API key.
+func (s *APIKeyService) GetAPIKeyByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.DB.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("API key")
+		}
+		return nil, fmt.Errorf("database error retrieving API key: %w", err)
+	}
+	return &key, nil
+}
+
+// UpdateAPIKey changes a key's name, scopes, rate limit and expiry. Revoked keys stay revoked.
+func (s *APIKeyService) UpdateAPIKey(id uint, req *schema.APIKeyRequest) (*models.APIKey, error) {
+	key, err := s.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyAPIKeyRequest(key, req); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Save(key).Error; err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	s.Limiter.Forget(key.ID) // Start again with the new limits
+	return key, nil
+}
+
+// RevokeAPIKey stops a key from working. It cannot be undone, issue a new key instead.
+func (s *APIKeyService) RevokeAPIKey(id uint) (*models.APIKey, error) {
+	key, err := s.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked {
+		return key, nil
+	}
+	now := time.Now()
+	key.Revoked = true
+	key.RevokedAt = &now
+	if err := s.DB.Model(key).Updates(map[string]interface{}{"revoked": true, "revoked_at": now}).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	s.Limiter.Forget(key.ID)
+	return key, nil
+}
+
+// Authenticate checks a key sent by a client and returns it when it is valid, not revoked
+// and not expired.
+func (s *APIKeyService) Authenticate(raw string) (*models.APIKey, error) {
+	parts := strings.SplitN(raw, "_", 3) // The base64url secret may hold underscores itself
+	if len(parts) != 3 || parts[0] != apiKeyMarker || parts[1] == "" {
+		return nil, unauthorized("Invalid API key")
+	}
+
+	var key models.APIKey
+	if err := s.DB.Where("prefix = ?", parts[1]).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, unauthorized("Invalid API key")
+		}
+		return nil, fmt.Errorf("database error retrieving API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(raw)), []byte(key.KeyHash)) != 1 {
+		return nil, unauthorized("Invalid API key")
+	}
+	if key.Revoked {
+		return nil, unauthorized("API key has been revoked")
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, unauthorized("API key has expired")
+	}
+	if err := checkInstitutionActive(s.DB, key.FinancialInstitutionID); err != nil {
+		return nil, err
+	}
+
+	// Recording every call would mean a write per request, once a minute is close enough
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchEvery {
+		if err := s.DB.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("Warning: failed to record use of API key %d: %v\n", key.ID, err)
+		}
+	}
+	return &key, nil
+}
+
+// TakeToken applies the key's rate limit to one request.
+func (s *APIKeyService) TakeToken(key *models.APIKey) RateLimitResult {
+	return s.Limiter.Take(key.ID, key.RateLimitPerMinute, key.Burst)
+}
@@ -0,0 +1,377 @@
+// services/auth_service.go
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Permission is something a route needs. Roles are granted sets of permissions in rolePermissions.
+type Permission string
+
+const (
+	PermReadData         Permission = "data:read"          // Listings, valuations, agents
+	PermReadStats        Permission = "stats:read"         // Market stats, the price index and agent performance
+	PermManageData       Permission = "data:manage"        // Bulk imports, clean-ups, scans, index refreshes and AVM training
+	PermWriteListings    Permission = "listings:write"     // Create and edit listings for any agent
+	PermWriteOwnListings Permission = "listings:write_own" // Create and edit listings under the user's own agent profile
+	PermWriteValuations  Permission = "valuations:write"   // Create, update and delete valuations
+	PermManageAgents     Permission = "agents:manage"      // Create agents and edit any agent
+	PermEditOwnAgent     Permission = "agents:edit_own"    // Edit the agent profile linked to the user
+	PermUseSavedSearches Permission = "saved_searches:use" // Saved searches and their notifications
+	PermSync             Permission = "sync"               // Trigger a sync with the upstream API
+	PermManageUsers      Permission = "users:manage"       // Set other users' passwords
+	PermManageAPIKeys    Permission = "api_keys:manage"    // Issue, change and revoke API keys
+	PermAllTenants       Permission = "tenants:all"        // See every financial institution's private data
+)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleAdmin: {
+		PermReadData, PermReadStats, PermManageData, PermWriteListings, PermWriteValuations,
+		PermManageAgents, PermUseSavedSearches, PermSync, PermManageUsers, PermManageAPIKeys, PermAllTenants,
+	},
+	models.RoleValuer: {PermReadData, PermReadStats, PermWriteListings, PermWriteValuations, PermUseSavedSearches},
+	models.RoleAgent:  {PermReadData, PermReadStats, PermWriteOwnListings, PermEditOwnAgent, PermUseSavedSearches},
+	models.RoleViewer: {PermReadData, PermReadStats, PermUseSavedSearches},
+}
+
+// scopePermissions is what each API key scope grants
+var scopePermissions = map[string]Permission{
+	models.ScopePropertiesRead:  PermReadData,
+	models.ScopePropertiesWrite: PermWriteListings,
+	models.ScopeSyncRun:         PermSync,
+	models.ScopeStatsRead:       PermReadStats,
+}
+
+// NormaliseRole lower-cases a role. Roles not in rolePermissions get no permissions.
+func NormaliseRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
+
+// HasPermission reports whether a role grants the permission.
+func HasPermission(role string, perm Permission) bool {
+	for _, granted := range rolePermissions[NormaliseRole(role)] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesGrant reports whether any of an API key's scopes grants the permission.
+func ScopesGrant(scopes []string, perm Permission) bool {
+	for _, scope := range scopes {
+		if scopePermissions[scope] == perm {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	minPasswordLen   = 8
+)
+
+// Defaults used when the auth section is missing from config.yaml
+var defaultAuthConfig = config.AuthConfig{
+	AccessTokenMinutes: 15,
+	RefreshTokenHours:  168,
+}
+
+func authSettings() config.AuthConfig {
+	settings := defaultAuthConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Auth
+	settings.JWTSecret = cfg.JWTSecret
+	if cfg.AccessTokenMinutes > 0 {
+		settings.AccessTokenMinutes = cfg.AccessTokenMinutes
+	}
+	if cfg.RefreshTokenHours > 0 {
+		settings.RefreshTokenHours = cfg.RefreshTokenHours
+	}
+	settings.AdminEmail = cfg.AdminEmail
+	settings.AdminPassword = cfg.AdminPassword
+	return settings
+}
+
+// tokenClaims are carried by both token types. The subject is the user ID.
+type tokenClaims struct {
+	Type string `json:"typ"`
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type AuthService struct {
+	DB         *gorm.DB
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewAuthService(db *gorm.DB) *AuthService {
+	settings := authSettings()
+	secret := []byte(settings.JWTSecret)
+	if len(secret) == 0 {
+		log.Println("Warning: auth.jwt_secret is not set, using a random secret. Tokens will stop working on restart.")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate a JWT secret: %v", err)
+		}
+	}
+	return &AuthService{
+		DB:         db,
+		secret:     secret,
+		accessTTL:  time.Duration(settings.AccessTokenMinutes) * time.Minute,
+		refreshTTL: time.Duration(settings.RefreshTokenHours) * time.Hour,
+	}
+}
+
+var errInvalidCredentials = utils.NewAPIError(http.StatusUnauthorized, "Unauthorized", "Invalid email or password")
+
+func unauthorized(details string) *schema.CustomError {
+	return utils.NewAPIError(http.StatusUnauthorized, "Unauthorized", details)
+}
+
+// Login checks the email and password and issues a token pair.
+func (s *AuthService) Login(req *schema.LoginRequest) (*schema.TokenResponse, error) {
+	var user models.User
+	err := s.DB.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(req.Email)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidCredentials
+		}
+		return nil, fmt.Errorf("database error retrieving user: %w", err)
+	}
+	// Users synced from upstream have no password here until one is set
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, errInvalidCredentials
+	}
+	if err := s.checkUserAccess(&user); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(&user)
+}
+
+// Refresh swaps a valid refresh token for a new token pair.
+func (s *AuthService) Refresh(refreshToken string) (*schema.TokenResponse, error) {
+	user, err := s.authenticate(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user)
+}
+
+// Authenticate checks an access token and returns its user as currently stored, so role
+// changes apply straight away.
+func (s *AuthService) Authenticate(accessToken string) (*models.User, error) {
+	return s.authenticate(accessToken, tokenTypeAccess)
+}
+
+func (s *AuthService) authenticate(token, tokenType string) (*models.User, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuedAt())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, unauthorized("Token has expired")
+		}
+		return nil, unauthorized("Invalid token")
+	}
+	if claims.Type != tokenType {
+		return nil, unauthorized("Expected a token of type " + tokenType)
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil || claims.IssuedAt == nil {
+		return nil, unauthorized("Invalid token")
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, unauthorized("User no longer exists")
+		}
+		return nil, fmt.Errorf("database error retrieving user: %w", err)
+	}
+	// A password change ends every session started before it. iat has whole seconds only.
+	if user.PasswordLastUpdatedAt != nil && claims.IssuedAt.Unix() < user.PasswordLastUpdatedAt.Unix() {
+		return nil, unauthorized("Token was issued before the password was changed, log in again")
+	}
+	if err := s.checkUserAccess(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userActive reports whether the user's upstream status lets them use the API.
+func userActive(user *models.User) bool {
+	return strings.EqualFold(strings.TrimSpace(user.Status), models.UserStatusActive)
+}
+
+// checkUserAccess refuses users whose account is not active, and users of an inactive financial
+// institution, admins excepted for the institution.
+func (s *AuthService) checkUserAccess(user *models.User) error {
+	if !userActive(user) {
+		return unauthorized("Your account is not active")
+	}
+	if HasPermission(user.Role, PermAllTenants) {
+		return nil
+	}
+	return checkInstitutionActive(s.DB, user.FinancialInstitutionID)
+}
+
+func (s *AuthService) signToken(user *models.User, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	claims := tokenClaims{
+		Type: tokenType,
+		Role: NormaliseRole(user.Role),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %w", tokenType, err)
+	}
+	return signed, nil
+}
+
+func (s *AuthService) issueTokens(user *models.User) (*schema.TokenResponse, error) {
+	now := time.Now()
+	access, err := s.signToken(user, tokenTypeAccess, now, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.signToken(user, tokenTypeRefresh, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+		User:         mapUserToResponse(user),
+	}, nil
+}
+
+// setPassword stores a new bcrypt hash and ends the user's existing sessions.
+func (s *AuthService) setPassword(user *models.User, password string) error {
+	if len(password) < minPasswordLen {
+		return utils.NewBadRequestError(fmt.Sprintf("Password must be at least %d characters", minPasswordLen))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	// Whole seconds, to compare with the iat of tokens issued afterwards
+	now := time.Now().Truncate(time.Second)
+	user.PasswordHash = string(hash)
+	user.PasswordLastUpdatedAt = &now
+	err = s.DB.Model(user).Updates(map[string]interface{}{
+		"password_hash":            user.PasswordHash,
+		"password_last_updated_at": now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword sets a new password for a user who knows the current one, and returns a
+// fresh token pair as the old ones stop working.
+func (s *AuthService) ChangePassword(user *models.User, req *schema.ChangePasswordRequest) (*schema.TokenResponse, error) {
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		return nil, unauthorized("Current password is incorrect")
+	}
+	if err := s.setPassword(user, req.NewPassword); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user)
+}
+
+// SetUserPassword lets an admin set any user's password, e.g. for users synced from upstream.
+func (s *AuthService) SetUserPassword(userID uint, password string) error {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("User")
+		}
+		return fmt.Errorf("database error retrieving user: %w", err)
+	}
+	return s.setPassword(&user, password)
+}
+
+// EnsureAdmin makes the configured admin_email an admin, creating the user if needed, and sets
+// admin_password when the user has no password yet. It does nothing when either is empty. The
+// role is kept as set here, the sync does not demote the admin.
+func (s *AuthService) EnsureAdmin() error {
+	settings := authSettings()
+	email := strings.TrimSpace(settings.AdminEmail)
+	if email == "" || settings.AdminPassword == "" {
+		return nil
+	}
+
+	var user models.User
+	err := s.DB.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = models.User{Name: "Administrator", Email: email, Role: models.RoleAdmin, LocalRole: true, Status: models.UserStatusActive}
+		if err := s.DB.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create admin user: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("database error retrieving admin user: %w", err)
+	} else if NormaliseRole(user.Role) != models.RoleAdmin || !user.LocalRole {
+		if err := s.DB.Model(&user).Updates(map[string]interface{}{"role": models.RoleAdmin, "local_role": true}).Error; err != nil {
+			return fmt.Errorf("failed to promote admin user: %w", err)
+		}
+	}
+
+	if user.PasswordHash == "" {
+		return s.setPassword(&user, settings.AdminPassword)
+	}
+	return nil
+}
+
+func mapUserToResponse(u *models.User) schema.UserResponse {
+	return schema.UserResponse{
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Phone:           u.Phone,
+		Role:            u.Role,
+		ProfileImageURL: u.ProfileImageStorageURL,
+	}
+}
+
+// AgentIDForUser returns the agent profile linked to a user, nil when there is none.
+func (s *AuthService) AgentIDForUser(userID uint) (*uint, error) {
+	var agent models.Agent
+	err := s.DB.Select("id").Where("user_id = ?", userID).First(&agent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("database error retrieving agent: %w", err)
+	}
+	return &agent.ID, nil
+}
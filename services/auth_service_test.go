@@ -0,0 +1,203 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthService(t *testing.T) (*AuthService, sqlmock.Sqlmock) {
+	db, mock := setupMockDB(t)
+	return &AuthService{DB: db, secret: []byte("test-secret"), accessTTL: 15 * time.Minute, refreshTTL: time.Hour}, mock
+}
+
+func expectUser(mock sqlmock.Sqlmock, id uint, passwordUpdatedAt *time.Time) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "status", "password_last_updated_at"}).
+			AddRow(id, "valuer@example.com", models.RoleValuer, models.UserStatusActive, passwordUpdatedAt))
+}
+
+func TestIssueTokens(t *testing.T) {
+	service, _ := newTestAuthService(t)
+	user := &models.User{ID: 12, Email: "valuer@example.com", Role: "Valuer"}
+
+	tokens, err := service.issueTokens(user)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 900, tokens.ExpiresIn)
+	assert.Equal(t, uint(12), tokens.User.ID)
+
+	claims := &tokenClaims{}
+	_, err = jwt.ParseWithClaims(tokens.AccessToken, claims, func(*jwt.Token) (interface{}, error) { return service.secret, nil })
+	require.NoError(t, err)
+	assert.Equal(t, tokenTypeAccess, claims.Type)
+	assert.Equal(t, models.RoleValuer, claims.Role, "roles are stored lower-case")
+	assert.Equal(t, "12", claims.Subject)
+	assert.WithinDuration(t, claims.IssuedAt.Add(15*time.Minute), claims.ExpiresAt.Time, time.Second)
+
+	_, err = jwt.ParseWithClaims(tokens.RefreshToken, claims, func(*jwt.Token) (interface{}, error) { return service.secret, nil })
+	require.NoError(t, err)
+	assert.Equal(t, tokenTypeRefresh, claims.Type)
+	assert.WithinDuration(t, claims.IssuedAt.Add(time.Hour), claims.ExpiresAt.Time, time.Second)
+}
+
+func TestAuthenticate(t *testing.T) {
+	user := &models.User{ID: 12, Role: models.RoleValuer}
+	now := time.Now()
+	sign := func(service *AuthService, tokenType string, issued time.Time, ttl time.Duration) string {
+		token, err := service.signToken(user, tokenType, issued, ttl)
+		require.NoError(t, err)
+		return token
+	}
+	signWith := func(method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	later := now.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		token   func(s *AuthService) string
+		expect  func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		{
+			name:   "valid access token",
+			token:  func(s *AuthService) string { return sign(s, tokenTypeAccess, now, time.Minute) },
+			expect: func(mock sqlmock.Sqlmock) { expectUser(mock, 12, nil) },
+		},
+		{
+			name:    "refresh token used for access",
+			token:   func(s *AuthService) string { return sign(s, tokenTypeRefresh, now, time.Minute) },
+			wantErr: "Expected a token of type access",
+		},
+		{
+			name:    "expired",
+			token:   func(s *AuthService) string { return sign(s, tokenTypeAccess, now.Add(-time.Hour), time.Minute) },
+			wantErr: "Token has expired",
+		},
+		{
+			name: "signed with another secret",
+			token: func(*AuthService) string {
+				other := &AuthService{secret: []byte("other-secret")}
+				return sign(other, tokenTypeAccess, now, time.Minute)
+			},
+			wantErr: "Invalid token",
+		},
+		{
+			name: "another signing method",
+			token: func(s *AuthService) string {
+				return signWith(jwt.SigningMethodHS512, s.secret, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{
+					Subject: "12", IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(later),
+				}})
+			},
+			wantErr: "Invalid token",
+		},
+		{
+			name: "unsigned",
+			token: func(*AuthService) string {
+				return signWith(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{
+					Subject: "12", IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(later),
+				}})
+			},
+			wantErr: "Invalid token",
+		},
+		{
+			name: "subject is not a user ID",
+			token: func(s *AuthService) string {
+				return signWith(jwt.SigningMethodHS256, s.secret, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{
+					Subject: "admin", IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(later),
+				}})
+			},
+			wantErr: "Invalid token",
+		},
+		{
+			name: "no issued at",
+			token: func(s *AuthService) string {
+				return signWith(jwt.SigningMethodHS256, s.secret, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{
+					Subject: "12", ExpiresAt: jwt.NewNumericDate(later),
+				}})
+			},
+			wantErr: "Invalid token",
+		},
+		{
+			name:  "user deleted since",
+			token: func(s *AuthService) string { return sign(s, tokenTypeAccess, now, time.Minute) },
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			wantErr: "User no longer exists",
+		},
+		{
+			name:  "issued before the password changed",
+			token: func(s *AuthService) string { return sign(s, tokenTypeAccess, now.Add(-10*time.Second), time.Minute) },
+			expect: func(mock sqlmock.Sqlmock) {
+				changed := now.Truncate(time.Second)
+				expectUser(mock, 12, &changed)
+			},
+			wantErr: "Token was issued before the password was changed",
+		},
+		{
+			name:  "account suspended since",
+			token: func(s *AuthService) string { return sign(s, tokenTypeAccess, now, time.Minute) },
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id", "role", "status"}).
+					AddRow(12, models.RoleAdmin, "suspended"))
+			},
+			wantErr: "Your account is not active",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock := newTestAuthService(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			got, err := service.Authenticate(tt.token(service))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, uint(12), got.ID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRefresh_NeedsRefreshToken(t *testing.T) {
+	service, mock := newTestAuthService(t)
+	access, err := service.signToken(&models.User{ID: 12}, tokenTypeAccess, time.Now(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = service.Refresh(access)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Expected a token of type refresh")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLogin_InactiveUser(t *testing.T) {
+	service, mock := newTestAuthService(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("change-me"), bcrypt.MinCost)
+	require.NoError(t, err)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("valuer@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "status", "password_hash"}).
+			AddRow(12, "valuer@example.com", models.RoleValuer, "inactive", string(hash)))
+
+	_, err = service.Login(&schema.LoginRequest{Email: "valuer@example.com", Password: "change-me"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Your account is not active")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
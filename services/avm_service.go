@@ -0,0 +1,685 @@
+// services/avm_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Defaults used when the avm section is missing from config.yaml
+var defaultAVMConfig = config.AVMConfig{
+	ModelDir:         "data/avm",
+	ListingType:      "Sale",
+	MinTrainingRows:  50,
+	MinCategoryCount: 5,
+	MaxAttributes:    20,
+	ConfidenceLevel:  0.9,
+}
+
+const (
+	avmModelFilePrefix  = "avm-"
+	avmVersionLayout    = "20060102T150405Z" // Versions are the UTC training time
+	avmTopContributions = 5
+)
+
+// AVMModel is a trained hedonic regression of log price, saved to disk as JSON.
+// Features are named so an artefact stays readable and usable after the code changes.
+type AVMModel struct {
+	Version          string              `json:"version"`
+	TrainedAt        time.Time           `json:"trained_at"`
+	ListingType      string              `json:"listing_type"`
+	Observations     int                 `json:"observations"`
+	RSquared         float64             `json:"r_squared"`
+	ResidualVariance float64             `json:"residual_variance"`
+	Features         []string            `json:"features"`
+	Coefficients     []float64           `json:"coefficients"`
+	Means            []float64           `json:"means"` // Training mean of each feature, used to explain estimates
+	XtXInverse       [][]float64         `json:"xtx_inverse"`
+	Levels           map[string][]string `json:"levels"`      // Category levels with their own coefficient
+	BaseLevels       map[string]string   `json:"base_levels"` // Level each category is measured against
+}
+
+func (m *AVMModel) summary() schema.AVMModelSummary {
+	return schema.AVMModelSummary{
+		Version:          m.Version,
+		TrainedAt:        m.TrainedAt,
+		ListingType:      m.ListingType,
+		Observations:     m.Observations,
+		FeatureCount:     len(m.Features),
+		RSquared:         roundTo(m.RSquared, 4),
+		ResidualStdError: roundTo(math.Sqrt(m.ResidualVariance), 4),
+	}
+}
+
+// avmInput is a property reduced to what the model looks at.
+type avmInput struct {
+	District     string
+	PropertyType string
+	Design       string
+	BuildingSqm  *float64
+	LandSqm      *float64
+	Rooms        *int
+	Bathrooms    *int
+	Age          *int
+	Attributes   []string
+}
+
+// avmRow is a training listing as loaded from the DB.
+type avmRow struct {
+	District        *string
+	PropertyType    *string
+	PropertyDesign  string
+	BuildingSizeSqm *float64
+	LandSizeSqm     *float64
+	NoRooms         *int
+	NoOfBathrooms   *int
+	Age             *int
+	YearBuilt       *string
+	Attributes      datatypes.JSON
+	Price           float64
+}
+
+type AVMService struct {
+	DB    *gorm.DB
+	cache *avmModelCache // Shared with the copies WithContext makes
+}
+
+// avmModelCache keeps the latest model so estimates do not re-read the artefact every time.
+type avmModelCache struct {
+	mu     sync.Mutex
+	latest *AVMModel
+}
+
+func NewAVMService(db *gorm.DB) *AVMService {
+	return &AVMService{DB: db, cache: &avmModelCache{}}
+}
+
+func (s *AVMService) WithContext(ctx context.Context) *AVMService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+func avmSettings() config.AVMConfig {
+	settings := defaultAVMConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.AVM
+	if cfg.ModelDir != "" {
+		settings.ModelDir = cfg.ModelDir
+	}
+	if cfg.ListingType != "" {
+		settings.ListingType = cfg.ListingType
+	}
+	if cfg.MinTrainingRows > 0 {
+		settings.MinTrainingRows = cfg.MinTrainingRows
+	}
+	if cfg.MinCategoryCount > 0 {
+		settings.MinCategoryCount = cfg.MinCategoryCount
+	}
+	if cfg.MaxAttributes > 0 {
+		settings.MaxAttributes = cfg.MaxAttributes
+	}
+	if cfg.ConfidenceLevel > 0 && cfg.ConfidenceLevel < 1 {
+		settings.ConfidenceLevel = cfg.ConfidenceLevel
+	}
+	return settings
+}
+
+func normaliseCategory(v string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(v), " "))
+}
+
+// ageFromYearBuilt derives an age when only the year built is recorded.
+func ageFromYearBuilt(age *int, yearBuilt *string, now time.Time) *int {
+	if age != nil || yearBuilt == nil {
+		return age
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(*yearBuilt))
+	if err != nil || year < 1800 || year > now.Year() {
+		return nil
+	}
+	a := now.Year() - year
+	return &a
+}
+
+// rawFeatures names every feature value of an input. Features the model does not know
+// about are ignored when the vector is built, so unseen levels fall back to the base.
+func rawFeatures(in avmInput) map[string]float64 {
+	f := map[string]float64{}
+	if in.District != "" {
+		f["district="+normaliseCategory(in.District)] = 1
+	}
+	if in.PropertyType != "" {
+		f["property_type="+normaliseCategory(in.PropertyType)] = 1
+	}
+	if in.Design != "" {
+		f["design="+normaliseCategory(in.Design)] = 1
+	}
+	f["ln_building_sqm"], f["building_sqm_missing"] = logOrMissing(in.BuildingSqm)
+	f["ln_land_sqm"], f["land_sqm_missing"] = logOrMissing(in.LandSqm)
+	f["rooms"], f["rooms_missing"] = countOrMissing(in.Rooms)
+	f["bathrooms"], f["bathrooms_missing"] = countOrMissing(in.Bathrooms)
+	f["age"], f["age_missing"] = countOrMissing(in.Age)
+	for _, a := range in.Attributes {
+		f["attribute="+strings.ToLower(strings.TrimSpace(a))] = 1
+	}
+	return f
+}
+
+func (m *AVMModel) featureVector(in avmInput) []float64 {
+	raw := rawFeatures(in)
+	x := make([]float64, len(m.Features))
+	for i, name := range m.Features {
+		if name == "intercept" {
+			x[i] = 1
+		} else {
+			x[i] = raw[name]
+		}
+	}
+	return x
+}
+
+// Train fits a new model on the current listings and writes it to the model directory.
+func (s *AVMService) Train() (*schema.AVMModelSummary, error) {
+	settings := avmSettings()
+	now := time.Now()
+
+	// Every tenant can query the model, so it only learns from shared listings
+	var rows []avmRow
+	err := sharedOnly(s.DB).Model(&models.Property{}).
+		Select("locations.district, properties.property_type, properties.property_design, properties.building_size_sqm, properties.land_size_sqm, "+
+			"properties.no_rooms, properties.no_of_bathrooms, properties.age, properties.year_built, properties.attributes, properties.price").
+		Joins("LEFT JOIN locations ON locations.property_id = properties.id").
+		Where("properties.price > 0").
+		Where("properties.listing_type ILIKE ?", settings.ListingType).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load training listings: %w", err)
+	}
+	if len(rows) < settings.MinTrainingRows {
+		return nil, utils.NewAPIError(http.StatusUnprocessableEntity, "Not enough listings to train", fmt.Sprintf("Found %d priced %s listings, at least %d are needed.", len(rows), settings.ListingType, settings.MinTrainingRows))
+	}
+
+	inputs := make([]avmInput, len(rows))
+	y := make([]float64, len(rows))
+	for i, r := range rows {
+		inputs[i] = avmInput{
+			District:     derefString(r.District),
+			PropertyType: derefString(r.PropertyType),
+			Design:       r.PropertyDesign,
+			BuildingSqm:  r.BuildingSizeSqm,
+			LandSqm:      r.LandSizeSqm,
+			Rooms:        r.NoRooms,
+			Bathrooms:    r.NoOfBathrooms,
+			Age:          ageFromYearBuilt(r.Age, r.YearBuilt, now),
+			Attributes:   models.ParseAttributeNames(r.Attributes),
+		}
+		y[i] = math.Log(r.Price)
+	}
+
+	model := &AVMModel{
+		Version:     now.UTC().Format(avmVersionLayout),
+		TrainedAt:   now,
+		ListingType: settings.ListingType,
+		Levels:      map[string][]string{},
+		BaseLevels:  map[string]string{},
+	}
+
+	// Candidate features: each common category level except the most common (the base),
+	// the numeric characteristics, then the most common attributes
+	candidates := []string{"intercept"}
+	categories := []struct {
+		name  string
+		value func(avmInput) string
+	}{
+		{"district", func(in avmInput) string { return in.District }},
+		{"property_type", func(in avmInput) string { return in.PropertyType }},
+		{"design", func(in avmInput) string { return in.Design }},
+	}
+	for _, cat := range categories {
+		counts := map[string]int{}
+		for _, in := range inputs {
+			if v := normaliseCategory(cat.value(in)); v != "" {
+				counts[v]++
+			}
+		}
+		levels := commonLevels(counts, settings.MinCategoryCount)
+		if len(levels) == 0 {
+			continue
+		}
+		model.BaseLevels[cat.name] = levels[0]
+		model.Levels[cat.name] = levels[1:]
+		for _, level := range levels[1:] {
+			candidates = append(candidates, cat.name+"="+level)
+		}
+	}
+	candidates = append(candidates, "ln_building_sqm", "building_sqm_missing", "ln_land_sqm", "land_sqm_missing",
+		"rooms", "rooms_missing", "bathrooms", "bathrooms_missing", "age", "age_missing")
+
+	attrCounts := map[string]int{}
+	for _, in := range inputs {
+		for _, a := range in.Attributes {
+			attrCounts[strings.ToLower(a)]++
+		}
+	}
+	attrs := commonLevels(attrCounts, settings.MinCategoryCount)
+	if len(attrs) > settings.MaxAttributes {
+		attrs = attrs[:settings.MaxAttributes]
+	}
+	for _, a := range attrs {
+		candidates = append(candidates, "attribute="+a)
+	}
+
+	X := make([][]float64, len(inputs))
+	probe := &AVMModel{Features: candidates}
+	for i, in := range inputs {
+		X[i] = probe.featureVector(in)
+	}
+
+	kept := independentColumns(X)
+	reduced := make([][]float64, len(X))
+	for i, row := range X {
+		r := make([]float64, len(kept))
+		for j, c := range kept {
+			r[j] = row[c]
+		}
+		reduced[i] = r
+	}
+	for _, c := range kept {
+		model.Features = append(model.Features, candidates[c])
+	}
+
+	fit, err := utils.FitOLS(reduced, y)
+	if err != nil {
+		if errors.Is(err, utils.ErrSingularMatrix) {
+			return nil, utils.NewAPIError(http.StatusUnprocessableEntity, "Could not fit the model", "The features are collinear or there are too few listings for the number of features.")
+		}
+		return nil, fmt.Errorf("failed to fit model: %w", err)
+	}
+	model.Coefficients = fit.Coefficients
+	model.XtXInverse = fit.XtXInverse
+	model.ResidualVariance = fit.ResidualVariance
+	model.RSquared = fit.RSquared
+	model.Observations = fit.Observations
+
+	model.Means = make([]float64, len(kept))
+	for _, row := range reduced {
+		for j, v := range row {
+			model.Means[j] += v / float64(len(reduced))
+		}
+	}
+
+	if err := saveAVMModel(settings.ModelDir, model); err != nil {
+		return nil, err
+	}
+
+	s.cache.mu.Lock()
+	s.cache.latest = model
+	s.cache.mu.Unlock()
+
+	summary := model.summary()
+	return &summary, nil
+}
+
+// commonLevels returns the values seen at least min times, most common first.
+func commonLevels(counts map[string]int, min int) []string {
+	levels := make([]string, 0, len(counts))
+	for v, n := range counts {
+		if n >= min {
+			levels = append(levels, v)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if counts[levels[i]] != counts[levels[j]] {
+			return counts[levels[i]] > counts[levels[j]]
+		}
+		return levels[i] < levels[j]
+	})
+	return levels
+}
+
+// independentColumns keeps the intercept and drops columns that are constant or exact
+// copies of an earlier column, either of which would make X'X singular.
+func independentColumns(X [][]float64) []int {
+	candidates := nonConstantColumns(X, 1)
+	var kept []int
+	for _, c := range candidates {
+		duplicate := false
+		for _, k := range kept {
+			same := true
+			for _, row := range X {
+				if row[c] != row[k] {
+					same = false
+					break
+				}
+			}
+			if same {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func saveAVMModel(dir string, model *AVMModel) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode model: %w", err)
+	}
+	// Write then rename so a reader never sees a half written artefact
+	path := filepath.Join(dir, avmModelFilePrefix+model.Version+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	return nil
+}
+
+// validAVMVersion reports whether version is a training timestamp. Versions come from
+// requests and end up in a file path, anything else could point outside the model directory.
+func validAVMVersion(version string) bool {
+	t, err := time.Parse(avmVersionLayout, version)
+	return err == nil && t.Format(avmVersionLayout) == version
+}
+
+// check makes sure the vectors and matrix match the features, the estimate maths assumes it.
+func (m *AVMModel) check() error {
+	n := len(m.Features)
+	if n == 0 || len(m.Coefficients) != n || len(m.Means) != n || len(m.XtXInverse) != n {
+		return fmt.Errorf("model %s does not have one coefficient, mean and matrix row per feature", m.Version)
+	}
+	for _, row := range m.XtXInverse {
+		if len(row) != n {
+			return fmt.Errorf("model %s has a matrix row of the wrong length", m.Version)
+		}
+	}
+	return nil
+}
+
+func loadAVMModel(dir, version string) (*AVMModel, error) {
+	if !validAVMVersion(version) {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Invalid model version '%s', expected a version like 20240131T120000Z", version))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, avmModelFilePrefix+version+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, utils.NewNotFoundError(fmt.Sprintf("AVM model %s", version))
+		}
+		return nil, fmt.Errorf("failed to read model: %w", err)
+	}
+	var model AVMModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to decode model %s: %w", version, err)
+	}
+	if model.Version != version {
+		return nil, fmt.Errorf("model file %s holds version %s", version, model.Version)
+	}
+	if err := model.check(); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// modelVersions lists the artefacts on disk, newest first. Versions are timestamps so
+// they sort as strings.
+func modelVersions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	var versions []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, avmModelFilePrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if version := strings.TrimSuffix(strings.TrimPrefix(name, avmModelFilePrefix), ".json"); validAVMVersion(version) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	return versions, nil
+}
+
+// ListModels returns a summary of every trained model, newest first.
+func (s *AVMService) ListModels() ([]schema.AVMModelSummary, error) {
+	dir := avmSettings().ModelDir
+	versions, err := modelVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]schema.AVMModelSummary, 0, len(versions))
+	for _, v := range versions {
+		model, err := loadAVMModel(dir, v)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, model.summary())
+	}
+	return summaries, nil
+}
+
+// model returns the requested version, or the latest when version is empty.
+func (s *AVMService) model(version string) (*AVMModel, error) {
+	dir := avmSettings().ModelDir
+	if version != "" {
+		return loadAVMModel(dir, version)
+	}
+
+	versions, err := modelVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, utils.NewAPIError(http.StatusNotFound, "No AVM model has been trained", "Train one with POST /api/v1/avm/train.")
+	}
+
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+	if s.cache.latest != nil && s.cache.latest.Version == versions[0] {
+		return s.cache.latest, nil
+	}
+	model, err := loadAVMModel(dir, versions[0])
+	if err != nil {
+		return nil, err
+	}
+	s.cache.latest = model
+	return model, nil
+}
+
+// Estimate values a property with a trained model. The estimate is the median implied by
+// the log-price regression, the interval is a prediction interval for a single listing.
+func (s *AVMService) Estimate(req *schema.AVMEstimateRequest) (*schema.AVMEstimateResponse, error) {
+	settings := avmSettings()
+	model, err := s.model(req.ModelVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := s.estimateInput(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, cat := range []struct{ name, value string }{
+		{"district", in.District},
+		{"property_type", in.PropertyType},
+		{"design", in.Design},
+	} {
+		if cat.value == "" {
+			continue
+		}
+		value := normaliseCategory(cat.value)
+		known := model.BaseLevels[cat.name] == value
+		for _, level := range model.Levels[cat.name] {
+			if level == value {
+				known = true
+			}
+		}
+		if !known {
+			warnings = append(warnings, fmt.Sprintf("%s '%s' had too few training listings, it is treated as '%s'", cat.name, cat.value, model.BaseLevels[cat.name]))
+		}
+	}
+	if in.BuildingSqm == nil {
+		warnings = append(warnings, "No usable building size, the estimate is less reliable")
+	}
+
+	x := model.featureVector(in)
+	logEstimate := utils.Dot(x, model.Coefficients)
+	predictionVariance := model.ResidualVariance * (1 + utils.QuadraticForm(x, model.XtXInverse))
+
+	level := settings.ConfidenceLevel
+	if req.ConfidenceLevel != nil {
+		level = *req.ConfidenceLevel
+	}
+	z := math.Sqrt2 * math.Erfinv(level)
+	margin := z * math.Sqrt(math.Max(predictionVariance, 0))
+
+	var contributions []schema.AVMContribution
+	for j, name := range model.Features {
+		if name == "intercept" {
+			continue
+		}
+		effect := model.Coefficients[j] * (x[j] - model.Means[j])
+		if roundTo(effect, 4) == 0 {
+			continue
+		}
+		contributions = append(contributions, schema.AVMContribution{
+			Feature:       name,
+			Value:         x[j],
+			LogEffect:     roundTo(effect, 4),
+			PercentEffect: roundTo((math.Exp(effect)-1)*100, 2),
+		})
+	}
+	sort.Slice(contributions, func(i, j int) bool {
+		return math.Abs(contributions[i].LogEffect) > math.Abs(contributions[j].LogEffect)
+	})
+	if len(contributions) > avmTopContributions {
+		contributions = contributions[:avmTopContributions]
+	}
+	if contributions == nil {
+		contributions = []schema.AVMContribution{}
+	}
+
+	return &schema.AVMEstimateResponse{
+		ModelVersion:     model.Version,
+		PropertyID:       req.PropertyID,
+		Estimate:         roundTo(math.Exp(logEstimate), 0),
+		Low:              roundTo(math.Exp(logEstimate-margin), 0),
+		High:             roundTo(math.Exp(logEstimate+margin), 0),
+		ConfidenceLevel:  level,
+		TopContributions: contributions,
+		Warnings:         warnings,
+	}, nil
+}
+
+// estimateInput merges a stored property, if any, with the fields in the request. The property
+// is looked up through s.DB, so a service from WithContext only values what its tenant can see.
+func (s *AVMService) estimateInput(req *schema.AVMEstimateRequest) (avmInput, error) {
+	now := time.Now()
+	var in avmInput
+
+	if req.PropertyID != nil {
+		var property models.Property
+		if err := s.DB.Preload("Location").First(&property, *req.PropertyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return in, utils.NewNotFoundError("Property")
+			}
+			return in, fmt.Errorf("database error retrieving property: %w", err)
+		}
+		in = avmInput{
+			District:     property.Location.District,
+			PropertyType: derefString(property.PropertyType),
+			Design:       property.PropertyDesign,
+			BuildingSqm:  property.BuildingSizeSqm,
+			LandSqm:      property.LandSizeSqm,
+			Rooms:        property.NoRooms,
+			Bathrooms:    property.NoOfBathrooms,
+			Age:          ageFromYearBuilt(property.Age, property.YearBuilt, now),
+			Attributes:   models.ParseAttributeNames(property.Attributes),
+		}
+	}
+
+	if req.District != nil {
+		in.District = *req.District
+	}
+	if req.PropertyType != nil {
+		in.PropertyType = *req.PropertyType
+	}
+	if req.PropertyDesign != nil {
+		in.Design = *req.PropertyDesign
+	}
+	if req.NoRooms != nil {
+		in.Rooms = req.NoRooms
+	}
+	if req.NoOfBathrooms != nil {
+		in.Bathrooms = req.NoOfBathrooms
+	}
+	if req.Age != nil {
+		in.Age = req.Age
+	}
+	if req.Attributes != nil {
+		in.Attributes = req.Attributes
+	}
+	for _, size := range []struct {
+		value *float64
+		unit  string
+		field string
+		dest  **float64
+	}{
+		{req.BuildingSize, req.BuildingSizeUnit, "building_size_unit", &in.BuildingSqm},
+		{req.LandSize, req.LandSizeUnit, "land_size_unit", &in.LandSqm},
+	} {
+		if size.value == nil {
+			continue
+		}
+		unit := size.unit
+		if unit == "" {
+			unit = "sqm"
+		}
+		sqm, ok := utils.AreaToSqm(size.value, unit)
+		if !ok {
+			return in, utils.NewBadRequestError(fmt.Sprintf("Unrecognised %s '%s'", size.field, size.unit))
+		}
+		*size.dest = sqm
+	}
+
+	if req.PropertyID == nil && in.District == "" && in.BuildingSqm == nil && in.PropertyType == "" {
+		return in, utils.NewBadRequestError("Pass property_id or describe the property (district, property_type, building_size, ...)")
+	}
+	return in, nil
+}
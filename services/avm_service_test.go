@@ -0,0 +1,76 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidAVMVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		valid   bool
+	}{
+		{"20240131T120000Z", true},
+		{"", false},
+		{"latest", false},
+		{"../../etc/passwd", false},
+		{"20240131T120000Z/../../x", false},
+		{"20241331T120000Z", false}, // No 13th month
+		{"2024-01-31T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.valid, validAVMVersion(tt.version), tt.version)
+	}
+}
+
+func writeAVMModel(t *testing.T, dir string, model AVMModel) {
+	t.Helper()
+	data, err := json.Marshal(model)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, avmModelFilePrefix+model.Version+".json"), data, 0o644))
+}
+
+func TestLoadAVMModel(t *testing.T) {
+	dir := t.TempDir()
+	good := AVMModel{
+		Version:      "20240131T120000Z",
+		Features:     []string{"intercept", "log_building_sqm"},
+		Coefficients: []float64{10, 0.8},
+		Means:        []float64{1, 4.5},
+		XtXInverse:   [][]float64{{0.1, 0}, {0, 0.2}},
+	}
+	writeAVMModel(t, dir, good)
+
+	corrupt := good
+	corrupt.Version = "20240201T120000Z"
+	corrupt.Coefficients = []float64{10} // One short, Dot would panic
+	writeAVMModel(t, dir, corrupt)
+
+	ragged := good
+	ragged.Version = "20240202T120000Z"
+	ragged.XtXInverse = [][]float64{{0.1, 0}, {0}}
+	writeAVMModel(t, dir, ragged)
+
+	// A JSON file outside the model directory must not be reachable
+	outside := filepath.Join(filepath.Dir(dir), "secret.json")
+	require.NoError(t, os.WriteFile(outside, []byte(`{"version": "x"}`), 0o644))
+	t.Cleanup(func() { os.Remove(outside) })
+
+	model, err := loadAVMModel(dir, good.Version)
+	require.NoError(t, err)
+	assert.Equal(t, good.Coefficients, model.Coefficients)
+
+	for _, version := range []string{corrupt.Version, ragged.Version, "../secret", "20240301T120000Z"} {
+		_, err := loadAVMModel(dir, version)
+		assert.Error(t, err, version)
+	}
+
+	versions, err := modelVersions(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ragged.Version, corrupt.Version, good.Version}, versions)
+}
@@ -0,0 +1,289 @@
+// services/comparable_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultComparablesLimit = 10
+	MaxComparablesLimit     = 50
+
+	ageToleranceYears = 20.0 // An age gap of this many years scores 0 on age
+)
+
+// Defaults used when the comparables section is missing from config.yaml
+var defaultComparablesConfig = config.ComparablesConfig{
+	MaxRadiusKm:       5,
+	MaxCandidates:     500,
+	RecencyWindowDays: 730,
+	Weights: config.ComparableWeights{
+		Distance:     3,
+		PropertyType: 2,
+		Design:       1,
+		Rooms:        1.5,
+		Bathrooms:    1,
+		BuildingSize: 1.5,
+		LandSize:     1,
+		Age:          1,
+		Recency:      0.5,
+	},
+}
+
+type ComparableService struct {
+	DB *gorm.DB
+}
+
+func NewComparableService(db *gorm.DB) *ComparableService {
+	return &ComparableService{DB: db}
+}
+
+func (s *ComparableService) WithContext(ctx context.Context) *ComparableService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// ComparableMatch is a candidate property together with how closely it matches the subject.
+type ComparableMatch struct {
+	Property   models.Property
+	Score      float64
+	DistanceKm *float64
+	Breakdown  []schema.ComparableScoreComponent
+}
+
+// comparablesSettings merges the configured settings over the defaults.
+func comparablesSettings() config.ComparablesConfig {
+	settings := defaultComparablesConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Comparables
+	if cfg.MaxRadiusKm > 0 {
+		settings.MaxRadiusKm = cfg.MaxRadiusKm
+	}
+	if cfg.MaxCandidates > 0 {
+		settings.MaxCandidates = cfg.MaxCandidates
+	}
+	if cfg.RecencyWindowDays > 0 {
+		settings.RecencyWindowDays = cfg.RecencyWindowDays
+	}
+	if cfg.Weights != (config.ComparableWeights{}) { // Only replace weights if at least one was set
+		settings.Weights = cfg.Weights
+	}
+	return settings
+}
+
+// FindComparables ranks other properties by how similar they are to the subject property.
+// radiusKm overrides the configured search radius when > 0.
+func (s *ComparableService) FindComparables(subjectID uint, limit int, radiusKm float64) ([]ComparableMatch, error) {
+	settings := comparablesSettings()
+	if radiusKm > 0 {
+		settings.MaxRadiusKm = radiusKm
+	}
+	if limit <= 0 {
+		limit = DefaultComparablesLimit
+	}
+	if limit > MaxComparablesLimit {
+		limit = MaxComparablesLimit
+	}
+
+	var subject models.Property
+	err := s.DB.Preload("Location").First(&subject, subjectID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Property")
+		}
+		return nil, fmt.Errorf("database error retrieving subject property: %w", err)
+	}
+
+	// Candidates must have a price and the same listing type, a rental is no comparable for a sale
+	query := s.DB.Model(&models.Property{}).
+		Where("properties.id <> ?", subject.ID).
+		Where("properties.price IS NOT NULL").
+		Where("properties.listing_type = ?", subject.ListingType)
+
+	// Narrow the pool by location: a bounding box around the subject if it has coordinates,
+	// plus same-district rows that have no coordinates of their own.
+	loc := subject.Location
+	if loc.Lat != nil && loc.Lng != nil {
+		minLat, maxLat, minLng, maxLng := utils.BoundingBox(*loc.Lat, *loc.Lng, settings.MaxRadiusKm)
+		query = query.Where(
+			"properties.id IN (SELECT property_id FROM locations WHERE (lat BETWEEN ? AND ? AND lng BETWEEN ? AND ?) OR (lat IS NULL AND district ILIKE ?))",
+			minLat, maxLat, minLng, maxLng, escapeLike(loc.District),
+		)
+	} else if loc.District != "" {
+		query = query.Where("properties.id IN (SELECT property_id FROM locations WHERE district ILIKE ?)", escapeLike(loc.District))
+	}
+
+	var candidates []models.Property
+	err = query.Order("properties.created_at DESC").
+		Limit(settings.MaxCandidates).
+		Preload("Location").
+		Preload("Agent.User").
+		Preload("CoverPhoto").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve comparable candidates: %w", err)
+	}
+
+	now := time.Now()
+	matches := make([]ComparableMatch, 0, len(candidates))
+	for _, candidate := range candidates {
+		matches = append(matches, scoreComparable(&subject, candidate, settings, now))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// scoreComparable computes the weighted similarity of a candidate to the subject.
+// Factors the subject has no data for are left out entirely; factors the candidate
+// has no data for count with a similarity of 0 so sparse rows don't float to the top.
+func scoreComparable(subject *models.Property, candidate models.Property, settings config.ComparablesConfig, now time.Time) ComparableMatch {
+	match := ComparableMatch{Property: candidate}
+	w := settings.Weights
+	var totalWeight, weightedSum float64
+
+	add := func(factor string, weight float64, subjectHasData bool, similarity float64, candidateHasData bool) {
+		if weight <= 0 || !subjectHasData {
+			return
+		}
+		if !candidateHasData {
+			similarity = 0
+		}
+		totalWeight += weight
+		weightedSum += weight * similarity
+		match.Breakdown = append(match.Breakdown, schema.ComparableScoreComponent{
+			Factor:     factor,
+			Weight:     weight,
+			Similarity: roundTo(similarity, 4),
+			Available:  candidateHasData,
+		})
+	}
+
+	// Distance
+	sLoc, cLoc := subject.Location, candidate.Location
+	subjectHasCoords := sLoc.Lat != nil && sLoc.Lng != nil
+	candidateHasCoords := cLoc.Lat != nil && cLoc.Lng != nil
+	distanceSim := 0.0
+	if subjectHasCoords && candidateHasCoords {
+		d := utils.HaversineKm(*sLoc.Lat, *sLoc.Lng, *cLoc.Lat, *cLoc.Lng)
+		match.DistanceKm = &d
+		distanceSim = clamp01(1 - d/settings.MaxRadiusKm)
+	}
+	add("distance", w.Distance, subjectHasCoords, distanceSim, candidateHasCoords)
+
+	// Property type and design are exact (case-insensitive) matches
+	subjectType, candidateType := derefString(subject.PropertyType), derefString(candidate.PropertyType)
+	add("property_type", w.PropertyType, subjectType != "", boolSimilarity(strings.EqualFold(subjectType, candidateType)), candidateType != "")
+	subjectDesign, candidateDesign := strings.TrimSpace(subject.PropertyDesign), strings.TrimSpace(candidate.PropertyDesign)
+	add("design", w.Design, subjectDesign != "", boolSimilarity(strings.EqualFold(subjectDesign, candidateDesign)), candidateDesign != "")
+
+	// Room counts
+	add("rooms", w.Rooms, subject.NoRooms != nil, countSimilarity(subject.NoRooms, candidate.NoRooms), candidate.NoRooms != nil)
+	add("bathrooms", w.Bathrooms, subject.NoOfBathrooms != nil, countSimilarity(subject.NoOfBathrooms, candidate.NoOfBathrooms), candidate.NoOfBathrooms != nil)
+
+	// Sizes are compared in square metres
+	buildingSim, buildingOK := sizeSimilarity(subject.BuildingSizeSqm, candidate.BuildingSizeSqm)
+	add("building_size", w.BuildingSize, subject.BuildingSizeSqm != nil, buildingSim, buildingOK)
+	landSim, landOK := sizeSimilarity(subject.LandSizeSqm, candidate.LandSizeSqm)
+	add("land_size", w.LandSize, subject.LandSizeSqm != nil, landSim, landOK)
+
+	// Age
+	ageSim := 0.0
+	if subject.Age != nil && candidate.Age != nil {
+		ageSim = clamp01(1 - math.Abs(float64(*subject.Age-*candidate.Age))/ageToleranceYears)
+	}
+	add("age", w.Age, subject.Age != nil, ageSim, candidate.Age != nil)
+
+	// Recency of the candidate listing
+	daysOld := now.Sub(candidate.CreatedAt).Hours() / 24
+	add("recency", w.Recency, true, clamp01(1-daysOld/float64(settings.RecencyWindowDays)), !candidate.CreatedAt.IsZero())
+
+	if totalWeight > 0 {
+		match.Score = roundTo(weightedSum/totalWeight, 4)
+		for i := range match.Breakdown {
+			c := &match.Breakdown[i]
+			c.Contribution = roundTo(c.Weight*c.Similarity/totalWeight, 4)
+		}
+	}
+	return match
+}
+
+func countSimilarity(a, b *int) float64 {
+	if a == nil || b == nil {
+		return 0
+	}
+	return 1 / (1 + math.Abs(float64(*a-*b)))
+}
+
+// sizeSimilarity returns the ratio of the smaller to the larger size.
+func sizeSimilarity(a, b *float64) (float64, bool) {
+	if a == nil || b == nil || *a <= 0 || *b <= 0 {
+		return 0, false
+	}
+	return math.Min(*a, *b) / math.Max(*a, *b), true
+}
+
+func boolSimilarity(equal bool) float64 {
+	if equal {
+		return 1
+	}
+	return 0
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
+
+// MapComparablesToResponse maps scored matches to the API response.
+func MapComparablesToResponse(subjectID uint, matches []ComparableMatch) schema.ComparablesResponse {
+	resp := schema.ComparablesResponse{
+		SubjectID:   subjectID,
+		Comparables: make([]schema.ComparableResponse, len(matches)),
+	}
+	for i, m := range matches {
+		var distance *float64
+		if m.DistanceKm != nil {
+			d := roundTo(*m.DistanceKm, 3)
+			distance = &d
+		}
+		resp.Comparables[i] = schema.ComparableResponse{
+			Property:   MapPropertyToResponse(&m.Property),
+			Score:      m.Score,
+			DistanceKm: distance,
+			Breakdown:  m.Breakdown,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupMockDB returns a DB whose statements must each be expected on the mock, in order.
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, PreferSimpleProtocol: true}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestScoreComparable(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	lat, lng := -13.9626, 33.7741
+	houseType := "House"
+	size := 200.0
+	subject := &models.Property{
+		PropertyType:    &houseType,
+		PropertyDesign:  "Bungalow",
+		NoRooms:         intPtr(3),
+		BuildingSizeSqm: &size,
+		Age:             intPtr(10),
+		Location:        models.Location{Lat: &lat, Lng: &lng},
+	}
+	settings := config.ComparablesConfig{
+		MaxRadiusKm:       5,
+		RecencyWindowDays: 100,
+		Weights:           config.ComparableWeights{Distance: 3, PropertyType: 2, Design: 1, Rooms: 1, BuildingSize: 1, LandSize: 1, Age: 1, Recency: 1},
+	}
+
+	// The same house listed today matches on everything the subject has data for
+	same := *subject
+	same.CreatedAt = now
+	match := scoreComparable(subject, same, settings, now)
+	assert.Equal(t, 1.0, match.Score)
+	require.NotNil(t, match.DistanceKm)
+	assert.Equal(t, 0.0, *match.DistanceKm)
+	factors := map[string]bool{}
+	var contributions float64
+	for _, c := range match.Breakdown {
+		factors[c.Factor] = true
+		contributions += c.Contribution
+	}
+	assert.NotContains(t, factors, "land_size", "the subject has no land size, so the factor is left out")
+	assert.NotContains(t, factors, "bathrooms")
+	assert.InDelta(t, match.Score, contributions, 1e-3)
+
+	// Half the radius away, one room more, listed half a window ago
+	half := lat + 2.5/111.2
+	other := same
+	other.Location = models.Location{Lat: &half, Lng: &lng}
+	other.NoRooms = intPtr(4)
+	other.CreatedAt = now.AddDate(0, 0, -50)
+	match = scoreComparable(subject, other, settings, now)
+	byFactor := map[string]float64{}
+	for _, c := range match.Breakdown {
+		byFactor[c.Factor] = c.Similarity
+	}
+	assert.InDelta(t, 0.5, byFactor["distance"], 0.01)
+	assert.Equal(t, 0.5, byFactor["rooms"])
+	assert.Equal(t, 0.5, byFactor["recency"])
+	// (3 x 0.5 + 2 + 1 + 0.5 + 1 + 1 + 0.5) / 10
+	assert.InDelta(t, 0.75, match.Score, 0.01)
+
+	// A candidate without data scores 0 on that factor rather than being let off
+	sparse := models.Property{CreatedAt: now}
+	match = scoreComparable(subject, sparse, settings, now)
+	assert.InDelta(t, 0.1, match.Score, 1e-9, "only recency is known, 1 of 10")
+	for _, c := range match.Breakdown {
+		if c.Factor != "recency" {
+			assert.False(t, c.Available, c.Factor)
+		}
+	}
+}
+
+func expectComparableSubject(mock sqlmock.Sqlmock, location *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT \* FROM "properties" WHERE "properties"."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "listing_type"}).AddRow(1, "Rent"))
+	mock.ExpectQuery(`SELECT \* FROM "locations" WHERE "locations"."property_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(location)
+}
+
+func TestFindComparables_BoundingBoxAndDistrict(t *testing.T) {
+	db, mock := setupMockDB(t)
+	expectComparableSubject(mock, sqlmock.NewRows([]string{"id", "property_id", "district", "lat", "lng"}).
+		AddRow(10, 1, "Area_4%", -13.9, 33.7))
+	minLat, maxLat, minLng, maxLng := utils.BoundingBox(-13.9, 33.7, 5) // The configured radius
+	mock.ExpectQuery(`WHERE properties.id <> \$1 AND properties.price IS NOT NULL AND properties.listing_type = \$2 `+
+		`AND \(properties.id IN \(SELECT property_id FROM locations WHERE \(lat BETWEEN \$3 AND \$4 AND lng BETWEEN \$5 AND \$6\) OR \(lat IS NULL AND district ILIKE \$7\)\)\) `+
+		`ORDER BY properties.created_at DESC LIMIT \$8`).
+		WithArgs(1, "Rent", approx(minLat), approx(maxLat), approx(minLng), approx(maxLng), `Area\_4\%`, 500).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	matches, err := NewComparableService(db).FindComparables(1, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindComparables_DistrictOnly(t *testing.T) {
+	db, mock := setupMockDB(t)
+	expectComparableSubject(mock, sqlmock.NewRows([]string{"id", "property_id", "district"}).AddRow(10, 1, "Lilongwe"))
+	mock.ExpectQuery(`AND properties.listing_type = \$2 AND properties.id IN \(SELECT property_id FROM locations WHERE district ILIKE \$3\) ORDER BY`).
+		WithArgs(1, "Rent", "Lilongwe", 500).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := NewComparableService(db).FindComparables(1, 0, 0)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// approx matches a float argument to within about ten metres of a coordinate.
+type approx float64
+
+func (a approx) Match(v interface{}) bool {
+	f, ok := v.(float64)
+	return ok && f-float64(a) < 1e-4 && float64(a)-f < 1e-4
+}
@@ -0,0 +1,277 @@
+// services/cost_approach_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	DepreciationStraightLine = "straight_line" // Age / EUL
+	DepreciationAgeLife      = "age_life"      // Age / (Age + REL)
+)
+
+type CostApproachService struct {
+	DB *gorm.DB
+}
+
+func NewCostApproachService(db *gorm.DB) *CostApproachService {
+	return &CostApproachService{DB: db}
+}
+
+func (s *CostApproachService) WithContext(ctx context.Context) *CostApproachService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// CalculateAndSave computes the depreciated replacement cost of a property plus its land value
+// and stores the result. Values in the request override what is on the property record.
+func (s *CostApproachService) CalculateAndSave(propertyID uint, req *schema.CostApproachRequest) (*models.CostValuation, error) {
+	var property models.Property
+	err := s.DB.Preload("Location").First(&property, propertyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Property")
+		}
+		return nil, fmt.Errorf("database error retrieving property: %w", err)
+	}
+
+	valuation, err := calculateCostApproach(&property, req, costApproachSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the headline figures as a Valuation and keep the line items alongside it
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		replacementCost := valuation.ReplacementCost
+		record := models.Valuation{
+			PropertyID:        property.ID,
+			ValuerID:          req.ValuerID,
+			ValuationDate:     time.Now(),
+			Method:            models.ValuationMethodCost,
+			MarketValue:       &valuation.TotalValue,
+			InsuranceValue:    &replacementCost, // Reinstatement cost new
+			Notes:             req.Notes,
+			ParentValuationID: req.ParentValuationID,
+		}
+		if err := createValuation(tx, &record); err != nil {
+			return err
+		}
+		valuation.ValuationID = &record.ID
+		if err := tx.Create(valuation).Error; err != nil {
+			return fmt.Errorf("failed to save cost valuation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return valuation, nil
+}
+
+func costApproachSettings() config.CostApproachConfig {
+	if config.Cfg == nil {
+		return config.CostApproachConfig{}
+	}
+	return config.Cfg.Valuation.CostApproach
+}
+
+// calculateCostApproach does the actual arithmetic, kept free of DB access.
+func calculateCostApproach(property *models.Property, req *schema.CostApproachRequest, settings config.CostApproachConfig) (*models.CostValuation, error) {
+	method := req.DepreciationMethod
+	if method == "" {
+		method = settings.DepreciationMethod
+	}
+	if method == "" {
+		method = DepreciationStraightLine
+	}
+
+	// 1. Gross floor area
+	area := req.BuildingAreaSqm
+	if area == nil {
+		area = property.BuildingSizeSqm
+	}
+	if area == nil || *area <= 0 {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Property has no usable building size (%v %s), pass building_area_sqm", derefFloat(property.BuildingSize), property.BuildingSizeUnit))
+	}
+
+	// 2. Rebuild rate
+	rate, rateSource := 0.0, "request"
+	if req.RatePerSqm != nil {
+		rate = *req.RatePerSqm
+	} else {
+		rate, rateSource = resolveRebuildRate(settings, derefString(property.PropertyType), property.Location.District)
+	}
+	if rate <= 0 {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("No rebuild rate configured for property type '%s' in district '%s', pass rate_per_sqm", derefString(property.PropertyType), property.Location.District))
+	}
+
+	// 3. Depreciation
+	age, eul, rel := property.Age, property.Eul, property.Rel
+	if req.Age != nil {
+		age = req.Age
+	}
+	if req.Eul != nil {
+		eul = req.Eul
+	}
+	if req.Rel != nil {
+		rel = req.Rel
+	}
+	depRate, depBasis, err := depreciationRate(method, age, eul, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	replacementCost := roundTo(*area*rate, 2)
+	depreciation := roundTo(replacementCost*depRate, 2)
+	depreciatedCost := roundTo(replacementCost-depreciation, 2)
+
+	valuation := &models.CostValuation{
+		PropertyID:         property.ID,
+		ValuerID:           req.ValuerID,
+		DepreciationMethod: method,
+		BuildingAreaSqm:    *area,
+		RatePerSqm:         rate,
+		RateSource:         rateSource,
+		Age:                age,
+		Eul:                eul,
+		Rel:                rel,
+		ReplacementCost:    replacementCost,
+		DepreciationRate:   roundTo(depRate, 4),
+		Depreciation:       depreciation,
+		DepreciatedCost:    depreciatedCost,
+		Notes:              req.Notes,
+	}
+
+	lineItems := []models.CostLineItem{
+		{Label: "Replacement cost new", Basis: fmt.Sprintf("%.2f sqm x %.2f per sqm (%s)", *area, rate, rateSource), Amount: replacementCost},
+		{Label: "Less depreciation", Basis: depBasis, Amount: -depreciation},
+		{Label: "Depreciated replacement cost", Basis: "Replacement cost new less depreciation", Amount: depreciatedCost},
+	}
+
+	// 4. Land value
+	landItem := models.CostLineItem{Label: "Land value"}
+	switch {
+	case req.LandValue != nil:
+		valuation.LandValue = *req.LandValue
+		landItem.Basis = "Provided in request"
+	default:
+		landArea := property.LandSizeSqm
+		landRate := req.LandRatePerSqm
+		if landRate == nil {
+			if r := resolveLandRate(settings, property.Location.District); r > 0 {
+				landRate = &r
+			}
+		}
+		if landArea != nil && landRate != nil {
+			valuation.LandAreaSqm = landArea
+			valuation.LandRatePerSqm = landRate
+			valuation.LandValue = roundTo(*landArea**landRate, 2)
+			landItem.Basis = fmt.Sprintf("%.2f sqm x %.2f per sqm", *landArea, *landRate)
+		} else {
+			landItem.Basis = "Not valued: no usable land size or land rate, pass land_value"
+		}
+	}
+	landItem.Amount = valuation.LandValue
+	lineItems = append(lineItems, landItem)
+
+	valuation.TotalValue = roundTo(depreciatedCost+valuation.LandValue, 2)
+	lineItems = append(lineItems, models.CostLineItem{Label: "Market value (cost approach)", Basis: "Depreciated replacement cost plus land value", Amount: valuation.TotalValue})
+
+	itemsJSON, err := json.Marshal(lineItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode line items: %w", err)
+	}
+	valuation.LineItems = datatypes.JSON(itemsJSON)
+
+	return valuation, nil
+}
+
+// depreciationRate returns the share of replacement cost lost to age, capped to 0-1.
+func depreciationRate(method string, age, eul, rel *int) (float64, string, error) {
+	if age == nil {
+		return 0, "", utils.NewBadRequestError("Property has no age, pass age to compute depreciation")
+	}
+	var rate float64
+	var basis string
+	switch method {
+	case DepreciationStraightLine:
+		if eul == nil || *eul <= 0 {
+			return 0, "", utils.NewBadRequestError("Straight-line depreciation needs an estimated useful life (eul)")
+		}
+		rate = float64(*age) / float64(*eul)
+		basis = fmt.Sprintf("Straight line: age %d / EUL %d", *age, *eul)
+	case DepreciationAgeLife:
+		if rel == nil {
+			return 0, "", utils.NewBadRequestError("Age-life depreciation needs a remaining economic life (rel)")
+		}
+		totalLife := *age + *rel
+		if totalLife <= 0 {
+			return 0, "", utils.NewBadRequestError("Age plus remaining economic life must be greater than zero")
+		}
+		rate = float64(*age) / float64(totalLife)
+		basis = fmt.Sprintf("Age-life: age %d / (age %d + REL %d)", *age, *age, *rel)
+	default:
+		return 0, "", utils.NewBadRequestError(fmt.Sprintf("Unknown depreciation method '%s'", method))
+	}
+	rate = clamp01(rate)
+	return rate, fmt.Sprintf("%s = %.2f%%", basis, rate*100), nil
+}
+
+// resolveRebuildRate picks the most specific configured rate for a type and district.
+func resolveRebuildRate(settings config.CostApproachConfig, propertyType, district string) (float64, string) {
+	bestScore := -1
+	best := 0.0
+	source := ""
+	for _, r := range settings.RebuildRates {
+		if r.PropertyType != "" && !strings.EqualFold(r.PropertyType, propertyType) {
+			continue
+		}
+		if r.District != "" && !strings.EqualFold(r.District, district) {
+			continue
+		}
+		score := 0
+		if r.District != "" {
+			score += 2 // District is more specific than type, construction costs vary by location
+		}
+		if r.PropertyType != "" {
+			score++
+		}
+		if score > bestScore {
+			bestScore, best = score, r.RatePerSqm
+			source = fmt.Sprintf("configured rate for type '%s', district '%s'", r.PropertyType, r.District)
+		}
+	}
+	if bestScore >= 0 {
+		return best, source
+	}
+	return settings.DefaultRatePerSqm, "default configured rate"
+}
+
+func resolveLandRate(settings config.CostApproachConfig, district string) float64 {
+	for _, r := range settings.LandRates {
+		if strings.EqualFold(r.District, district) {
+			return r.RatePerSqm
+		}
+	}
+	return settings.DefaultLandRatePerSqm
+}
+
+func derefFloat(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
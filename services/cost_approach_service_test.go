@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestDepreciationRate(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		age, eul, rel *int
+		want          float64
+		wantErr       string
+	}{
+		{name: "straight line", method: DepreciationStraightLine, age: intPtr(10), eul: intPtr(50), want: 0.2},
+		{name: "straight line, new", method: DepreciationStraightLine, age: intPtr(0), eul: intPtr(50), want: 0},
+		{name: "straight line, at EUL", method: DepreciationStraightLine, age: intPtr(50), eul: intPtr(50), want: 1},
+		{name: "straight line, past EUL is capped", method: DepreciationStraightLine, age: intPtr(80), eul: intPtr(50), want: 1},
+		{name: "straight line without EUL", method: DepreciationStraightLine, age: intPtr(10), wantErr: "estimated useful life"},
+		{name: "straight line with zero EUL", method: DepreciationStraightLine, age: intPtr(10), eul: intPtr(0), wantErr: "estimated useful life"},
+		{name: "age-life", method: DepreciationAgeLife, age: intPtr(10), rel: intPtr(30), want: 0.25},
+		{name: "age-life, new", method: DepreciationAgeLife, age: intPtr(0), rel: intPtr(60), want: 0},
+		{name: "age-life, no life left", method: DepreciationAgeLife, age: intPtr(40), rel: intPtr(0), want: 1},
+		{name: "age-life ignores EUL", method: DepreciationAgeLife, age: intPtr(10), eul: intPtr(20), rel: intPtr(30), want: 0.25},
+		{name: "age-life without REL", method: DepreciationAgeLife, age: intPtr(10), eul: intPtr(50), wantErr: "remaining economic life"},
+		{name: "age-life with no life at all", method: DepreciationAgeLife, age: intPtr(0), rel: intPtr(0), wantErr: "greater than zero"},
+		{name: "no age", method: DepreciationStraightLine, eul: intPtr(50), wantErr: "no age"},
+		{name: "unknown method", method: "declining_balance", age: intPtr(10), eul: intPtr(50), wantErr: "Unknown depreciation method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, basis, err := depreciationRate(tt.method, tt.age, tt.eul, tt.rel)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, rate, 1e-9)
+			assert.NotEmpty(t, basis)
+		})
+	}
+}
+
+func TestResolveRebuildRate(t *testing.T) {
+	settings := config.CostApproachConfig{
+		DefaultRatePerSqm: 100,
+		RebuildRates: []config.RebuildRate{
+			{PropertyType: "House", RatePerSqm: 200},
+			{District: "Lilongwe", RatePerSqm: 300},
+			{PropertyType: "House", District: "Lilongwe", RatePerSqm: 400},
+			{PropertyType: "Flat", District: "Blantyre", RatePerSqm: 500},
+		},
+	}
+	tests := []struct {
+		name         string
+		propertyType string
+		district     string
+		want         float64
+	}{
+		{name: "type and district", propertyType: "house", district: "LILONGWE", want: 400},
+		{name: "district beats type", propertyType: "Flat", district: "Lilongwe", want: 300},
+		{name: "type only", propertyType: "House", district: "Zomba", want: 200},
+		{name: "nothing matches", propertyType: "Shop", district: "Zomba", want: 100},
+		{name: "type and district must both match", propertyType: "Flat", district: "Zomba", want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, source := resolveRebuildRate(settings, tt.propertyType, tt.district)
+			assert.Equal(t, tt.want, rate)
+			assert.NotEmpty(t, source)
+		})
+	}
+}
+
+func TestCalculateCostApproach(t *testing.T) {
+	houseType := "House"
+	area, land := 150.0, 1000.0
+	property := &models.Property{
+		PropertyType:    &houseType,
+		BuildingSizeSqm: &area,
+		LandSizeSqm:     &land,
+		Age:             intPtr(60),
+		Eul:             intPtr(50),
+		Rel:             intPtr(20),
+		Location:        models.Location{District: "Lilongwe"},
+	}
+	settings := config.CostApproachConfig{
+		RebuildRates: []config.RebuildRate{{District: "Lilongwe", RatePerSqm: 200000}},
+		LandRates:    []config.LandRate{{District: "lilongwe", RatePerSqm: 5000}},
+	}
+
+	// Older than its EUL, the building is fully depreciated and only the land is left
+	valuation, err := calculateCostApproach(property, &schema.CostApproachRequest{}, settings)
+	require.NoError(t, err)
+	assert.Equal(t, DepreciationStraightLine, valuation.DepreciationMethod)
+	assert.Equal(t, 30000000.0, valuation.ReplacementCost)
+	assert.Equal(t, 1.0, valuation.DepreciationRate)
+	assert.Equal(t, 0.0, valuation.DepreciatedCost)
+	assert.Equal(t, 5000000.0, valuation.LandValue)
+	assert.Equal(t, 5000000.0, valuation.TotalValue)
+
+	var items []models.CostLineItem
+	require.NoError(t, json.Unmarshal(valuation.LineItems, &items))
+	require.Len(t, items, 5)
+	assert.Equal(t, -30000000.0, items[1].Amount)
+
+	// The request overrides the property's figures
+	valuation, err = calculateCostApproach(property, &schema.CostApproachRequest{
+		DepreciationMethod: DepreciationAgeLife,
+		Age:                intPtr(20),
+		RatePerSqm:         floatPtr(100000),
+		LandValue:          floatPtr(1000000),
+	}, settings)
+	require.NoError(t, err)
+	assert.Equal(t, "request", valuation.RateSource)
+	assert.Equal(t, 0.5, valuation.DepreciationRate) // 20 / (20 + 20)
+	assert.Equal(t, 7500000.0, valuation.DepreciatedCost)
+	assert.Equal(t, 8500000.0, valuation.TotalValue)
+
+	// Age-life without a REL anywhere is refused
+	noREL := *property
+	noREL.Rel = nil
+	_, err = calculateCostApproach(&noREL, &schema.CostApproachRequest{DepreciationMethod: DepreciationAgeLife}, settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remaining economic life")
+
+	// Without any rate configured there is nothing to go on
+	_, err = calculateCostApproach(property, &schema.CostApproachRequest{}, config.CostApproachConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No rebuild rate configured")
+}
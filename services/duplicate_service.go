@@ -0,0 +1,668 @@
+// services/duplicate_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Defaults used when the duplicates section is missing from config.yaml
+var defaultDuplicatesConfig = config.DuplicatesConfig{
+	MinScore:      0.8,
+	MaxDistanceKm: 0.5,
+	MaxPairs:      20000,
+	Weights: config.DuplicateWeights{
+		OwnerName: 3,
+		Location:  3,
+		Size:      2,
+		Design:    1,
+	},
+}
+
+// A pair needs data on at least this share of the total weight to be scored, otherwise
+// two sparse rows that agree on a single factor would look like a perfect match.
+const minDuplicateEvidence = 0.5
+
+type DuplicateService struct {
+	DB *gorm.DB
+}
+
+func NewDuplicateService(db *gorm.DB) *DuplicateService {
+	return &DuplicateService{DB: db}
+}
+
+func (s *DuplicateService) WithContext(ctx context.Context) *DuplicateService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+func duplicatesSettings() config.DuplicatesConfig {
+	settings := defaultDuplicatesConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Duplicates
+	if cfg.MinScore > 0 {
+		settings.MinScore = cfg.MinScore
+	}
+	if cfg.MaxDistanceKm > 0 {
+		settings.MaxDistanceKm = cfg.MaxDistanceKm
+	}
+	if cfg.MaxPairs > 0 {
+		settings.MaxPairs = cfg.MaxPairs
+	}
+	if cfg.Weights != (config.DuplicateWeights{}) {
+		settings.Weights = cfg.Weights
+	}
+	return settings
+}
+
+// duplicateFields holds the normalised values a property is compared on, so each
+// property is normalised once rather than once per pair.
+type duplicateFields struct {
+	property *models.Property
+	owner    string
+	place    string // Area and sub-area
+	design   string // Property type and design
+}
+
+func newDuplicateFields(p *models.Property) duplicateFields {
+	return duplicateFields{
+		property: p,
+		owner:    utils.NormaliseName(p.OwnerName),
+		place:    utils.NormaliseText(p.Location.Area + " " + derefString(p.Location.SubArea)),
+		design:   utils.NormaliseText(derefString(p.PropertyType) + " " + p.PropertyDesign),
+	}
+}
+
+// scoreDuplicate rates how likely two properties are the same house. Unlike comparables a
+// factor only counts when both sides have data. ok is false when the pair is too far apart
+// or there is too little data to judge.
+func scoreDuplicate(a, b duplicateFields, settings config.DuplicatesConfig) (score float64, breakdown []schema.ComparableScoreComponent, ok bool) {
+	w := settings.Weights
+	var availableWeight, weightedSum float64
+
+	add := func(factor string, weight float64, available bool, similarity float64) {
+		if weight <= 0 {
+			return
+		}
+		breakdown = append(breakdown, schema.ComparableScoreComponent{
+			Factor:     factor,
+			Weight:     weight,
+			Similarity: roundTo(similarity, 4),
+			Available:  available,
+		})
+		if available {
+			availableWeight += weight
+			weightedSum += weight * similarity
+		}
+	}
+
+	add("owner_name", w.OwnerName, a.owner != "" && b.owner != "", utils.StringSimilarity(a.owner, b.owner))
+
+	// Coordinates decide the location when both sides have them, the area names otherwise
+	la, lb := a.property.Location, b.property.Location
+	if la.Lat != nil && la.Lng != nil && lb.Lat != nil && lb.Lng != nil {
+		d := utils.HaversineKm(*la.Lat, *la.Lng, *lb.Lat, *lb.Lng)
+		if d > settings.MaxDistanceKm {
+			return 0, nil, false
+		}
+		add("location", w.Location, true, clamp01(1-d/settings.MaxDistanceKm))
+	} else {
+		add("location", w.Location, a.place != "" && b.place != "", utils.StringSimilarity(a.place, b.place))
+	}
+
+	// Sizes are compared in square metres, averaging whichever of building and land both have
+	var sizeSum float64
+	var sizeCount int
+	if sim, ok := sizeSimilarity(a.property.BuildingSizeSqm, b.property.BuildingSizeSqm); ok {
+		sizeSum += sim
+		sizeCount++
+	}
+	if sim, ok := sizeSimilarity(a.property.LandSizeSqm, b.property.LandSizeSqm); ok {
+		sizeSum += sim
+		sizeCount++
+	}
+	sizeSim := 0.0
+	if sizeCount > 0 {
+		sizeSim = sizeSum / float64(sizeCount)
+	}
+	add("size", w.Size, sizeCount > 0, sizeSim)
+
+	add("design", w.Design, a.design != "" && b.design != "", utils.StringSimilarity(a.design, b.design))
+
+	totalWeight := w.OwnerName + w.Location + w.Size + w.Design
+	if availableWeight == 0 || availableWeight < minDuplicateEvidence*totalWeight {
+		return 0, nil, false
+	}
+	for i := range breakdown {
+		c := &breakdown[i]
+		if c.Available {
+			c.Contribution = roundTo(c.Weight*c.Similarity/availableWeight, 4)
+		}
+	}
+	return roundTo(weightedSum/availableWeight, 4), breakdown, true
+}
+
+// duplicatePairsSQL lists the pairs worth scoring: same institution and district, and either
+// close together when both have coordinates, or without them sharing an area, a similar owner
+// name or a similar size. The rest could not reach the minimum score, or only on design alone.
+const duplicatePairsSQL = `
+SELECT a.id AS property_id, b.id AS duplicate_id
+FROM properties a
+JOIN locations la ON la.property_id = a.id
+JOIN locations lb ON upper(trim(lb.district)) = upper(trim(la.district)) AND lb.property_id > a.id
+JOIN properties b ON b.id = lb.property_id
+	AND b.financial_institution_id IS NOT DISTINCT FROM a.financial_institution_id
+WHERE trim(la.district) <> '' AND @tenant
+	AND CASE WHEN la.lat IS NOT NULL AND la.lng IS NOT NULL AND lb.lat IS NOT NULL AND lb.lng IS NOT NULL
+		THEN abs(lb.lat - la.lat) <= @latDelta AND abs(lb.lng - la.lng) <= @latDelta / greatest(cos(radians(la.lat)), 0.01)
+		ELSE (a.owner_name <> '' AND b.owner_name <> '' AND similarity(a.owner_name, b.owner_name) >= @ownerSimilarity)
+			OR (trim(la.area) <> '' AND lower(trim(la.area)) = lower(trim(lb.area)))
+			OR (a.building_size_sqm > 0 AND b.building_size_sqm > 0
+				AND least(a.building_size_sqm, b.building_size_sqm) >= @sizeRatio * greatest(a.building_size_sqm, b.building_size_sqm))
+			OR (a.land_size_sqm > 0 AND b.land_size_sqm > 0
+				AND least(a.land_size_sqm, b.land_size_sqm) >= @sizeRatio * greatest(a.land_size_sqm, b.land_size_sqm))
+	END
+ORDER BY a.id, b.id
+LIMIT @limit`
+
+const (
+	duplicateOwnerSimilarity = 0.5  // Minimum trigram similarity of owner names for a pair without coordinates
+	duplicateSizeRatio       = 0.8  // Minimum smaller/larger size for a pair without coordinates
+	duplicateLoadBatch       = 1000 // Properties loaded per query when scoring pairs
+)
+
+// Scan scores the pairs of properties within a district that duplicatePairsSQL picks out and
+// stores those scoring at least the configured minimum. Reviewed pairs keep their status,
+// pending pairs that no longer qualify are removed. At most duplicates.max_pairs pairs are
+// scored per run, when there are more the result says so and no pending pair is removed.
+func (s *DuplicateService) Scan() (*schema.DuplicateScanResult, error) {
+	settings := duplicatesSettings()
+	result := &schema.DuplicateScanResult{}
+
+	var pairs []struct {
+		PropertyID  uint
+		DuplicateID uint
+	}
+	err := s.DB.Raw(duplicatePairsSQL, map[string]interface{}{
+		"latDelta":        settings.MaxDistanceKm / 111.0, // ~111km per degree of latitude
+		"ownerSimilarity": duplicateOwnerSimilarity,
+		"sizeRatio":       duplicateSizeRatio,
+		"limit":           settings.MaxPairs + 1,
+		"tenant":          tenantExpr(s.DB, "a"), // Raw SQL, the tenant callbacks don't apply
+	}).Scan(&pairs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate pairs: %w", err)
+	}
+	if len(pairs) > settings.MaxPairs {
+		log.Printf("Warning: duplicate scan found more than %d pairs, scoring the first %d.\n", settings.MaxPairs, settings.MaxPairs)
+		pairs = pairs[:settings.MaxPairs]
+		result.Truncated = true
+	}
+
+	// Each property is loaded and normalised once however many pairs it is in
+	fields := map[uint]duplicateFields{}
+	var ids []uint
+	for _, pair := range pairs {
+		for _, id := range []uint{pair.PropertyID, pair.DuplicateID} {
+			if _, ok := fields[id]; !ok {
+				fields[id] = duplicateFields{}
+				ids = append(ids, id)
+			}
+		}
+	}
+	for start := 0; start < len(ids); start += duplicateLoadBatch {
+		end := start + duplicateLoadBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch []models.Property
+		if err := s.DB.Preload("Location").Where("id IN ?", ids[start:end]).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to load properties for duplicate scan: %w", err)
+		}
+		for i := range batch {
+			fields[batch[i].ID] = newDuplicateFields(&batch[i])
+		}
+	}
+	result.Properties = len(ids)
+
+	var found []models.DuplicateCandidate
+	for _, pair := range pairs {
+		a, b := fields[pair.PropertyID], fields[pair.DuplicateID]
+		if a.property == nil || b.property == nil { // Removed since the pairs were listed
+			continue
+		}
+		result.PairsCompared++
+		score, breakdown, ok := scoreDuplicate(a, b, settings)
+		if !ok || score < settings.MinScore {
+			continue
+		}
+		breakdownJSON, err := json.Marshal(breakdown)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode duplicate score breakdown: %w", err)
+		}
+		found = append(found, models.DuplicateCandidate{
+			PropertyID:  pair.PropertyID,
+			DuplicateID: pair.DuplicateID,
+			Score:       score,
+			Breakdown:   breakdownJSON,
+			Status:      models.DuplicateStatusPending,
+		})
+	}
+	result.Candidates = len(found)
+
+	var existing []models.DuplicateCandidate
+	if err := s.DB.Select("id", "property_id", "duplicate_id", "status").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load existing duplicate candidates: %w", err)
+	}
+	seen := make(map[[2]uint]bool, len(found))
+	for _, c := range found {
+		seen[[2]uint{c.PropertyID, c.DuplicateID}] = true
+	}
+	known := make(map[[2]uint]bool, len(existing))
+	var stale []uint
+	for _, c := range existing {
+		key := [2]uint{c.PropertyID, c.DuplicateID}
+		known[key] = true
+		// A cut-short scan has not seen every pair, so it can't tell which no longer qualify
+		if c.Status == models.DuplicateStatusPending && !seen[key] && !result.Truncated {
+			stale = append(stale, c.ID)
+		}
+	}
+	for _, c := range found {
+		if !known[[2]uint{c.PropertyID, c.DuplicateID}] {
+			result.NewCandidates++
+		}
+	}
+
+	if len(stale) == 0 && len(found) == 0 {
+		return result, nil
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if len(stale) > 0 {
+			if err := tx.Delete(&models.DuplicateCandidate{}, stale).Error; err != nil {
+				return err
+			}
+		}
+		if len(found) == 0 {
+			return nil
+		}
+		// Rescoring an existing pair leaves its review status alone
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "property_id"}, {Name: "duplicate_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "breakdown", "updated_at"}),
+		}).CreateInBatches(&found, 200).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save duplicate candidates: %w", err)
+	}
+	result.Removed = len(stale)
+	return result, nil
+}
+
+// GetCandidates retrieves candidate pairs, highest score first.
+func (s *DuplicateService) GetCandidates(pag schema.PaginationRequest, filter schema.DuplicateCandidateFilter) ([]schema.DuplicateCandidateResponse, int64, error) {
+	query := s.DB.Model(&models.DuplicateCandidate{})
+	if isTenantScoped(s.DB) {
+		// Both listings of a pair must be visible, or the pair would give the other one away
+		visible := visiblePropertyIDs(s.DB)
+		query = query.Where("property_id IN (?) AND duplicate_id IN (?)", visible, visible)
+	}
+	if filter.Status != nil && *filter.Status != "" {
+		query = query.Where("status = ?", strings.ToLower(*filter.Status))
+	}
+	if filter.MinScore != nil {
+		query = query.Where("score >= ?", *filter.MinScore)
+	}
+	if filter.PropertyID != nil {
+		query = query.Where("property_id = ? OR duplicate_id = ?", *filter.PropertyID, *filter.PropertyID)
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count duplicate candidates: %w", err)
+	}
+
+	var candidates []models.DuplicateCandidate
+	err := query.Order("score DESC, id").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve duplicate candidates: %w", err)
+	}
+
+	responses, err := s.mapCandidatesToResponse(candidates)
+	if err != nil {
+		return nil, 0, err
+	}
+	return responses, totalItems, nil
+}
+
+// ReviewCandidate confirms or dismisses a pending or previously reviewed pair.
+func (s *DuplicateService) ReviewCandidate(id uint, req *schema.DuplicateReviewRequest) (*schema.DuplicateCandidateResponse, error) {
+	candidate, err := s.findCandidate(s.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Status == models.DuplicateStatusMerged || candidate.Status == models.DuplicateStatusObsolete {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Duplicate candidate is already %s", candidate.Status))
+	}
+
+	now := time.Now()
+	candidate.Status = req.Status
+	candidate.ReviewNote = strings.TrimSpace(req.Note)
+	candidate.ReviewedAt = &now
+	err = s.DB.Model(candidate).Select("status", "review_note", "reviewed_at").Updates(candidate).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update duplicate candidate %d: %w", id, err)
+	}
+
+	responses, err := s.mapCandidatesToResponse([]models.DuplicateCandidate{*candidate})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// MergeCandidate keeps one property of the pair and removes the other. Fields the canonical
+// property is missing are taken from the other one, valuations move across, and the removed
+// ID is recorded so the sync does not bring it back.
+func (s *DuplicateService) MergeCandidate(id uint, req *schema.DuplicateMergeRequest) (*schema.DuplicateMergeResult, error) {
+	result := &schema.DuplicateMergeResult{}
+	var canonical models.Property
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		candidate, err := s.findCandidate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+		if err != nil {
+			return err
+		}
+		switch candidate.Status {
+		case models.DuplicateStatusDismissed, models.DuplicateStatusMerged, models.DuplicateStatusObsolete:
+			return utils.NewBadRequestError(fmt.Sprintf("Cannot merge a duplicate candidate that is %s", candidate.Status))
+		}
+
+		canonicalID, mergedID := candidate.PropertyID, candidate.DuplicateID
+		if req.CanonicalID != nil {
+			switch *req.CanonicalID {
+			case candidate.PropertyID:
+			case candidate.DuplicateID:
+				canonicalID, mergedID = candidate.DuplicateID, candidate.PropertyID
+			default:
+				return utils.NewBadRequestError("canonical_id must be one of the two properties in the pair")
+			}
+		}
+
+		var merged models.Property
+		if err := tx.Preload("Location").Preload("CoverPhoto").First(&canonical, canonicalID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Property")
+			}
+			return fmt.Errorf("failed to load property %d: %w", canonicalID, err)
+		}
+		if err := tx.Preload("Location").Preload("CoverPhoto").First(&merged, mergedID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Property")
+			}
+			return fmt.Errorf("failed to load property %d: %w", mergedID, err)
+		}
+
+		// Merging copies fields and valuations across, which would show one institution's
+		// private listing to another, or to everyone through a shared one
+		if !sameInstitution(canonical.FinancialInstitutionID, merged.FinancialInstitutionID) {
+			return utils.NewBadRequestError("Cannot merge properties of different financial institutions")
+		}
+
+		snapshot, err := json.Marshal(merged)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot property %d: %w", mergedID, err)
+		}
+
+		fillMissingPropertyFields(&canonical, &merged)
+		if err := tx.Omit(clause.Associations).Save(&canonical).Error; err != nil {
+			return fmt.Errorf("failed to update property %d: %w", canonicalID, err)
+		}
+		if err := mergeLocation(tx, &canonical, &merged); err != nil {
+			return err
+		}
+		if canonical.CoverPhoto.ID == 0 && merged.CoverPhoto.ID != 0 {
+			if err := tx.Model(&merged.CoverPhoto).Update("property_id", canonicalID).Error; err != nil {
+				return fmt.Errorf("failed to move cover photo: %w", err)
+			}
+			merged.CoverPhoto.ID = 0
+		}
+
+		// Valuation records follow the canonical property
+		for _, model := range []interface{}{&models.Valuation{}, &models.CostValuation{}, &models.IncomeValuation{}} {
+			moved := tx.Model(model).Where("property_id = ?", mergedID).Update("property_id", canonicalID)
+			if moved.Error != nil {
+				return fmt.Errorf("failed to move valuations: %w", moved.Error)
+			}
+			result.MovedValuations += int(moved.RowsAffected)
+		}
+
+		// Remove the merged property and what hangs off it
+		if err := tx.Where("property_id = ?", mergedID).Delete(&models.QualityIssue{}).Error; err != nil {
+			return fmt.Errorf("failed to remove quality issues: %w", err)
+		}
+		if merged.Location.ID != 0 {
+			if err := tx.Delete(&merged.Location).Error; err != nil {
+				return fmt.Errorf("failed to remove location: %w", err)
+			}
+		}
+		if merged.CoverPhoto.ID != 0 {
+			if err := tx.Delete(&merged.CoverPhoto).Error; err != nil {
+				return fmt.Errorf("failed to remove cover photo: %w", err)
+			}
+		}
+		if err := tx.Delete(&models.Property{}, mergedID).Error; err != nil {
+			return fmt.Errorf("failed to remove property %d: %w", mergedID, err)
+		}
+
+		record := models.PropertyMerge{
+			MergedPropertyID:    mergedID,
+			CanonicalPropertyID: canonicalID,
+			CandidateID:         &candidate.ID,
+			Snapshot:            snapshot,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record merge: %w", err)
+		}
+
+		now := time.Now()
+		err = tx.Model(candidate).Updates(map[string]interface{}{
+			"status":      models.DuplicateStatusMerged,
+			"reviewed_at": now,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update duplicate candidate %d: %w", id, err)
+		}
+		// Other open pairs with the removed property no longer make sense
+		err = tx.Model(&models.DuplicateCandidate{}).
+			Where("id <> ? AND (property_id = ? OR duplicate_id = ?)", candidate.ID, mergedID, mergedID).
+			Where("status IN ?", []string{models.DuplicateStatusPending, models.DuplicateStatusConfirmed}).
+			Update("status", models.DuplicateStatusObsolete).Error
+		if err != nil {
+			return fmt.Errorf("failed to retire duplicate candidates of property %d: %w", mergedID, err)
+		}
+
+		// Filled-in fields and a moved location change what the canonical property is found by
+		if _, err := refreshSearchVectors(tx, "pr.id = ?", canonicalID); err != nil {
+			return err
+		}
+
+		result.CanonicalPropertyID = canonicalID
+		result.MergedPropertyID = mergedID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Filled-in fields may have fixed or caused quality issues
+	if err := checkPropertyQuality(s.DB, &canonical); err != nil {
+		log.Printf("Warning: quality checks after merging into property %d failed: %v\n", canonical.ID, err)
+	}
+	return result, nil
+}
+
+func (s *DuplicateService) findCandidate(db *gorm.DB, id uint) (*models.DuplicateCandidate, error) {
+	var candidate models.DuplicateCandidate
+	if err := db.First(&candidate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Duplicate candidate")
+		}
+		return nil, fmt.Errorf("database error retrieving duplicate candidate %d: %w", id, err)
+	}
+	return &candidate, nil
+}
+
+func sameInstitution(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// mergeLocation gives the canonical property the merged property's location if it has none,
+// or just its coordinates if those are what's missing.
+func mergeLocation(tx *gorm.DB, canonical, merged *models.Property) error {
+	if merged.Location.ID == 0 {
+		return nil
+	}
+	if canonical.Location.ID == 0 {
+		if err := tx.Model(&merged.Location).Update("property_id", canonical.ID).Error; err != nil {
+			return fmt.Errorf("failed to move location: %w", err)
+		}
+		canonical.Location = merged.Location
+		canonical.Location.PropertyID = canonical.ID
+		merged.Location.ID = 0
+		return nil
+	}
+	if canonical.Location.Lat == nil && merged.Location.Lat != nil {
+		loc := &canonical.Location
+		loc.Latitude, loc.Longitude = merged.Location.Latitude, merged.Location.Longitude
+		loc.Lat, loc.Lng = merged.Location.Lat, merged.Location.Lng
+		err := tx.Model(loc).Select("latitude", "longitude", "lat", "lng").Updates(loc).Error
+		if err != nil {
+			return fmt.Errorf("failed to copy coordinates: %w", err)
+		}
+	}
+	return nil
+}
+
+// fillMissingPropertyFields copies descriptive fields the canonical property lacks from the
+// merged one. Listing details such as price and status are left alone.
+func fillMissingPropertyFields(canonical, merged *models.Property) {
+	if canonical.PropertyType == nil {
+		canonical.PropertyType = merged.PropertyType
+	}
+	if canonical.PropertyDesign == "" {
+		canonical.PropertyDesign = merged.PropertyDesign
+	}
+	if canonical.OwnerName == "" {
+		canonical.OwnerName = merged.OwnerName
+	}
+	if canonical.YearBuilt == nil {
+		canonical.YearBuilt = merged.YearBuilt
+	}
+	if canonical.Age == nil {
+		canonical.Age = merged.Age
+	}
+	if canonical.Eul == nil {
+		canonical.Eul = merged.Eul
+	}
+	if canonical.Rel == nil {
+		canonical.Rel = merged.Rel
+	}
+	if canonical.NoRooms == nil {
+		canonical.NoRooms = merged.NoRooms
+	}
+	if canonical.NoOfBathrooms == nil {
+		canonical.NoOfBathrooms = merged.NoOfBathrooms
+	}
+	if canonical.BuildingSize == nil {
+		canonical.BuildingSize, canonical.BuildingSizeUnit = merged.BuildingSize, merged.BuildingSizeUnit
+		canonical.BuildingSizeSqm, canonical.BuildingPricePerSqm = merged.BuildingSizeSqm, nil
+	}
+	if canonical.LandSize == nil {
+		canonical.LandSize, canonical.LandSizeUnit = merged.LandSize, merged.LandSizeUnit
+		canonical.LandSizeSqm, canonical.LandPricePerSqm = merged.LandSizeSqm, nil
+	}
+	if canonical.Description == "" {
+		canonical.Description = merged.Description
+	}
+	if canonical.AgentID == nil {
+		canonical.AgentID = merged.AgentID
+	}
+	// Price per sqm is worked out from the canonical price, not copied
+	if canonical.Price != nil && *canonical.Price > 0 {
+		if canonical.BuildingPricePerSqm == nil && canonical.BuildingSizeSqm != nil && *canonical.BuildingSizeSqm > 0 {
+			v := *canonical.Price / *canonical.BuildingSizeSqm
+			canonical.BuildingPricePerSqm = &v
+		}
+		if canonical.LandPricePerSqm == nil && canonical.LandSizeSqm != nil && *canonical.LandSizeSqm > 0 {
+			v := *canonical.Price / *canonical.LandSizeSqm
+			canonical.LandPricePerSqm = &v
+		}
+	}
+}
+
+// mapCandidatesToResponse loads both sides of each pair in one query.
+func (s *DuplicateService) mapCandidatesToResponse(candidates []models.DuplicateCandidate) ([]schema.DuplicateCandidateResponse, error) {
+	ids := make([]uint, 0, len(candidates)*2)
+	for _, c := range candidates {
+		ids = append(ids, c.PropertyID, c.DuplicateID)
+	}
+	properties := map[uint]*schema.PropertyResponse{}
+	if len(ids) > 0 {
+		var rows []models.Property
+		err := s.DB.Preload("Location").
+			Preload("Agent.User").
+			Preload("CoverPhoto").
+			Where("id IN ?", ids).
+			Find(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load properties for duplicate candidates: %w", err)
+		}
+		for i := range rows {
+			response := MapPropertyToResponse(&rows[i])
+			properties[rows[i].ID] = &response
+		}
+	}
+
+	responses := make([]schema.DuplicateCandidateResponse, len(candidates))
+	for i, c := range candidates {
+		var breakdown []schema.ComparableScoreComponent
+		if len(c.Breakdown) > 0 {
+			if err := json.Unmarshal(c.Breakdown, &breakdown); err != nil {
+				return nil, fmt.Errorf("invalid score breakdown on duplicate candidate %d: %w", c.ID, err)
+			}
+		}
+		responses[i] = schema.DuplicateCandidateResponse{
+			ID:         c.ID,
+			Score:      c.Score,
+			Status:     c.Status,
+			ReviewNote: c.ReviewNote,
+			ReviewedAt: c.ReviewedAt,
+			Breakdown:  breakdown,
+			Property:   properties[c.PropertyID],
+			Duplicate:  properties[c.DuplicateID],
+			CreatedAt:  c.CreatedAt,
+		}
+	}
+	return responses, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	settings := defaultDuplicatesConfig
+	lat, lng := -13.9626, 33.7741
+	near := lat + 0.001 // About 110m north
+	size, similarSize := 200.0, 190.0
+
+	base := models.Property{
+		OwnerName:       "John Banda",
+		PropertyDesign:  "Bungalow",
+		BuildingSizeSqm: &size,
+		Location:        models.Location{Area: "Area 43", Lat: &lat, Lng: &lng},
+	}
+	same := base
+	same.OwnerName = "Banda John"
+	same.BuildingSizeSqm = &similarSize
+	same.Location.Lat = &near
+
+	score, breakdown, ok := scoreDuplicate(newDuplicateFields(&base), newDuplicateFields(&same), settings)
+	require.True(t, ok)
+	assert.Greater(t, score, settings.MinScore)
+	assert.Len(t, breakdown, 4)
+
+	far := same
+	farLat := lat + 0.1
+	far.Location.Lat = &farLat
+	_, _, ok = scoreDuplicate(newDuplicateFields(&base), newDuplicateFields(&far), settings)
+	assert.False(t, ok, "pairs beyond max_distance_km are not scored")
+
+	// Agreeing on design alone is too little to go on
+	sparse := models.Property{PropertyDesign: "Bungalow"}
+	_, _, ok = scoreDuplicate(newDuplicateFields(&sparse), newDuplicateFields(&sparse), settings)
+	assert.False(t, ok)
+}
+
+func TestDuplicateScan_NarrowsPairsInSQL(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+
+	_, err := NewDuplicateService(db).Scan()
+	require.NoError(t, err)
+
+	statements := recorder.touching(`JOIN locations lb`)
+	require.Len(t, statements, 1)
+	sql := statements[0]
+	assert.Contains(t, sql, `upper(trim(lb.district)) = upper(trim(la.district))`)
+	assert.Contains(t, sql, `similarity(a.owner_name, b.owner_name) >= 0.5`)
+	assert.Contains(t, sql, `LIMIT 20001`)
+	assert.Empty(t, recorder.touching(`FROM "properties"`), "no properties are loaded when no pair qualifies")
+}
+
+func TestDuplicateScan_StopsAtMaxPairs(t *testing.T) {
+	previous := config.Cfg
+	config.Cfg = &config.Config{Duplicates: config.DuplicatesConfig{MaxPairs: 1}}
+	defer func() { config.Cfg = previous }()
+
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`JOIN locations lb .* LIMIT \$6`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), duplicateOwnerSimilarity, duplicateSizeRatio, duplicateSizeRatio, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"property_id", "duplicate_id"}).AddRow(1, 2).AddRow(1, 3))
+	mock.ExpectQuery(`SELECT \* FROM "properties" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_name"}).AddRow(1, "John Banda").AddRow(2, "John Banda"))
+	mock.ExpectQuery(`SELECT \* FROM "locations" WHERE "locations"."property_id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "area"}).AddRow(10, 1, "Area 43").AddRow(11, 2, "Area 43"))
+	// A pending pair the scan did not get to is kept
+	mock.ExpectQuery(`SELECT "id","property_id","duplicate_id","status" FROM "duplicate_candidates"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "duplicate_id", "status"}).
+			AddRow(5, 4, 6, models.DuplicateStatusPending))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "duplicate_candidates"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	result, err := NewDuplicateService(db).Scan()
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, 1, result.PairsCompared)
+	assert.Equal(t, 2, result.Properties)
+	assert.Equal(t, 1, result.Candidates)
+	assert.Equal(t, 0, result.Removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,523 @@
+// services/filter_expr.go
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hopekali04/valuations/utils"
+)
+
+// Filter expressions are the `filter` query parameter, e.g.
+//
+//	price between 200000 and 400000 and (district = "BLANTYRE URBAN" or area ~ "Namiwawa") and no_rooms >= 3
+//
+// Grammar (keywords are case-insensitive):
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//	           | field [ "not" ] "between" value "and" value
+//	           | field [ "not" ] "in" "(" value { "," value } ")"
+//	           | field "is" [ "not" ] "null"
+//	op         = "=" | "!=" | "<>" | "<" | "<=" | ">" | ">=" | "~" | "!~"
+//	value      = number | "double quoted" | 'single quoted'
+//
+// Text compares ignore case and ~ means "contains". Dates are written as strings, 2024-01-31
+// or RFC 3339. Values always become query parameters, fields only come from filterFields.
+
+// Limits that keep a single expression cheap to parse and to run
+const (
+	maxFilterLength   = 1000
+	maxFilterDepth    = 16
+	maxFilterInValues = 100
+)
+
+type filterFieldType int
+
+const (
+	filterNumber filterFieldType = iota
+	filterText
+	filterTime
+)
+
+func (t filterFieldType) String() string {
+	switch t {
+	case filterNumber:
+		return "a number"
+	case filterTime:
+		return "a date"
+	default:
+		return "text"
+	}
+}
+
+type filterField struct {
+	Expr string
+	Type filterFieldType
+}
+
+// locationFilterSQL reads a location column for the property. A correlated subquery keeps the
+// expression usable next to the joins search adds.
+func locationFilterSQL(column string) string {
+	return "(SELECT l." + column + " FROM locations l WHERE l.property_id = properties.id LIMIT 1)"
+}
+
+// filterFields is every field a filter expression may use, by the name used in the expression.
+var filterFields = map[string]filterField{
+	// Property
+	"id":                     {"properties.id", filterNumber},
+	"owner_name":             {"properties.owner_name", filterText},
+	"property_type":          {"properties.property_type", filterText},
+	"property_design":        {"properties.property_design", filterText},
+	"construction_stage":     {"properties.construction_stage", filterText},
+	"listing_type":           {"properties.listing_type", filterText},
+	"occupancy":              {"properties.occupancy", filterText},
+	"description":            {"properties.description", filterText},
+	"entry_type":             {"properties.entry_type", filterText},
+	"visibility":             {"properties.visibility", filterText},
+	"is_approved":            {"properties.is_approved", filterText},
+	"is_sale_completed":      {"properties.is_sale_completed", filterText},
+	"title_deeds_available":  {"properties.title_deeds_available", filterText},
+	"price":                  {"properties.price", filterNumber},
+	"age":                    {"properties.age", filterNumber},
+	"eul":                    {"properties.eul", filterNumber},
+	"rel":                    {"properties.rel", filterNumber},
+	"no_rooms":               {"properties.no_rooms", filterNumber},
+	"no_of_bathrooms":        {"properties.no_of_bathrooms", filterNumber},
+	"building_size_sqm":      {"properties.building_size_sqm", filterNumber},
+	"land_size_sqm":          {"properties.land_size_sqm", filterNumber},
+	"building_price_per_sqm": {"properties.building_price_per_sqm", filterNumber},
+	"land_price_per_sqm":     {"properties.land_price_per_sqm", filterNumber},
+	"views":                  {"properties.views", filterNumber},
+	"agent_id":               {"properties.agent_id", filterNumber},
+	"created_at":             {"properties.created_at", filterTime},
+	"approved_at":            {"properties.approved_at", filterTime},
+
+	// Location
+	"region":        {locationFilterSQL("region"), filterText},
+	"district":      {locationFilterSQL("district"), filterText},
+	"area":          {locationFilterSQL("area"), filterText},
+	"sub_area":      {locationFilterSQL("sub_area"), filterText},
+	"zoning":        {locationFilterSQL("zoning"), filterText},
+	"zone_category": {locationFilterSQL("zone_category"), filterText},
+	"lat":           {locationFilterSQL("lat"), filterNumber},
+	"lng":           {locationFilterSQL("lng"), filterNumber},
+
+	// Agent
+	"agent.name":          {"(SELECT u.name FROM agents a JOIN users u ON u.id = a.user_id WHERE a.id = properties.agent_id)", filterText},
+	"agent.type":          {"(SELECT a.agent_type FROM agents a WHERE a.id = properties.agent_id)", filterText},
+	"agent.coverage_area": {"(SELECT a.coverage_area FROM agents a WHERE a.id = properties.agent_id)", filterText},
+}
+
+// lookupFilterField also accepts the location fields with a "location." prefix.
+func lookupFilterField(name string) (filterField, bool) {
+	name = strings.ToLower(name)
+	if field, ok := filterFields[name]; ok {
+		return field, true
+	}
+	if rest, ok := strings.CutPrefix(name, "location."); ok && !strings.Contains(rest, ".") {
+		field, ok := filterFields[rest]
+		return field, ok && strings.HasPrefix(field.Expr, "(SELECT l.")
+	}
+	return filterField{}, false
+}
+
+// --- Lexer ---
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type filterToken struct {
+	Kind  filterTokenKind
+	Text  string // Identifier, operator or the unquoted string
+	Value float64
+	Pos   int // 1-based character position in the expression
+}
+
+func filterError(pos int, format string, args ...interface{}) error {
+	return utils.NewBadRequestError(fmt.Sprintf("Invalid filter at position %d: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+func lexFilter(input string) ([]filterToken, error) {
+	runes := []rune(input)
+	var tokens []filterToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, filterToken{Kind: tokenLParen, Text: "(", Pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{Kind: tokenRParen, Text: ")", Pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{Kind: tokenComma, Text: ",", Pos: pos})
+			i++
+
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			closed := false
+			for j < len(runes) {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					b.WriteRune(runes[j+1])
+					j += 2
+					continue
+				}
+				if runes[j] == r {
+					closed = true
+					break
+				}
+				b.WriteRune(runes[j])
+				j++
+			}
+			if !closed {
+				return nil, filterError(pos, "string is not closed")
+			}
+			tokens = append(tokens, filterToken{Kind: tokenString, Text: b.String(), Pos: pos})
+			i = j + 1
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			text := string(runes[i:j])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, filterError(pos, "invalid number '%s'", text)
+			}
+			tokens = append(tokens, filterToken{Kind: tokenNumber, Text: text, Value: value, Pos: pos})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, filterToken{Kind: tokenIdent, Text: string(runes[i:j]), Pos: pos})
+			i = j
+
+		default:
+			op := ""
+			for _, candidate := range []string{"<=", ">=", "!=", "<>", "!~", "=", "<", ">", "~"} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, filterError(pos, "unexpected character '%c'", r)
+			}
+			tokens = append(tokens, filterToken{Kind: tokenOperator, Text: op, Pos: pos})
+			i += len([]rune(op))
+		}
+	}
+	tokens = append(tokens, filterToken{Kind: tokenEOF, Pos: len(runes) + 1})
+	return tokens, nil
+}
+
+// --- Parser ---
+
+// filterParser compiles the tokens straight into a WHERE fragment with ? placeholders.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	depth  int
+	args   []interface{}
+}
+
+// compileFilterExpr turns a filter expression into SQL and its arguments for query.Where.
+func compileFilterExpr(input string) (string, []interface{}, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", nil, nil
+	}
+	if len([]rune(input)) > maxFilterLength {
+		return "", nil, utils.NewBadRequestError(fmt.Sprintf("Filter is longer than %d characters", maxFilterLength))
+	}
+	tokens, err := lexFilter(input)
+	if err != nil {
+		return "", nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	sql, err := p.parseOr()
+	if err != nil {
+		return "", nil, err
+	}
+	if tok := p.peek(); tok.Kind != tokenEOF {
+		return "", nil, filterError(tok.Pos, "unexpected '%s'", tok.Text)
+	}
+	return sql, p.args, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.Kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword reports whether tok is the given keyword, ignoring case.
+func isKeyword(tok filterToken, keyword string) bool {
+	return tok.Kind == tokenIdent && strings.EqualFold(tok.Text, keyword)
+}
+
+func (p *filterParser) expectKeyword(keyword string) error {
+	tok := p.next()
+	if !isKeyword(tok, keyword) {
+		return filterError(tok.Pos, "expected '%s'%s", keyword, describeToken(tok))
+	}
+	return nil
+}
+
+func describeToken(tok filterToken) string {
+	if tok.Kind == tokenEOF {
+		return " but the filter ended"
+	}
+	return fmt.Sprintf(" but found '%s'", tok.Text)
+}
+
+func (p *filterParser) parseOr() (string, error) {
+	parts := []string{}
+	for {
+		part, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+		if !isKeyword(p.peek(), "or") {
+			break
+		}
+		p.next()
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", nil
+}
+
+func (p *filterParser) parseAnd() (string, error) {
+	parts := []string{}
+	for {
+		part, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+		if !isKeyword(p.peek(), "and") {
+			break
+		}
+		p.next()
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", nil
+}
+
+func (p *filterParser) parseUnary() (string, error) {
+	tok := p.peek()
+	if isKeyword(tok, "not") || tok.Kind == tokenLParen {
+		p.depth++
+		if p.depth > maxFilterDepth {
+			return "", filterError(tok.Pos, "nested more than %d levels deep", maxFilterDepth)
+		}
+		defer func() { p.depth-- }()
+	}
+
+	switch {
+	case isKeyword(tok, "not"):
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+
+	case tok.Kind == tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if closing := p.next(); closing.Kind != tokenRParen {
+			return "", filterError(closing.Pos, "expected ')'%s", describeToken(closing))
+		}
+		return "(" + inner + ")", nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (string, error) {
+	tok := p.next()
+	if tok.Kind != tokenIdent {
+		return "", filterError(tok.Pos, "expected a field name%s", describeToken(tok))
+	}
+	field, ok := lookupFilterField(tok.Text)
+	if !ok {
+		return "", filterError(tok.Pos, "unknown field '%s'", tok.Text)
+	}
+	name := strings.ToLower(tok.Text)
+
+	next := p.next()
+	negate := false
+	if isKeyword(next, "not") {
+		negate = true
+		next = p.next()
+		if !isKeyword(next, "between") && !isKeyword(next, "in") {
+			return "", filterError(next.Pos, "expected 'between' or 'in' after 'not'%s", describeToken(next))
+		}
+	}
+	not := ""
+	if negate {
+		not = "NOT "
+	}
+
+	switch {
+	case isKeyword(next, "between"):
+		if field.Type == filterText {
+			return "", filterError(next.Pos, "between needs a number or date field, %s is text", name)
+		}
+		low, err := p.parseValue(name, field)
+		if err != nil {
+			return "", err
+		}
+		if err := p.expectKeyword("and"); err != nil {
+			return "", err
+		}
+		high, err := p.parseValue(name, field)
+		if err != nil {
+			return "", err
+		}
+		p.args = append(p.args, low, high)
+		return fmt.Sprintf("%s %sBETWEEN ? AND ?", field.Expr, not), nil
+
+	case isKeyword(next, "in"):
+		open := p.next()
+		if open.Kind != tokenLParen {
+			return "", filterError(open.Pos, "expected '(' after in%s", describeToken(open))
+		}
+		var values []interface{}
+		for {
+			if len(values) == maxFilterInValues {
+				return "", filterError(p.peek().Pos, "in takes at most %d values", maxFilterInValues)
+			}
+			value, err := p.parseValue(name, field)
+			if err != nil {
+				return "", err
+			}
+			if field.Type == filterText {
+				value = strings.ToLower(value.(string))
+			}
+			values = append(values, value)
+			sep := p.next()
+			if sep.Kind == tokenRParen {
+				break
+			}
+			if sep.Kind != tokenComma {
+				return "", filterError(sep.Pos, "expected ',' or ')'%s", describeToken(sep))
+			}
+		}
+		p.args = append(p.args, values)
+		if field.Type == filterText {
+			return fmt.Sprintf("lower(%s) %sIN ?", field.Expr, not), nil
+		}
+		return fmt.Sprintf("%s %sIN ?", field.Expr, not), nil
+
+	case isKeyword(next, "is"):
+		not := ""
+		if isKeyword(p.peek(), "not") {
+			p.next()
+			not = "NOT "
+		}
+		if err := p.expectKeyword("null"); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s IS %sNULL", field.Expr, not), nil
+
+	case next.Kind == tokenOperator:
+		op := next.Text
+		if op == "<>" {
+			op = "!="
+		}
+		value, err := p.parseValue(name, field)
+		if err != nil {
+			return "", err
+		}
+
+		if op == "~" || op == "!~" {
+			if field.Type != filterText {
+				return "", filterError(next.Pos, "%s only works on text fields, %s is %s", op, name, field.Type)
+			}
+			p.args = append(p.args, "%"+escapeLike(value.(string))+"%")
+			if op == "!~" {
+				return fmt.Sprintf("%s NOT ILIKE ?", field.Expr), nil
+			}
+			return fmt.Sprintf("%s ILIKE ?", field.Expr), nil
+		}
+
+		sqlOp := map[string]string{"=": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">="}[op]
+		if field.Type == filterText {
+			if op != "=" && op != "!=" {
+				return "", filterError(next.Pos, "%s can't compare text, use = != or ~ on %s", op, name)
+			}
+			p.args = append(p.args, value)
+			return fmt.Sprintf("lower(%s) %s lower(?)", field.Expr, sqlOp), nil
+		}
+		p.args = append(p.args, value)
+		return fmt.Sprintf("%s %s ?", field.Expr, sqlOp), nil
+	}
+
+	return "", filterError(next.Pos, "expected an operator after %s%s", name, describeToken(next))
+}
+
+// parseValue reads a literal and checks it suits the field's type.
+func (p *filterParser) parseValue(name string, field filterField) (interface{}, error) {
+	tok := p.next()
+	switch field.Type {
+	case filterNumber:
+		if tok.Kind != tokenNumber {
+			return nil, filterError(tok.Pos, "%s needs a number%s", name, describeToken(tok))
+		}
+		return tok.Value, nil
+	case filterTime:
+		if tok.Kind != tokenString {
+			return nil, filterError(tok.Pos, "%s needs a quoted date%s", name, describeToken(tok))
+		}
+		for _, layout := range []string{"2006-01-02", time.RFC3339} {
+			if t, err := time.Parse(layout, tok.Text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, filterError(tok.Pos, "'%s' is not a date, use 2006-01-02 or RFC 3339", tok.Text)
+	default:
+		if tok.Kind != tokenString {
+			return nil, filterError(tok.Pos, "%s needs a quoted string%s", name, describeToken(tok))
+		}
+		return tok.Text, nil
+	}
+}
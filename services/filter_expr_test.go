@@ -0,0 +1,125 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexFilter(t *testing.T) {
+	tokens, err := lexFilter(`price >= -1.5 and (area ~ 'Area \'43\'' or district != "BLANTYRE") in (1,2)`)
+	require.NoError(t, err)
+
+	want := []filterToken{
+		{Kind: tokenIdent, Text: "price", Pos: 1},
+		{Kind: tokenOperator, Text: ">=", Pos: 7},
+		{Kind: tokenNumber, Text: "-1.5", Value: -1.5, Pos: 10},
+		{Kind: tokenIdent, Text: "and", Pos: 15},
+		{Kind: tokenLParen, Text: "(", Pos: 19},
+		{Kind: tokenIdent, Text: "area", Pos: 20},
+		{Kind: tokenOperator, Text: "~", Pos: 25},
+		{Kind: tokenString, Text: "Area '43'", Pos: 27},
+		{Kind: tokenIdent, Text: "or", Pos: 41},
+		{Kind: tokenIdent, Text: "district", Pos: 44},
+		{Kind: tokenOperator, Text: "!=", Pos: 53},
+		{Kind: tokenString, Text: "BLANTYRE", Pos: 56},
+		{Kind: tokenRParen, Text: ")", Pos: 66},
+		{Kind: tokenIdent, Text: "in", Pos: 68},
+		{Kind: tokenLParen, Text: "(", Pos: 71},
+		{Kind: tokenNumber, Text: "1", Value: 1, Pos: 72},
+		{Kind: tokenComma, Text: ",", Pos: 73},
+		{Kind: tokenNumber, Text: "2", Value: 2, Pos: 74},
+		{Kind: tokenRParen, Text: ")", Pos: 75},
+		{Kind: tokenEOF, Pos: 76},
+	}
+	assert.Equal(t, want, tokens)
+}
+
+func TestLexFilter_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`area = "Namiwawa`, "position 8: string is not closed"},
+		{`price = 1.2.3`, "position 9: invalid number '1.2.3'"},
+		{`price & 3`, "position 7: unexpected character '&'"},
+	}
+	for _, tt := range tests {
+		_, err := lexFilter(tt.input)
+		require.Error(t, err, tt.input)
+		assert.Contains(t, err.Error(), tt.want, tt.input)
+	}
+}
+
+func TestCompileFilterExpr(t *testing.T) {
+	jan31 := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	district := locationFilterSQL("district")
+
+	tests := []struct {
+		name     string
+		input    string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"empty", "  ", "", nil},
+		{"number compare", "price > 100000", "properties.price > ?", []interface{}{100000.0}},
+		{"not equal spelt <>", "no_rooms <> 3", "properties.no_rooms <> ?", []interface{}{3.0}},
+		{"text ignores case", `listing_type = "Sale"`, "lower(properties.listing_type) = lower(?)", []interface{}{"Sale"}},
+		{"contains escapes wildcards", `description ~ "50%_off"`, "properties.description ILIKE ?", []interface{}{`%50\%\_off%`}},
+		{"does not contain", `description !~ 'borehole'`, "properties.description NOT ILIKE ?", []interface{}{"%borehole%"}},
+		{"between dates", `created_at between "2024-01-31" and "2024-02-01T10:00:00Z"`, "properties.created_at BETWEEN ? AND ?",
+			[]interface{}{jan31, time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)}},
+		{"not between", "price not between 1 and 2", "properties.price NOT BETWEEN ? AND ?", []interface{}{1.0, 2.0}},
+		{"text in", `district in ("Lilongwe", 'ZOMBA')`, "lower(" + district + ") IN ?", []interface{}{[]interface{}{"lilongwe", "zomba"}}},
+		{"number not in", "agent_id not in (4, 5)", "properties.agent_id NOT IN ?", []interface{}{[]interface{}{4.0, 5.0}}},
+		{"is null", "approved_at is null", "properties.approved_at IS NULL", nil},
+		{"is not null", "LAT IS NOT NULL", locationFilterSQL("lat") + " IS NOT NULL", nil},
+		{"location prefix", `location.area = "Area 43"`, "lower(" + locationFilterSQL("area") + ") = lower(?)", []interface{}{"Area 43"}},
+		{"and binds tighter than or", "price > 1 or price < 2 and views > 3",
+			"(properties.price > ? OR (properties.price < ? AND properties.views > ?))", []interface{}{1.0, 2.0, 3.0}},
+		{"parentheses and not", "not (price > 1 or views > 2)", "NOT (((properties.price > ? OR properties.views > ?)))", []interface{}{1.0, 2.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := compileFilterExpr(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestCompileFilterExpr_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unknown field", "bedrooms = 3", "unknown field 'bedrooms'"},
+		{"prefix only for location", "location.price = 3", "unknown field 'location.price'"},
+		{"text for a number", `price = "cheap"`, "price needs a number"},
+		{"number for text", "district = 3", "district needs a quoted string"},
+		{"bad date", `created_at > "last week"`, "'last week' is not a date"},
+		{"contains on a number", "price ~ 3", "~ only works on text fields"},
+		{"ordering text", `district < "M"`, "< can't compare text"},
+		{"between on text", `district between "A" and "B"`, "between needs a number or date field"},
+		{"missing operator", "price 3", "expected an operator after price"},
+		{"not without between or in", "price not = 3", "expected 'between' or 'in' after 'not'"},
+		{"unclosed parenthesis", "(price > 3", "expected ')' but the filter ended"},
+		{"trailing token", "price > 3 views > 2", "unexpected 'views'"},
+		{"field missing", "= 3", "expected a field name but found '='"},
+		{"too deep", strings.Repeat("not ", maxFilterDepth+1) + "price > 1", "nested more than 16 levels deep"},
+		{"too long", "price > " + strings.Repeat("1", maxFilterLength), "longer than 1000 characters"},
+		{"too many in values", "id in (" + strings.TrimSuffix(strings.Repeat("1,", maxFilterInValues+1), ",") + ")", "at most 100 values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := compileFilterExpr(tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
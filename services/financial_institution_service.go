@@ -0,0 +1,144 @@
+// services/financial_institution_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+type FinancialInstitutionService struct {
+	DB *gorm.DB
+}
+
+func NewFinancialInstitutionService(db *gorm.DB) *FinancialInstitutionService {
+	return &FinancialInstitutionService{DB: db}
+}
+
+// applyInstitutionRequest copies the request onto the institution. Codes are unique, ignoring case.
+func (s *FinancialInstitutionService) applyInstitutionRequest(institution *models.FinancialInstitution, req *schema.FinancialInstitutionRequest) error {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code == "" {
+		return utils.NewBadRequestError("code must not be blank")
+	}
+
+	query := s.DB.Model(&models.FinancialInstitution{}).Where("code = ?", code)
+	if institution.ID != 0 {
+		query = query.Where("id <> ?", institution.ID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("database error checking financial institution code: %w", err)
+	}
+	if count > 0 {
+		return utils.NewAPIError(http.StatusConflict, "Financial institution already exists", fmt.Sprintf("The code %s is already in use.", code))
+	}
+
+	institution.Name = strings.TrimSpace(req.Name)
+	institution.Code = code
+	if req.Active != nil {
+		institution.Active = *req.Active
+	}
+	return nil
+}
+
+// CreateInstitution adds a financial institution. Give it the upstream ID to pick up the users
+// the sync already stored with it.
+func (s *FinancialInstitutionService) CreateInstitution(req *schema.FinancialInstitutionRequest) (*models.FinancialInstitution, error) {
+	institution := models.FinancialInstitution{Active: true}
+	if err := s.applyInstitutionRequest(&institution, req); err != nil {
+		return nil, err
+	}
+	if req.ID != nil {
+		if *req.ID == 0 || *req.ID >= LocalIDStart {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("id must be between 1 and %d", LocalIDStart-1))
+		}
+		var count int64
+		if err := s.DB.Model(&models.FinancialInstitution{}).Where("id = ?", *req.ID).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("database error checking financial institution ID: %w", err)
+		}
+		if count > 0 {
+			return nil, utils.NewAPIError(http.StatusConflict, "Financial institution already exists", fmt.Sprintf("The ID %d is already in use.", *req.ID))
+		}
+		institution.ID = *req.ID
+	}
+	if err := s.DB.Create(&institution).Error; err != nil {
+		return nil, fmt.Errorf("failed to create financial institution: %w", err)
+	}
+	return &institution, nil
+}
+
+// GetInstitutions retrieves financial institutions by name.
+func (s *FinancialInstitutionService) GetInstitutions(pag schema.PaginationRequest) ([]models.FinancialInstitution, int64, error) {
+	query := s.DB.Model(&models.FinancialInstitution{})
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count financial institutions: %w", err)
+	}
+
+	institutions := []models.FinancialInstitution{}
+	err := query.Order("name, id").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Find(&institutions).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve financial institutions: %w", err)
+	}
+	return institutions, totalItems, nil
+}
+
+// GetInstitutionByID retrieves a single financial institution.
+func (s *FinancialInstitutionService) GetInstitutionByID(id uint) (*models.FinancialInstitution, error) {
+	return findInstitution(s.DB, id)
+}
+
+// UpdateInstitution changes a financial institution's name, code or status.
+func (s *FinancialInstitutionService) UpdateInstitution(id uint, req *schema.FinancialInstitutionRequest) (*models.FinancialInstitution, error) {
+	institution, err := findInstitution(s.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInstitutionRequest(institution, req); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Save(institution).Error; err != nil {
+		return nil, fmt.Errorf("failed to update financial institution: %w", err)
+	}
+	return institution, nil
+}
+
+// SetUserInstitution moves a user to a financial institution, or to none. The sync leaves the
+// user's institution alone from then on.
+func (s *FinancialInstitutionService) SetUserInstitution(userID uint, institutionID *uint) error {
+	if institutionID != nil {
+		if _, err := findInstitution(s.DB, *institutionID); err != nil {
+			return err
+		}
+	}
+	result := s.DB.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"financial_institution_id": institutionID, "local_institution": true})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user's financial institution: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewNotFoundError("User")
+	}
+	return nil
+}
+
+func findInstitution(db *gorm.DB, id uint) (*models.FinancialInstitution, error) {
+	var institution models.FinancialInstitution
+	if err := db.First(&institution, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Financial institution")
+		}
+		return nil, fmt.Errorf("database error retrieving financial institution: %w", err)
+	}
+	return &institution, nil
+}
@@ -0,0 +1,254 @@
+// services/income_approach_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// Defaults used when the income_approach section is missing from config.yaml
+var (
+	defaultVacancyRate           = 0.10
+	defaultOperatingExpenseRatio = 0.25
+
+	defaultIncomeApproachConfig = config.IncomeApproachConfig{
+		RentListingType:              "Rent",
+		SaleListingType:              "Sale",
+		RentPeriodsPerYear:           12,
+		MinComparables:               3,
+		DefaultVacancyRate:           &defaultVacancyRate,
+		DefaultOperatingExpenseRatio: &defaultOperatingExpenseRatio,
+		DefaultCapRate:               0.12,
+	}
+)
+
+type IncomeApproachService struct {
+	DB *gorm.DB
+}
+
+func NewIncomeApproachService(db *gorm.DB) *IncomeApproachService {
+	return &IncomeApproachService{DB: db}
+}
+
+func (s *IncomeApproachService) WithContext(ctx context.Context) *IncomeApproachService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// districtPriceStats is the median asking price of a set of district listings.
+type districtPriceStats struct {
+	Median *float64
+	Count  int64
+}
+
+func incomeApproachSettings() config.IncomeApproachConfig {
+	settings := defaultIncomeApproachConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Valuation.IncomeApproach
+	if cfg.RentListingType != "" {
+		settings.RentListingType = cfg.RentListingType
+	}
+	if cfg.SaleListingType != "" {
+		settings.SaleListingType = cfg.SaleListingType
+	}
+	if cfg.RentPeriodsPerYear > 0 {
+		settings.RentPeriodsPerYear = cfg.RentPeriodsPerYear
+	}
+	if cfg.MinComparables > 0 {
+		settings.MinComparables = cfg.MinComparables
+	}
+	// Zero is a valid rate here, only a missing or out of range one falls back
+	if cfg.DefaultVacancyRate != nil && *cfg.DefaultVacancyRate >= 0 && *cfg.DefaultVacancyRate < 1 {
+		settings.DefaultVacancyRate = cfg.DefaultVacancyRate
+	}
+	if cfg.DefaultOperatingExpenseRatio != nil && *cfg.DefaultOperatingExpenseRatio >= 0 && *cfg.DefaultOperatingExpenseRatio < 1 {
+		settings.DefaultOperatingExpenseRatio = cfg.DefaultOperatingExpenseRatio
+	}
+	if cfg.DefaultCapRate > 0 {
+		settings.DefaultCapRate = cfg.DefaultCapRate
+	}
+	return settings
+}
+
+// CalculateAndSave capitalises the net operating income of a property and records
+// the result as an income Valuation with the workings stored alongside it.
+func (s *IncomeApproachService) CalculateAndSave(propertyID uint, req *schema.IncomeApproachRequest) (*models.IncomeValuation, error) {
+	settings := incomeApproachSettings()
+
+	var property models.Property
+	err := s.DB.Preload("Location").First(&property, propertyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Property")
+		}
+		return nil, fmt.Errorf("database error retrieving property: %w", err)
+	}
+
+	// District rentals are needed for the rent default and the implied cap rate, sales for the latter
+	var rentals, sales districtPriceStats
+	if req.GrossAnnualRent == nil || req.CapRate == nil {
+		rentals, err = s.districtMedianPrice(&property, settings.RentListingType, settings.MinComparables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.CapRate == nil {
+		sales, err = s.districtMedianPrice(&property, settings.SaleListingType, settings.MinComparables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := calculateIncomeApproach(&property, req, settings, rentals, sales)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		record := models.Valuation{
+			PropertyID:        property.ID,
+			ValuerID:          req.ValuerID,
+			ValuationDate:     time.Now(),
+			Method:            models.ValuationMethodIncome,
+			MarketValue:       &result.CapitalisedValue,
+			Notes:             req.Notes,
+			ParentValuationID: req.ParentValuationID,
+		}
+		if err := createValuation(tx, &record); err != nil {
+			return err
+		}
+		result.ValuationID = &record.ID
+		if err := tx.Create(result).Error; err != nil {
+			return fmt.Errorf("failed to save income valuation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// calculateIncomeApproach does the actual arithmetic, kept free of DB access. rentals and sales
+// are the district medians, left empty when the request makes them unnecessary.
+func calculateIncomeApproach(property *models.Property, req *schema.IncomeApproachRequest, settings config.IncomeApproachConfig, rentals, sales districtPriceStats) (*models.IncomeValuation, error) {
+	result := &models.IncomeValuation{
+		PropertyID:        property.ID,
+		ValuerID:          req.ValuerID,
+		Notes:             req.Notes,
+		ComparableRentals: rentals.Count,
+		ComparableSales:   sales.Count,
+	}
+	periods := float64(settings.RentPeriodsPerYear)
+
+	// 1. Gross annual rent
+	isRental := strings.EqualFold(property.ListingType, settings.RentListingType)
+	switch {
+	case req.GrossAnnualRent != nil:
+		result.GrossAnnualRent = *req.GrossAnnualRent
+		result.GrossRentSource = "request"
+	case isRental && property.Price != nil && *property.Price > 0:
+		result.GrossAnnualRent = *property.Price * periods
+		result.GrossRentSource = "subject asking rent"
+	case rentals.Median != nil && rentals.Count >= int64(settings.MinComparables):
+		result.GrossAnnualRent = *rentals.Median * periods
+		result.GrossRentSource = fmt.Sprintf("median of %d comparable rentals in %s", rentals.Count, property.Location.District)
+	default:
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Not enough comparable rentals in '%s' to estimate rent, pass gross_annual_rent", property.Location.District))
+	}
+
+	// 2. Vacancy and effective gross income. Listings carry no vacancy or expense figures, so
+	// these come from the request or the configured defaults, never from comparables.
+	result.VacancyRate = *settings.DefaultVacancyRate
+	if req.VacancyRate != nil {
+		result.VacancyRate = *req.VacancyRate
+	}
+	result.VacancyLoss = roundTo(result.GrossAnnualRent*result.VacancyRate, 2)
+	result.EffectiveGrossIncome = roundTo(result.GrossAnnualRent-result.VacancyLoss, 2)
+
+	// 3. Operating expenses
+	expenseRatio := *settings.DefaultOperatingExpenseRatio
+	switch {
+	case req.OperatingExpenses != nil:
+		result.OperatingExpenses = *req.OperatingExpenses
+		result.OperatingExpenseSource = "request"
+	case req.OperatingExpenseRatio != nil:
+		expenseRatio = *req.OperatingExpenseRatio
+		result.OperatingExpenses = roundTo(result.EffectiveGrossIncome*expenseRatio, 2)
+		result.OperatingExpenseSource = fmt.Sprintf("%.1f%% of effective gross income (request)", expenseRatio*100)
+	default:
+		result.OperatingExpenses = roundTo(result.EffectiveGrossIncome*expenseRatio, 2)
+		result.OperatingExpenseSource = fmt.Sprintf("%.1f%% of effective gross income (default)", expenseRatio*100)
+	}
+	result.NetOperatingIncome = roundTo(result.EffectiveGrossIncome-result.OperatingExpenses, 2)
+	if result.NetOperatingIncome <= 0 {
+		return nil, utils.NewBadRequestError("Operating expenses exceed effective gross income, net operating income must be positive")
+	}
+
+	// 4. Capitalisation rate
+	switch {
+	case req.CapRate != nil:
+		result.CapRate = *req.CapRate
+		result.CapRateSource = "request"
+	default:
+		result.CapRate = settings.DefaultCapRate
+		result.CapRateSource = "default"
+		if rentals.Median != nil && sales.Median != nil && *sales.Median > 0 &&
+			rentals.Count >= int64(settings.MinComparables) && sales.Count >= int64(settings.MinComparables) {
+			// Net yield implied by typical rents and sale prices in the district
+			implied := (*rentals.Median * periods) * (1 - result.VacancyRate) * (1 - expenseRatio) / *sales.Median
+			if implied > 0 && implied < 1 {
+				result.CapRate = roundTo(implied, 4)
+				result.CapRateSource = fmt.Sprintf("implied by %d rentals and %d sales in %s", rentals.Count, sales.Count, property.Location.District)
+			}
+		}
+	}
+
+	result.CapitalisedValue = roundTo(result.NetOperatingIncome/result.CapRate, 2)
+	return result, nil
+}
+
+// districtMedianPrice returns the median asking price of other listings of the given type in the
+// subject's district. It narrows to the subject's property type when there are enough of those.
+func (s *IncomeApproachService) districtMedianPrice(subject *models.Property, listingType string, minCount int) (districtPriceStats, error) {
+	var stats districtPriceStats
+	if subject.Location.District == "" {
+		return stats, nil
+	}
+
+	base := func() *gorm.DB {
+		return s.DB.Model(&models.Property{}).
+			Select("percentile_cont(0.5) WITHIN GROUP (ORDER BY properties.price) AS median, COUNT(*) AS count").
+			Where("properties.id <> ?", subject.ID).
+			Where("properties.price > 0").
+			Where("properties.listing_type ILIKE ?", listingType).
+			Where("properties.id IN (SELECT property_id FROM locations WHERE district ILIKE ?)", escapeLike(subject.Location.District))
+	}
+
+	if subject.PropertyType != nil && *subject.PropertyType != "" {
+		if err := base().Where("properties.property_type = ?", *subject.PropertyType).Scan(&stats).Error; err != nil {
+			return stats, fmt.Errorf("failed to compute district %s median: %w", listingType, err)
+		}
+		if stats.Count >= int64(minCount) {
+			return stats, nil
+		}
+	}
+
+	stats = districtPriceStats{}
+	if err := base().Scan(&stats).Error; err != nil {
+		return stats, fmt.Errorf("failed to compute district %s median: %w", listingType, err)
+	}
+	return stats, nil
+}
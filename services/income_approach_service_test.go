@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomeApproachSettings_KeepsZeroRates(t *testing.T) {
+	previous := config.Cfg
+	defer func() { config.Cfg = previous }()
+
+	zero, tooHigh := 0.0, 1.5
+	config.Cfg = &config.Config{Valuation: config.ValuationConfig{IncomeApproach: config.IncomeApproachConfig{
+		DefaultVacancyRate:           &zero,
+		DefaultOperatingExpenseRatio: &tooHigh,
+	}}}
+	settings := incomeApproachSettings()
+	assert.Equal(t, 0.0, *settings.DefaultVacancyRate)
+	assert.Equal(t, defaultOperatingExpenseRatio, *settings.DefaultOperatingExpenseRatio, "out of range falls back")
+
+	// Left out, the defaults apply
+	config.Cfg = &config.Config{}
+	settings = incomeApproachSettings()
+	assert.Equal(t, defaultVacancyRate, *settings.DefaultVacancyRate)
+	assert.Equal(t, defaultOperatingExpenseRatio, *settings.DefaultOperatingExpenseRatio)
+}
+
+func TestCalculateIncomeApproach(t *testing.T) {
+	settings := defaultIncomeApproachConfig
+	zero := 0.0
+	noCosts := settings
+	noCosts.DefaultVacancyRate = &zero
+	noCosts.DefaultOperatingExpenseRatio = &zero
+
+	rental := &models.Property{ListingType: "Rent", Price: floatPtr(100000), Location: models.Location{District: "Lilongwe"}}
+	house := &models.Property{ListingType: "Sale", Price: floatPtr(50000000), Location: models.Location{District: "Lilongwe"}}
+	rentals := districtPriceStats{Median: floatPtr(200000), Count: 5}
+	sales := districtPriceStats{Median: floatPtr(24000000), Count: 4}
+
+	tests := []struct {
+		name            string
+		property        *models.Property
+		req             schema.IncomeApproachRequest
+		settings        config.IncomeApproachConfig
+		rentals, sales  districtPriceStats
+		wantRent        float64
+		wantNOI         float64
+		wantCapRate     float64
+		wantValue       float64
+		wantCapRateFrom string
+		wantErr         string
+	}{
+		{
+			// 1.2M rent, 10% vacancy leaves 1.08M, 25% expenses leave 810k, at 12%
+			name:     "subject rent with the defaults",
+			property: rental, settings: settings,
+			wantRent: 1200000, wantNOI: 810000, wantCapRate: 0.12, wantValue: 6750000, wantCapRateFrom: "default",
+		},
+		{
+			name:     "configured zero vacancy and expenses are used",
+			property: rental, settings: noCosts,
+			wantRent: 1200000, wantNOI: 1200000, wantCapRate: 0.12, wantValue: 10000000, wantCapRateFrom: "default",
+		},
+		{
+			// 2.4M rent from the district median, implied yield 2.4M x 0.9 x 0.75 / 24M
+			name:     "district rent and implied cap rate",
+			property: house, settings: settings, rentals: rentals, sales: sales,
+			wantRent: 2400000, wantNOI: 1620000, wantCapRate: 0.0675, wantValue: 24000000, wantCapRateFrom: "implied by 5 rentals and 4 sales in Lilongwe",
+		},
+		{
+			name:     "too few sales keep the default cap rate",
+			property: house, settings: settings, rentals: rentals, sales: districtPriceStats{Median: floatPtr(24000000), Count: 2},
+			wantRent: 2400000, wantNOI: 1620000, wantCapRate: 0.12, wantValue: 13500000, wantCapRateFrom: "default",
+		},
+		{
+			name:     "request overrides everything",
+			property: house, settings: settings, rentals: rentals, sales: sales,
+			req: schema.IncomeApproachRequest{
+				GrossAnnualRent:   floatPtr(1000000),
+				VacancyRate:       floatPtr(0),
+				OperatingExpenses: floatPtr(200000),
+				CapRate:           floatPtr(0.1),
+			},
+			wantRent: 1000000, wantNOI: 800000, wantCapRate: 0.1, wantValue: 8000000, wantCapRateFrom: "request",
+		},
+		{
+			name:     "no rent to go on",
+			property: house, settings: settings, rentals: districtPriceStats{Median: floatPtr(200000), Count: 1},
+			wantErr: "Not enough comparable rentals",
+		},
+		{
+			name:     "expenses above income",
+			property: rental, settings: settings,
+			req:     schema.IncomeApproachRequest{OperatingExpenses: floatPtr(2000000)},
+			wantErr: "net operating income must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculateIncomeApproach(tt.property, &tt.req, tt.settings, tt.rentals, tt.sales)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRent, result.GrossAnnualRent)
+			assert.Equal(t, tt.wantNOI, result.NetOperatingIncome)
+			assert.Equal(t, tt.wantCapRate, result.CapRate)
+			assert.Equal(t, tt.wantValue, result.CapitalisedValue)
+			assert.Equal(t, tt.wantCapRateFrom, result.CapRateSource)
+		})
+	}
+}
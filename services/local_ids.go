@@ -0,0 +1,30 @@
+// services/local_ids.go
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// LocalIDStart is where IDs of rows created here begin. The sync writes upstream rows with
+// their upstream IDs, which stay well below it, so a local row and an upstream one never share
+// an ID and a sync never overwrites something created here.
+const LocalIDStart = 1_000_000_000
+
+// localIDTables are the tables holding upstream IDs whose rows are also created here
+var localIDTables = []string{"users", "agents", "locations", "cover_photos", "financial_institutions"}
+
+// ReserveLocalIDs moves the ID sequences of tables shared with the sync past LocalIDStart and
+// past every stored ID. Explicit IDs don't advance a sequence, without this the next local
+// insert could take an ID the sync already used. Run after migrating and after each sync.
+func ReserveLocalIDs(db *gorm.DB) error {
+	for _, table := range localIDTables {
+		err := db.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'),
+			GREATEST(?, (SELECT COALESCE(MAX(id), 0) FROM %[1]s)))`, table), LocalIDStart).Error
+		if err != nil {
+			return fmt.Errorf("failed to reserve local IDs for %s: %w", table, err)
+		}
+	}
+	return nil
+}
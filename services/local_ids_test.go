@@ -0,0 +1,70 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectReserveLocalIDs(mock sqlmock.Sqlmock) {
+	for _, table := range localIDTables {
+		mock.ExpectExec(`SELECT setval\(pg_get_serial_sequence\('` + table + `', 'id'\),\s+GREATEST\(\$1, \(SELECT COALESCE\(MAX\(id\), 0\) FROM ` + table + `\)\)\)`).
+			WithArgs(LocalIDStart).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestReserveLocalIDs(t *testing.T) {
+	db, mock := setupMockDB(t)
+	expectReserveLocalIDs(mock)
+
+	require.NoError(t, ReserveLocalIDs(db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAgentAfterSync(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [], "meta": {"total": 0, "current_page": 1, "next_page_url": null}}`))
+	}))
+	defer upstream.Close()
+
+	previous := config.Cfg
+	config.Cfg = &config.Config{ExternalAPI: config.ExternalAPIConfig{PropertiesURL: upstream.URL}}
+	defer func() { config.Cfg = previous }()
+
+	db, mock := setupMockDB(t)
+
+	// The sync moves the sequences past the upstream IDs it wrote
+	expectReserveLocalIDs(mock)
+	result, err := NewSyncService(db).FetchAndSyncProperties()
+	require.NoError(t, err)
+	assert.Equal(t, "completed", result.Status)
+
+	// So the agent created afterwards takes a local ID
+	mock.ExpectQuery(`SELECT "id" FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(42, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "agents" WHERE user_id = \$1`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`INSERT INTO "agents" .* RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(LocalIDStart))
+	mock.ExpectQuery(`SELECT \* FROM "agents" WHERE "agents"."id" = \$1`).
+		WithArgs(LocalIDStart, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "phone_1", "agent_type"}).AddRow(LocalIDStart, 42, "0999000111", "independent"))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(42, "Chikondi"))
+
+	agent, err := NewAgentService(db).CreateAgent(&schema.AgentRequest{UserID: 42, Phone1: "0999000111", AgentType: "independent"})
+	require.NoError(t, err)
+	assert.EqualValues(t, LocalIDStart, agent.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,279 @@
+// services/notifier.go
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+)
+
+// Notifier delivers saved search alerts over one channel. SavedSearchService.Notifiers holds
+// one per channel, tests and local setups can swap in their own.
+type Notifier interface {
+	Send(target string, msg schema.AlertMessage) error
+}
+
+// Defaults used when the notifications section is missing from config.yaml
+var defaultNotificationsConfig = config.NotificationsConfig{
+	MaxAttempts:       5,
+	RetryDelaySeconds: 60,
+	SMTP:              config.SMTPConfig{Port: 25},
+	Webhook:           config.WebhookConfig{TimeoutSeconds: 10},
+}
+
+// Longest wait between two attempts at a notification
+const maxRetryDelay = time.Hour
+
+func notificationsSettings() config.NotificationsConfig {
+	settings := defaultNotificationsConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Notifications
+	if cfg.MaxAttempts > 0 {
+		settings.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.RetryDelaySeconds > 0 {
+		settings.RetryDelaySeconds = cfg.RetryDelaySeconds
+	}
+	if cfg.SMTP.Host != "" {
+		port := settings.SMTP.Port
+		if cfg.SMTP.Port > 0 {
+			port = cfg.SMTP.Port
+		}
+		settings.SMTP = cfg.SMTP
+		settings.SMTP.Port = port
+	}
+	if cfg.Webhook.TimeoutSeconds > 0 {
+		settings.Webhook.TimeoutSeconds = cfg.Webhook.TimeoutSeconds
+	}
+	settings.Webhook.Secret = cfg.Webhook.Secret
+	settings.Webhook.AllowPrivateNetworks = cfg.Webhook.AllowPrivateNetworks
+	return settings
+}
+
+// retryDelay is how long to wait after a notification's attempts-th failed attempt. The delay
+// doubles with each attempt so a channel that is down isn't hammered.
+func retryDelay(settings config.NotificationsConfig, attempts int) time.Duration {
+	delay := time.Duration(settings.RetryDelaySeconds) * time.Second
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// DefaultNotifiers builds the notifiers from config. Email is left out when no SMTP host is set.
+func DefaultNotifiers() map[string]Notifier {
+	settings := notificationsSettings()
+	notifiers := map[string]Notifier{
+		models.AlertChannelWebhook: NewWebhookNotifier(settings.Webhook),
+	}
+	if settings.SMTP.Host != "" {
+		notifiers[models.AlertChannelEmail] = NewSMTPNotifier(settings.SMTP)
+	}
+	return notifiers
+}
+
+// --- Email ---
+
+// SMTPNotifier sends alerts as plain text email.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	Auth smtp.Auth // nil for servers without auth
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig) *SMTPNotifier {
+	n := &SMTPNotifier{
+		Addr: cfg.Host + ":" + strconv.Itoa(cfg.Port),
+		From: cfg.From,
+	}
+	if cfg.Username != "" {
+		n.Auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return n
+}
+
+func (n *SMTPNotifier) Send(target string, msg schema.AlertMessage) error {
+	if err := smtp.SendMail(n.Addr, n.Auth, n.From, []string{target}, alertEmail(n.From, target, msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", target, err)
+	}
+	return nil
+}
+
+// headerSafe stops a saved search name from adding headers to the email.
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// alertEmail renders the message with the property's main details.
+func alertEmail(from, to string, msg schema.AlertMessage) []byte {
+	p := msg.Property
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerSafe(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerSafe(to))
+	fmt.Fprintf(&b, "Subject: New match for %s\r\n", headerSafe(msg.SavedSearchName))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	fmt.Fprintf(&b, "A property matching your saved search \"%s\" was listed.\r\n\r\n", msg.SavedSearchName)
+	fmt.Fprintf(&b, "Property: %d\r\n", p.ID)
+	if p.PropertyType != nil || p.PropertyDesign != "" {
+		fmt.Fprintf(&b, "Type: %s\r\n", strings.TrimSpace(derefString(p.PropertyType)+" "+p.PropertyDesign))
+	}
+	if p.Location != nil {
+		fmt.Fprintf(&b, "Location: %s\r\n", strings.Trim(p.Location.Area+", "+p.Location.District, ", "))
+	}
+	if p.NoRooms != nil {
+		fmt.Fprintf(&b, "Rooms: %d\r\n", *p.NoRooms)
+	}
+	if p.Price != nil {
+		fmt.Fprintf(&b, "Price: MWK %.0f\r\n", *p.Price)
+	}
+	if p.ListingType != "" {
+		fmt.Fprintf(&b, "Listing: %s\r\n", p.ListingType)
+	}
+	return []byte(b.String())
+}
+
+// --- Webhook ---
+
+// Ranges the net.IP helpers don't cover that a webhook must not reach either
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "This" network
+	"100.64.0.0/10", // Carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // Benchmarking
+	"64:ff9b::/96",  // NAT64, maps onto IPv4 addresses
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// isPublicIP reports whether ip is a routable internet address, not loopback, private,
+// link-local (which holds cloud metadata services) or otherwise reserved.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+var errPrivateWebhookTarget = errors.New("webhook target is not a public internet address")
+
+// publicOnlyControl runs after DNS resolution, just before connecting, so it checks the
+// address actually dialled. A name that resolves to a public address when the search is saved
+// and a private one later is still refused.
+func publicOnlyControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return errPrivateWebhookTarget
+	}
+	return nil
+}
+
+// checkWebhookHost resolves the host of a webhook URL and refuses it when any address is not
+// public. The dialer checks again on every delivery, this only reports mistakes early.
+func checkWebhookHost(host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", host)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return errPrivateWebhookTarget
+		}
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs the alert as JSON. With a secret set, the body's HMAC-SHA256 is sent
+// as "X-Signature: sha256=<hex>" so receivers can check it came from us.
+type WebhookNotifier struct {
+	Client *http.Client
+	Secret string
+}
+
+func NewWebhookNotifier(cfg config.WebhookConfig) *WebhookNotifier {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = publicOnlyControl
+	}
+	return &WebhookNotifier{
+		Client: &http.Client{
+			Timeout: timeout,
+			// No proxy from the environment, the dialer has to see the real target to check it
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		},
+		Secret: cfg.Secret,
+	}
+}
+
+func (n *WebhookNotifier) Send(target string, msg schema.AlertMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", msg.Event)
+	if n.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		if errors.Is(err, errPrivateWebhookTarget) {
+			return errPrivateWebhookTarget
+		}
+		return errors.New("webhook request failed") // The cause can describe hosts behind the target
+	}
+	defer resp.Body.Close()
+	// The body is not kept, it would hand whatever the target returned back to the user
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"41.70.1.1", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"::ffff:127.0.0.1", false}, // IPv4-mapped loopback
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.public, isPublicIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
+
+func TestCheckWebhookHost(t *testing.T) {
+	assert.ErrorIs(t, checkWebhookHost("127.0.0.1"), errPrivateWebhookTarget)
+	assert.ErrorIs(t, checkWebhookHost("169.254.169.254"), errPrivateWebhookTarget)
+	assert.ErrorIs(t, checkWebhookHost("localhost"), errPrivateWebhookTarget)
+	assert.NoError(t, checkWebhookHost("8.8.8.8"))
+}
+
+func TestWebhookNotifier_RefusesPrivateTargets(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer server.Close()
+
+	n := NewWebhookNotifier(config.WebhookConfig{TimeoutSeconds: 2})
+	err := n.Send(server.URL, schema.AlertMessage{Event: alertEventMatch})
+	assert.ErrorIs(t, err, errPrivateWebhookTarget)
+	assert.False(t, hit, "the dialer must refuse before connecting")
+}
+
+func TestWebhookNotifier_KeepsOnlyTheStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"AccessKeyId": "secret"}`))
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(config.WebhookConfig{TimeoutSeconds: 2, AllowPrivateNetworks: true})
+	err := n.Send(server.URL, schema.AlertMessage{Event: alertEventMatch})
+	require.Error(t, err)
+	assert.Equal(t, "webhook returned status 403", err.Error())
+}
+
+func TestHeaderSafe(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Area 43 houses", "Area 43 houses"},
+		{"Cheap\r\nBcc: everyone@example.com", "Cheap  Bcc: everyone@example.com"},
+		{"line\nbreak", "line break"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, headerSafe(tt.in))
+	}
+}
+
+func TestAlertEmail(t *testing.T) {
+	house, rooms, price := "House", 3, 45000000.0
+
+	tests := []struct {
+		name     string
+		msg      schema.AlertMessage
+		want     []string
+		wantNone []string
+	}{
+		{
+			name: "every detail",
+			msg: schema.AlertMessage{SavedSearchName: "3 bed in Area 43", Property: schema.PropertyResponse{
+				ID: 12, PropertyType: &house, PropertyDesign: "Bungalow", NoRooms: &rooms, Price: &price, ListingType: "Sale",
+				Location: &models.Location{Area: "Area 43", District: "Lilongwe"},
+			}},
+			want: []string{
+				"Subject: New match for 3 bed in Area 43\r\n",
+				"Property: 12\r\n",
+				"Type: House Bungalow\r\n",
+				"Location: Area 43, Lilongwe\r\n",
+				"Rooms: 3\r\n",
+				"Price: MWK 45000000\r\n",
+				"Listing: Sale\r\n",
+			},
+		},
+		{
+			name:     "missing details are left out",
+			msg:      schema.AlertMessage{SavedSearchName: "Plots", Property: schema.PropertyResponse{ID: 7, Location: &models.Location{District: "Zomba"}}},
+			want:     []string{"Property: 7\r\n", "Location: Zomba\r\n"},
+			wantNone: []string{"\r\nType:", "Rooms:", "Price:", "Listing:"},
+		},
+		{
+			name: "name can't add headers",
+			msg:  schema.AlertMessage{SavedSearchName: "x\r\nBcc: everyone@example.com"},
+			want: []string{"Subject: New match for x  Bcc: everyone@example.com\r\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := string(alertEmail("alerts@example.com", "client@example.com", tt.msg))
+			headers, _, _ := strings.Cut(email, "\r\n\r\n")
+			assert.NotContains(t, headers, "\r\nBcc:")
+			assert.Contains(t, email, "From: alerts@example.com\r\nTo: client@example.com\r\n")
+			assert.Contains(t, email, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+			for _, want := range tt.want {
+				assert.Contains(t, email, want)
+			}
+			for _, unwanted := range tt.wantNone {
+				assert.NotContains(t, email, unwanted)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_SignsPayload(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(config.WebhookConfig{TimeoutSeconds: 2, Secret: "s3cret", AllowPrivateNetworks: true})
+	require.NoError(t, n.Send(server.URL, schema.AlertMessage{Event: alertEventMatch, NotificationID: 4, SavedSearchName: "Plots"}))
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), header.Get("X-Signature"))
+	assert.Equal(t, alertEventMatch, header.Get("X-Event"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Contains(t, string(body), `"notificationId":4`)
+}
+
+func TestRetryDelay(t *testing.T) {
+	settings := config.NotificationsConfig{RetryDelaySeconds: 60}
+	assert.Equal(t, time.Minute, retryDelay(settings, 1))
+	assert.Equal(t, 2*time.Minute, retryDelay(settings, 2))
+	assert.Equal(t, 8*time.Minute, retryDelay(settings, 4))
+	assert.Equal(t, maxRetryDelay, retryDelay(settings, 10))
+	assert.Equal(t, maxRetryDelay, retryDelay(settings, 1000), "no overflow with many attempts")
+}
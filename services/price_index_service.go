@@ -0,0 +1,378 @@
+// services/price_index_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// Defaults used when the stats.price_index section is missing from config.yaml
+var defaultPriceIndexConfig = config.PriceIndexConfig{
+	ListingType:           "Sale",
+	MinListingsPerPeriod:  3,
+	MinListingsForHedonic: 30,
+}
+
+type PriceIndexService struct {
+	DB *gorm.DB
+	mu sync.Mutex // Refreshes rebuild the whole table, only run one at a time
+}
+
+func NewPriceIndexService(db *gorm.DB) *PriceIndexService {
+	return &PriceIndexService{DB: db}
+}
+
+func priceIndexSettings() config.PriceIndexConfig {
+	settings := defaultPriceIndexConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	cfg := config.Cfg.Stats.PriceIndex
+	if cfg.ListingType != "" {
+		settings.ListingType = cfg.ListingType
+	}
+	if cfg.MinListingsPerPeriod > 0 {
+		settings.MinListingsPerPeriod = cfg.MinListingsPerPeriod
+	}
+	if cfg.MinListingsForHedonic > 0 {
+		settings.MinListingsForHedonic = cfg.MinListingsForHedonic
+	}
+	return settings
+}
+
+// priceIndexListing is the slice of a property the index needs.
+type priceIndexListing struct {
+	District            string
+	CreatedAt           time.Time
+	Price               float64
+	BuildingPricePerSqm *float64
+	BuildingSizeSqm     *float64
+	LandSizeSqm         *float64
+	NoRooms             *int
+	NoOfBathrooms       *int
+	PropertyType        *string
+}
+
+// periodStart truncates a listing date to the start of its month or quarter in UTC.
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	month := t.Month()
+	if interval == models.PriceIndexQuarterly {
+		month = time.Month(((int(month)-1)/3)*3 + 1)
+	}
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Refresh rebuilds the price index tables from the current listings. Listings are dated
+// by when they were first recorded, so each period reflects asking prices at the time.
+func (s *PriceIndexService) Refresh() (*schema.PriceIndexRefreshResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := priceIndexSettings()
+	now := time.Now()
+
+	// The index is published to every tenant, so it is built from shared listings only
+	var listings []priceIndexListing
+	err := sharedOnly(s.DB).Model(&models.Property{}).
+		Select("UPPER(TRIM(locations.district)) AS district, properties.created_at, properties.price, properties.building_price_per_sqm, "+
+			"properties.building_size_sqm, properties.land_size_sqm, properties.no_rooms, properties.no_of_bathrooms, properties.property_type").
+		Joins("JOIN locations ON locations.property_id = properties.id").
+		Where("properties.price > 0").
+		Where("properties.listing_type ILIKE ?", settings.ListingType).
+		Where("TRIM(locations.district) <> ''").
+		Scan(&listings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load listings for price index: %w", err)
+	}
+
+	byDistrict := map[string][]priceIndexListing{}
+	for _, l := range listings {
+		byDistrict[l.District] = append(byDistrict[l.District], l)
+	}
+	districts := make([]string, 0, len(byDistrict))
+	for d := range byDistrict {
+		districts = append(districts, d)
+	}
+	sort.Strings(districts)
+
+	result := &schema.PriceIndexRefreshResult{
+		Listings:    len(listings),
+		Districts:   len(districts),
+		RefreshedAt: now,
+	}
+	var points []models.PriceIndexPoint
+	for _, district := range districts {
+		for _, interval := range []string{models.PriceIndexMonthly, models.PriceIndexQuarterly} {
+			series, hedonic := buildPriceIndexSeries(district, interval, byDistrict[district], settings, now)
+			points = append(points, series...)
+			if hedonic {
+				result.HedonicSeries++
+			}
+		}
+	}
+	result.Points = len(points)
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.PriceIndexPoint{}).Error; err != nil {
+			return fmt.Errorf("failed to clear price index: %w", err)
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(points, 500).Error; err != nil {
+			return fmt.Errorf("failed to save price index: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Price index refreshed: %d listings, %d districts, %d points.\n", result.Listings, result.Districts, result.Points)
+	return result, nil
+}
+
+// buildPriceIndexSeries computes one district's index at one interval. The bool reports
+// whether the hedonic regression could be fitted.
+func buildPriceIndexSeries(district, interval string, listings []priceIndexListing, settings config.PriceIndexConfig, now time.Time) ([]models.PriceIndexPoint, bool) {
+	byPeriod := map[time.Time][]priceIndexListing{}
+	for _, l := range listings {
+		p := periodStart(l.CreatedAt, interval)
+		byPeriod[p] = append(byPeriod[p], l)
+	}
+	periods := make([]time.Time, 0, len(byPeriod))
+	for p := range byPeriod {
+		periods = append(periods, p)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Before(periods[j]) })
+
+	var hedonic map[time.Time]float64
+	if len(listings) >= settings.MinListingsForHedonic && len(periods) >= 2 {
+		hedonic = fitHedonicIndex(listings, periods, interval)
+	}
+
+	points := make([]models.PriceIndexPoint, 0, len(periods))
+	var baseMedian float64
+	for _, p := range periods {
+		rows := byPeriod[p]
+		prices := make([]float64, 0, len(rows))
+		var perSqm []float64
+		for _, r := range rows {
+			prices = append(prices, r.Price)
+			if r.BuildingPricePerSqm != nil && *r.BuildingPricePerSqm > 0 {
+				perSqm = append(perSqm, *r.BuildingPricePerSqm)
+			}
+		}
+
+		point := models.PriceIndexPoint{
+			District:          district,
+			Interval:          interval,
+			PeriodStart:       p,
+			ListingCount:      int64(len(rows)),
+			MedianPrice:       roundPtr(medianOf(prices), 2),
+			MedianPricePerSqm: roundPtr(medianOf(perSqm), 2),
+			RefreshedAt:       now,
+		}
+
+		// The base is the first period with enough listings, thin periods get no median index
+		if len(rows) >= settings.MinListingsPerPeriod && point.MedianPrice != nil {
+			if baseMedian == 0 {
+				baseMedian = *point.MedianPrice
+			}
+			idx := roundTo(*point.MedianPrice/baseMedian*100, 2)
+			point.MedianIndex = &idx
+		}
+		if v, ok := hedonic[p]; ok {
+			idx := roundTo(v, 2)
+			point.HedonicIndex = &idx
+		}
+		points = append(points, point)
+	}
+	return points, hedonic != nil
+}
+
+// fitHedonicIndex regresses log price on period dummies plus property characteristics,
+// so the period coefficients show price movement with the mix of stock held constant.
+// The first period is the base (100). Missing characteristics get a zero value and an
+// indicator column rather than dropping the listing. Returns nil if the fit fails.
+func fitHedonicIndex(listings []priceIndexListing, periods []time.Time, interval string) map[time.Time]float64 {
+	periodCol := make(map[time.Time]int, len(periods))
+	for i, p := range periods[1:] {
+		periodCol[p] = 1 + i
+	}
+
+	typeSet := map[string]bool{}
+	for _, l := range listings {
+		typeSet[strings.ToLower(strings.TrimSpace(derefString(l.PropertyType)))] = true
+	}
+	types := make([]string, 0, len(typeSet))
+	for t := range typeSet {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	typeCol := map[string]int{}
+	next := len(periods) + 8 // intercept, period dummies, then 8 characteristic columns
+	for _, t := range types[1:] {
+		typeCol[t] = next
+		next++
+	}
+	width := next
+
+	X := make([][]float64, len(listings))
+	y := make([]float64, len(listings))
+	for i, l := range listings {
+		row := make([]float64, width)
+		row[0] = 1
+		if c, ok := periodCol[periodStart(l.CreatedAt, interval)]; ok {
+			row[c] = 1
+		}
+		base := len(periods)
+		row[base], row[base+1] = logOrMissing(l.BuildingSizeSqm)
+		row[base+2], row[base+3] = logOrMissing(l.LandSizeSqm)
+		row[base+4], row[base+5] = countOrMissing(l.NoRooms)
+		row[base+6], row[base+7] = countOrMissing(l.NoOfBathrooms)
+		if c, ok := typeCol[strings.ToLower(strings.TrimSpace(derefString(l.PropertyType)))]; ok {
+			row[c] = 1
+		}
+		X[i] = row
+		y[i] = math.Log(l.Price)
+	}
+
+	// Characteristics nobody in the district has (or everyone lacks) would make X'X singular
+	kept := nonConstantColumns(X, len(periods))
+	reduced := make([][]float64, len(X))
+	for i, row := range X {
+		r := make([]float64, len(kept))
+		for j, c := range kept {
+			r[j] = row[c]
+		}
+		reduced[i] = r
+	}
+
+	fit, err := utils.FitOLS(reduced, y)
+	if err != nil {
+		return nil
+	}
+
+	index := map[time.Time]float64{periods[0]: 100}
+	for j, c := range kept {
+		if c >= 1 && c < len(periods) {
+			index[periods[c]] = 100 * math.Exp(fit.Coefficients[j])
+		}
+	}
+	return index
+}
+
+// nonConstantColumns returns the column indexes worth keeping in a regression: the
+// intercept, the columns below keepBelow, and any other column that varies.
+func nonConstantColumns(X [][]float64, keepBelow int) []int {
+	if len(X) == 0 {
+		return nil
+	}
+	kept := []int{}
+	for c := range X[0] {
+		if c < keepBelow {
+			kept = append(kept, c)
+			continue
+		}
+		first := X[0][c]
+		for _, row := range X[1:] {
+			if row[c] != first {
+				kept = append(kept, c)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// logOrMissing returns ln(v) and 0, or 0 and 1 when the value is missing.
+func logOrMissing(v *float64) (float64, float64) {
+	if v == nil || *v <= 0 {
+		return 0, 1
+	}
+	return math.Log(*v), 0
+}
+
+// countOrMissing returns the count and 0, or 0 and 1 when the count is missing.
+func countOrMissing(v *int) (float64, float64) {
+	if v == nil || *v < 0 {
+		return 0, 1
+	}
+	return float64(*v), 0
+}
+
+func medianOf(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	m := sorted[mid]
+	if len(sorted)%2 == 0 {
+		m = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return &m
+}
+
+// GetPriceIndex returns the stored series for one interval, optionally narrowed to a
+// district and a date range.
+func (s *PriceIndexService) GetPriceIndex(district, interval string, from, to *time.Time) (*schema.PriceIndexResponse, error) {
+	if interval == "" {
+		interval = models.PriceIndexMonthly
+	}
+	if interval != models.PriceIndexMonthly && interval != models.PriceIndexQuarterly {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Unknown interval '%s', use month or quarter", interval))
+	}
+
+	query := s.DB.Model(&models.PriceIndexPoint{}).Where("index_interval = ?", interval)
+	if strings.TrimSpace(district) != "" {
+		query = query.Where("district = ?", strings.ToUpper(strings.TrimSpace(district)))
+	}
+	if from != nil {
+		query = query.Where("period_start >= ?", periodStart(*from, interval))
+	}
+	if to != nil {
+		query = query.Where("period_start <= ?", *to)
+	}
+
+	var points []models.PriceIndexPoint
+	if err := query.Order("district ASC, period_start ASC").Find(&points).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve price index: %w", err)
+	}
+
+	response := &schema.PriceIndexResponse{Interval: interval, Series: []schema.PriceIndexSeries{}}
+	for _, p := range points {
+		if response.RefreshedAt == nil || p.RefreshedAt.After(*response.RefreshedAt) {
+			refreshed := p.RefreshedAt
+			response.RefreshedAt = &refreshed
+		}
+		n := len(response.Series)
+		if n == 0 || response.Series[n-1].District != p.District {
+			response.Series = append(response.Series, schema.PriceIndexSeries{District: p.District, Interval: interval})
+			n++
+		}
+		response.Series[n-1].Points = append(response.Series[n-1].Points, schema.PriceIndexPointResponse{
+			PeriodStart:       p.PeriodStart.Format("2006-01-02"),
+			ListingCount:      p.ListingCount,
+			MedianPrice:       p.MedianPrice,
+			MedianPricePerSqm: p.MedianPricePerSqm,
+			MedianIndex:       p.MedianIndex,
+			HedonicIndex:      p.HedonicIndex,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodStart(t *testing.T) {
+	cat := time.FixedZone("CAT", 2*60*60)
+	tests := []struct {
+		name     string
+		at       time.Time
+		interval string
+		want     time.Time
+	}{
+		{"month", time.Date(2025, 5, 17, 13, 0, 0, 0, time.UTC), models.PriceIndexMonthly, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"first quarter", time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC), models.PriceIndexQuarterly, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"second quarter", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), models.PriceIndexQuarterly, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"last quarter", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), models.PriceIndexQuarterly, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)},
+		// Just after midnight on 1 June in Lilongwe is still May in UTC
+		{"converted to UTC first", time.Date(2025, 6, 1, 1, 0, 0, 0, cat), models.PriceIndexMonthly, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, periodStart(tt.at, tt.interval))
+		})
+	}
+}
+
+func TestBuildPriceIndexSeries_MedianIndex(t *testing.T) {
+	settings := config.PriceIndexConfig{MinListingsPerPeriod: 3, MinListingsForHedonic: 1000}
+	jan := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	mar := jan.AddDate(0, 2, 0)
+	listing := func(at time.Time, price float64) priceIndexListing {
+		return priceIndexListing{District: "LILONGWE", CreatedAt: at, Price: price}
+	}
+	listings := []priceIndexListing{
+		// January is too thin to be the base
+		listing(jan, 50),
+		listing(feb, 100), listing(feb, 200), listing(feb, 300),
+		listing(mar, 250), listing(mar, 300), listing(mar, 350), listing(mar, 9000),
+	}
+
+	points, hedonic := buildPriceIndexSeries("LILONGWE", models.PriceIndexMonthly, listings, settings, jan)
+	assert.False(t, hedonic, "too few listings for the regression")
+	require.Len(t, points, 3)
+
+	assert.Equal(t, int64(1), points[0].ListingCount)
+	assert.Equal(t, 50.0, *points[0].MedianPrice)
+	assert.Nil(t, points[0].MedianIndex)
+
+	assert.Equal(t, 200.0, *points[1].MedianPrice)
+	assert.Equal(t, 100.0, *points[1].MedianIndex)
+
+	assert.Equal(t, 325.0, *points[2].MedianPrice, "even count takes the mean of the middle pair")
+	assert.Equal(t, 162.5, *points[2].MedianIndex)
+	for _, p := range points {
+		assert.Nil(t, p.HedonicIndex)
+	}
+}
+
+func TestBuildPriceIndexSeries_HedonicHoldsMixConstant(t *testing.T) {
+	settings := config.PriceIndexConfig{MinListingsPerPeriod: 3, MinListingsForHedonic: 30}
+	jan := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	houseType := "House"
+
+	// Prices are 1000 per sqm and rise 10% in February, when bigger houses come on the market
+	var listings []priceIndexListing
+	for i := 0; i < 20; i++ {
+		small, large := float64(100+5*i), float64(200+5*i)
+		listings = append(listings,
+			priceIndexListing{CreatedAt: jan, Price: 1000 * small, BuildingSizeSqm: floatPtr(small), PropertyType: &houseType},
+			priceIndexListing{CreatedAt: feb, Price: 1100 * large, BuildingSizeSqm: floatPtr(large), PropertyType: &houseType},
+		)
+	}
+
+	points, hedonic := buildPriceIndexSeries("LILONGWE", models.PriceIndexMonthly, listings, settings, jan)
+	require.True(t, hedonic)
+	require.Len(t, points, 2)
+	require.NotNil(t, points[0].HedonicIndex)
+	require.NotNil(t, points[1].HedonicIndex)
+	assert.Equal(t, 100.0, *points[0].HedonicIndex)
+	assert.InDelta(t, 110.0, *points[1].HedonicIndex, 0.01)
+	assert.InDelta(t, 184.58, *points[1].MedianIndex, 0.01, "the median index mistakes the bigger houses for a price rise")
+}
+
+func TestFitHedonicIndex_SingularFit(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	// Every listing falls in January, so the February dummy is all zeros and cannot be fitted
+	listings := []priceIndexListing{{CreatedAt: jan, Price: 100}, {CreatedAt: jan, Price: 200}}
+	assert.Nil(t, fitHedonicIndex(listings, []time.Time{jan, feb}, models.PriceIndexMonthly))
+}
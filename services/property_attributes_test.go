@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterAttributeKeys(t *testing.T) {
+	assert.Nil(t, filterAttributeKeys(schema.PropertyFilter{}))
+	keys := filterAttributeKeys(schema.PropertyFilter{Attributes: strPtr(" Water  Tank,parking,,water tank , PARKING")})
+	assert.Equal(t, []string{"water tank", "parking"}, keys)
+	assert.Equal(t, `["water tank"]`, attributeKeyJSON(keys[0]))
+}
+
+func TestAttributesFilter_AnyAndAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		match *string
+		want  string
+	}{
+		{"any by default", nil, `(properties.attribute_names @> '["parking"]'::jsonb OR properties.attribute_names @> '["water tank"]'::jsonb)`},
+		{"any", strPtr("any"), `(properties.attribute_names @> '["parking"]'::jsonb OR properties.attribute_names @> '["water tank"]'::jsonb)`},
+		{"all", strPtr(" ALL "), `(properties.attribute_names @> '["parking"]'::jsonb AND properties.attribute_names @> '["water tank"]'::jsonb)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := setupRecordingDB(t)
+			filter := schema.PropertyFilter{Attributes: strPtr("Parking, Water Tank"), AttributesMatch: tt.match}
+			_, _, err := NewPropertyService(db).GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10}, filter)
+			require.NoError(t, err)
+
+			statements := recorder.touching(`FROM "properties"`)
+			require.NotEmpty(t, statements)
+			for _, sql := range statements {
+				assert.Contains(t, sql, tt.want)
+			}
+		})
+	}
+
+	_, _, err := NewPropertyService(nil).GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10},
+		schema.PropertyFilter{Attributes: strPtr("parking"), AttributesMatch: strPtr("most")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid attributesMatch")
+}
+
+func TestGetAttributeCounts(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT attribute.name AS name, COUNT\(\*\) AS count FROM "properties" ` +
+		`CROSS JOIN LATERAL jsonb_array_elements_text\(properties.attribute_names\) AS attribute\(name\) ` +
+		`WHERE properties.listing_type = \$1 AND jsonb_typeof\(properties.attribute_names\) = 'array' ` +
+		`GROUP BY "attribute"."name" ORDER BY count DESC, name`).
+		WithArgs("Sale").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).AddRow("parking", 12).AddRow("borehole", 3))
+
+	counts, err := NewPropertyService(db).GetAttributeCounts(schema.PropertyFilter{ListingType: strPtr("Sale")})
+	require.NoError(t, err)
+	assert.Equal(t, []schema.AttributeCount{{Name: "parking", Count: 12}, {Name: "borehole", Count: 3}}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
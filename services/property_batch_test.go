@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPropertiesByIDs_KeepsRequestOrder(t *testing.T) {
+	db, mock := setupMockDB(t)
+	// Repeats are asked for once, the database returns rows in its own order
+	mock.ExpectQuery(`SELECT properties.id, properties.price FROM "properties" WHERE properties.id IN \(\$1,\$2,\$3,\$4\)`).
+		WithArgs(30, 10, 99, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "price"}).AddRow(10, 100).AddRow(20, 200).AddRow(30, 300))
+
+	properties, missing, err := NewPropertyService(db).GetPropertiesByIDs([]uint{30, 10, 30, 99, 20, 10},
+		schema.PropertyFilter{Fields: strPtr("price")})
+	require.NoError(t, err)
+	ids := make([]uint, len(properties))
+	for i, p := range properties {
+		ids[i] = p.ID
+	}
+	assert.Equal(t, []uint{30, 10, 20}, ids)
+	assert.Equal(t, []uint{99}, missing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPropertiesByIDs_NothingFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`FROM "properties" WHERE properties.id IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	properties, missing, err := NewPropertyService(db).GetPropertiesByIDs([]uint{1, 2}, schema.PropertyFilter{Fields: strPtr("price")})
+	require.NoError(t, err)
+	assert.Empty(t, properties)
+	assert.Equal(t, []uint{1, 2}, missing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPropertiesByIDs_BatchCap(t *testing.T) {
+	db, mock := setupMockDB(t)
+	service := NewPropertyService(db)
+
+	_, _, err := service.GetPropertiesByIDs(nil, schema.PropertyFilter{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "At least one property ID")
+
+	tooMany := make([]uint, maxBatchGetSize+1)
+	for i := range tooMany {
+		tooMany[i] = uint(i + 1)
+	}
+	_, _, err = service.GetPropertiesByIDs(tooMany, schema.PropertyFilter{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "At most 100 property IDs")
+
+	// The cap counts the IDs asked for, the database is never reached
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// expectPropertyCreate walks CreateProperty through the insert, search index, quality checks
+// and the reload with its preloads.
+func expectPropertyCreate(mock sqlmock.Sqlmock, id uint) {
+	mock.ExpectQuery(`SELECT "id" FROM "properties" WHERE "properties"."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`INSERT INTO "properties"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectExec(`UPDATE properties SET search_vector`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "quality_issues"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	for range outlierMetrics {
+		mock.ExpectQuery(`WITH grouped AS`).WillReturnRows(sqlmock.NewRows([]string{"property_id"}))
+	}
+	mock.ExpectExec(`DELETE FROM "quality_issues"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "properties"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectQuery(`SELECT \* FROM "agents"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "cover_photos"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "locations"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "quality_issues"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestCreateProperty_StoresAgent(t *testing.T) {
+	db, mock := setupMockDB(t)
+	var inserted *uint
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:agent_id", func(tx *gorm.DB) {
+		if property, ok := tx.Statement.Dest.(*models.Property); ok {
+			inserted = property.AgentID
+		}
+	}))
+	expectPropertyCreate(mock, 99)
+
+	// The handler fills in the agent's own profile before it gets here
+	_, err := NewPropertyService(db).CreateProperty(&schema.CreatePropertyRequest{ID: 99, OwnerName: "John Banda", AgentID: uintPtr(4)})
+	require.NoError(t, err)
+	require.NotNil(t, inserted)
+	assert.Equal(t, uint(4), *inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
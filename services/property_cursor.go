@@ -0,0 +1,236 @@
+// services/property_cursor.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+)
+
+// How the total is reported on cursor pages
+const (
+	TotalEstimate = "estimate" // Planner estimate, no extra scan
+	TotalExact    = "exact"
+	TotalNone     = "none"
+)
+
+// Cursor pages use the same default order as page mode
+var defaultPropertySort = []propertySortKey{{Field: "created_at", Expr: "properties.created_at", Desc: true}}
+
+// Sort fields whose cursor values need converting back from JSON
+var (
+	cursorTimeFields = map[string]bool{"created_at": true, "approved_at": true}
+	cursorIntFields  = map[string]bool{"views": true, "age": true}
+)
+
+// propertyCursor marks the last row of a page by its sort values and id. Sort records the
+// keys it was made for so a cursor can't be replayed against a different order.
+type propertyCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	ID     uint          `json:"id"`
+}
+
+// sortSignature is a short form of the sort keys, e.g. "-price,created_at".
+func sortSignature(keys []propertySortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		} else {
+			parts[i] = key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// GetPropertiesByCursor returns the page of properties after the cursor using keyset
+// pagination, so deep pages cost the same as the first and rows added meanwhile don't
+// shift the pages. The next cursor is nil on the last page.
+func (s *PropertyService) GetPropertiesByCursor(req schema.CursorRequest, filter schema.PropertyFilter) ([]models.Property, *string, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, nil, err
+	}
+	keys, _ := parsePropertySort(filter)
+	if len(keys) == 0 {
+		keys = defaultPropertySort
+	}
+	signature := sortSignature(keys)
+	view, _ := ParsePropertyView(filter)
+
+	query := applyPropertyFilters(s.DB.Model(&models.Property{}), filter)
+	if req.Cursor != "" {
+		var cursor propertyCursor
+		if err := utils.DecodeCursor(req.Cursor, &cursor); err != nil {
+			return nil, nil, err
+		}
+		if cursor.Sort != signature || len(cursor.Values) != len(keys) {
+			return nil, nil, utils.NewBadRequestError("The cursor was made for a different sort, start again without a cursor")
+		}
+		values, err := cursorValues(keys, cursor.Values)
+		if err != nil {
+			return nil, nil, err
+		}
+		condition, args := keysetCondition(keys, values, cursor.ID)
+		query = query.Where(condition, args...)
+	}
+
+	// One extra row tells us whether there is a next page
+	var properties []models.Property
+	err := applyPropertySort(query, keys).
+		Limit(req.Limit + 1).
+		Scopes(propertyViewScope(view, "")).
+		Find(&properties).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve properties: %w", err)
+	}
+	if len(properties) <= req.Limit {
+		return properties, nil, nil
+	}
+	properties = properties[:req.Limit]
+
+	next, err := s.nextPropertyCursor(keys, signature, properties[len(properties)-1].ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return properties, &next, nil
+}
+
+// nextPropertyCursor reads the sort values of the page's last row into a cursor.
+func (s *PropertyService) nextPropertyCursor(keys []propertySortKey, signature string, lastID uint) (string, error) {
+	selects := make([]string, len(keys))
+	for i, key := range keys {
+		selects[i] = fmt.Sprintf("%s AS k%d", key.Expr, i)
+	}
+	row := map[string]interface{}{}
+	err := s.DB.Model(&models.Property{}).
+		Select(strings.Join(selects, ", ")).
+		Where("properties.id = ?", lastID).
+		Take(&row).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to read cursor values: %w", err)
+	}
+
+	cursor := propertyCursor{Sort: signature, Values: make([]interface{}, len(keys)), ID: lastID}
+	for i := range keys {
+		cursor.Values[i] = row[fmt.Sprintf("k%d", i)]
+	}
+	token, err := utils.EncodeCursor(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return token, nil
+}
+
+// cursorValues converts the JSON-decoded cursor values back to the column types.
+func cursorValues(keys []propertySortKey, raw []interface{}) ([]interface{}, error) {
+	invalid := utils.NewBadRequestError("Invalid cursor")
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if raw[i] == nil {
+			continue
+		}
+		switch {
+		case cursorTimeFields[key.Field]:
+			str, ok := raw[i].(string)
+			if !ok {
+				return nil, invalid
+			}
+			t, err := time.Parse(time.RFC3339Nano, str)
+			if err != nil {
+				return nil, invalid
+			}
+			values[i] = t
+		case cursorIntFields[key.Field]:
+			num, ok := raw[i].(float64)
+			if !ok || num != math.Trunc(num) {
+				return nil, invalid
+			}
+			values[i] = int64(num)
+		default:
+			num, ok := raw[i].(float64)
+			if !ok {
+				return nil, invalid
+			}
+			values[i] = num
+		}
+	}
+	return values, nil
+}
+
+// keysetCondition matches the rows that sort after the cursor row. Keys sort with NULLS LAST
+// and the id breaks ties, so a row comes later if it equals the cursor on the first keys and
+// then either is past the cursor value or is NULL where the cursor has a value.
+func keysetCondition(keys []propertySortKey, values []interface{}, id uint) (string, []interface{}) {
+	var (
+		terms     []string
+		args      []interface{}
+		equal     []string
+		equalArgs []interface{}
+	)
+	for i, key := range keys {
+		if values[i] == nil {
+			// Only NULLs follow a NULL, and those are ordered by the later keys
+			equal = append(equal, key.Expr+" IS NULL")
+			continue
+		}
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+		conds := append(append([]string{}, equal...), fmt.Sprintf("(%s %s ? OR %s IS NULL)", key.Expr, op, key.Expr))
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+		args = append(append(args, equalArgs...), values[i])
+
+		equal = append(equal, key.Expr+" = ?")
+		equalArgs = append(equalArgs, values[i])
+	}
+	conds := append(append([]string{}, equal...), "properties.id < ?")
+	terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	args = append(append(args, equalArgs...), id)
+
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// CountProperties counts the filtered properties for a cursor page. The estimate comes from
+// the query planner and avoids scanning the matches. Returns nil for TotalNone.
+func (s *PropertyService) CountProperties(filter schema.PropertyFilter, mode string) (*int64, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case TotalNone:
+		return nil, false, nil
+	case TotalExact:
+		var total int64
+		if err := applyPropertyFilters(s.DB.Model(&models.Property{}), filter).Count(&total).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to count properties: %w", err)
+		}
+		return &total, false, nil
+	case "", TotalEstimate:
+	default:
+		return nil, false, utils.NewBadRequestError(fmt.Sprintf("Invalid total '%s', use estimate, exact or none", mode))
+	}
+
+	var plan string
+	matches := applyPropertyFilters(s.DB.Model(&models.Property{}), filter).Select("properties.id")
+	if err := s.DB.Raw("EXPLAIN (FORMAT JSON) ?", matches).Row().Scan(&plan); err != nil {
+		return nil, false, fmt.Errorf("failed to estimate property count: %w", err)
+	}
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explain); err != nil {
+		return nil, false, fmt.Errorf("failed to read query plan: %w", err)
+	}
+	if len(explain) == 0 {
+		return nil, false, fmt.Errorf("failed to read query plan: empty plan")
+	}
+	total := int64(explain[0].Plan.Rows)
+	return &total, true, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hopekali04/valuations/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sortPrice     = propertySortKey{Field: "price", Expr: "properties.price"}
+	sortPriceDesc = propertySortKey{Field: "price", Expr: "properties.price", Desc: true}
+	sortCreatedAt = propertySortKey{Field: "created_at", Expr: "properties.created_at"}
+	sortViewsDesc = propertySortKey{Field: "views", Expr: "properties.views", Desc: true}
+)
+
+func TestSortSignature(t *testing.T) {
+	assert.Equal(t, "-price,created_at", sortSignature([]propertySortKey{sortPriceDesc, sortCreatedAt}))
+	assert.Equal(t, "", sortSignature(nil))
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []propertySortKey
+		values   []interface{}
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "id only",
+			wantSQL:  "((properties.id < ?))",
+			wantArgs: []interface{}{uint(5)},
+		},
+		{
+			name:     "one ascending key",
+			keys:     []propertySortKey{sortPrice},
+			values:   []interface{}{100.0},
+			wantSQL:  "(((properties.price > ? OR properties.price IS NULL)) OR (properties.price = ? AND properties.id < ?))",
+			wantArgs: []interface{}{100.0, 100.0, uint(5)},
+		},
+		{
+			name:   "ascending then descending",
+			keys:   []propertySortKey{sortPrice, sortViewsDesc},
+			values: []interface{}{100.0, int64(7)},
+			wantSQL: "(((properties.price > ? OR properties.price IS NULL))" +
+				" OR (properties.price = ? AND (properties.views < ? OR properties.views IS NULL))" +
+				" OR (properties.price = ? AND properties.views = ? AND properties.id < ?))",
+			wantArgs: []interface{}{100.0, 100.0, int64(7), 100.0, int64(7), uint(5)},
+		},
+		{
+			name:   "cursor row has a NULL",
+			keys:   []propertySortKey{sortPriceDesc, sortCreatedAt},
+			values: []interface{}{100.0, nil},
+			wantSQL: "(((properties.price < ? OR properties.price IS NULL))" +
+				" OR (properties.price = ? AND properties.created_at IS NULL AND properties.id < ?))",
+			wantArgs: []interface{}{100.0, 100.0, uint(5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := keysetCondition(tt.keys, tt.values, 5)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestCursorValues(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 30, 0, 123000000, time.UTC)
+
+	// Values come back from the token as JSON decoded them
+	token, err := utils.EncodeCursor(propertyCursor{Sort: "price,created_at,-views", Values: []interface{}{250000.5, created, 42}, ID: 9})
+	require.NoError(t, err)
+	var cursor propertyCursor
+	require.NoError(t, utils.DecodeCursor(token, &cursor))
+
+	values, err := cursorValues([]propertySortKey{sortPrice, sortCreatedAt, sortViewsDesc}, cursor.Values)
+	require.NoError(t, err)
+	assert.Equal(t, 250000.5, values[0])
+	assert.True(t, created.Equal(values[1].(time.Time)))
+	assert.Equal(t, int64(42), values[2])
+}
+
+func TestCursorValues_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		key  propertySortKey
+		raw  interface{}
+	}{
+		{"time not a string", sortCreatedAt, 12.0},
+		{"time not RFC 3339", sortCreatedAt, "yesterday"},
+		{"int with a fraction", sortViewsDesc, 4.5},
+		{"int as a string", sortViewsDesc, "4"},
+		{"number as a string", sortPrice, "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cursorValues([]propertySortKey{tt.key}, []interface{}{tt.raw})
+			assert.Error(t, err)
+		})
+	}
+
+	values, err := cursorValues([]propertySortKey{sortPrice}, []interface{}{nil})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil}, values, "NULLs stay NULL")
+}
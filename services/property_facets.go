@@ -0,0 +1,151 @@
+// services/property_facets.go
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"gorm.io/gorm"
+)
+
+// Facets with many values (areas, agents) only return the most common ones
+const maxFacetValues = 50
+
+// propertyFacets lists the grouped facets. Location and agent values come from correlated
+// subqueries rather than joins so they combine with the trigram search, which joins those
+// tables itself. clear removes the facet's own filter.
+var propertyFacets = []struct {
+	name  string
+	value string
+	label string
+	clear func(f *schema.PropertyFilter)
+}{
+	{"district", "(SELECT trim(l.district) FROM locations l WHERE l.property_id = properties.id LIMIT 1)", "",
+		func(f *schema.PropertyFilter) { f.District = nil }},
+	{"area", "(SELECT trim(l.area) FROM locations l WHERE l.property_id = properties.id LIMIT 1)", "",
+		func(f *schema.PropertyFilter) { f.Area = nil }},
+	{"property_type", "properties.property_type", "",
+		func(f *schema.PropertyFilter) { f.PropertyType = nil }},
+	{"construction_stage", "properties.construction_stage", "",
+		func(f *schema.PropertyFilter) { f.ConstructionStage = nil }},
+	{"listing_type", "properties.listing_type", "",
+		func(f *schema.PropertyFilter) { f.ListingType = nil }},
+	{"rooms", "properties.no_rooms::text", "",
+		func(f *schema.PropertyFilter) { f.Rooms = nil }},
+	{"agent", "properties.agent_id::text", "(SELECT u.name FROM agents a JOIN users u ON u.id = a.user_id WHERE a.id = properties.agent_id)",
+		func(f *schema.PropertyFilter) { f.AgentID = nil }},
+}
+
+type facetRow struct {
+	Value string
+	Label *string
+	Count int64
+}
+
+// GetFacets counts the properties behind each filter option for the current listing or
+// search. Every facet applies all the active filters except its own.
+func (s *PropertyService) GetFacets(filter schema.PropertyFilter, searchTerm string) (*schema.PropertyFacets, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, err
+	}
+	searchScope, err := s.searchScope(searchTerm, filter)
+	if err != nil {
+		return nil, err
+	}
+	base := func(f schema.PropertyFilter) *gorm.DB {
+		query := applyPropertyFilters(s.DB.Model(&models.Property{}), f)
+		if searchScope != nil {
+			query = query.Scopes(searchScope)
+		}
+		return query
+	}
+
+	facets := &schema.PropertyFacets{}
+	targets := map[string]*[]schema.FacetValue{
+		"district":           &facets.District,
+		"area":               &facets.Area,
+		"property_type":      &facets.PropertyType,
+		"construction_stage": &facets.ConstructionStage,
+		"listing_type":       &facets.ListingType,
+		"rooms":              &facets.Rooms,
+		"agent":              &facets.Agent,
+	}
+	for _, facet := range propertyFacets {
+		f := filter
+		facet.clear(&f)
+
+		label := "NULL"
+		if facet.label != "" {
+			label = facet.label
+		}
+		var rows []facetRow
+		err := base(f).
+			Select(fmt.Sprintf("%s AS value, %s AS label, COUNT(*) AS count", facet.value, label)).
+			Where(fmt.Sprintf("%s <> ''", facet.value)). // Also drops NULLs
+			Group("1, 2").
+			Order("count DESC, value").
+			Limit(maxFacetValues).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s facet: %w", facet.name, err)
+		}
+
+		values := make([]schema.FacetValue, len(rows))
+		for i, r := range rows {
+			values[i] = schema.FacetValue{Value: r.Value, Count: r.Count}
+			if r.Label != nil {
+				values[i].Label = strings.TrimSpace(*r.Label)
+			}
+		}
+		*targets[facet.name] = values
+	}
+
+	// Price uses the same buckets as the market stats
+	edges, err := ParsePriceBuckets("")
+	if err != nil {
+		return nil, err
+	}
+	f := filter
+	f.MinPrice, f.MaxPrice = nil, nil
+	var bucketRows []struct {
+		Bucket int
+		Count  int64
+	}
+	err = base(f).
+		Select(priceBucketSQL(edges) + " AS bucket, COUNT(*) AS count").
+		Where("properties.price > 0").
+		Group("bucket").
+		Scan(&bucketRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count price facet: %w", err)
+	}
+	counts := make(map[int]int64, len(bucketRows))
+	for _, r := range bucketRows {
+		counts[r.Bucket] = r.Count
+	}
+	facets.Price = priceDistribution(edges, counts)
+
+	return facets, nil
+}
+
+// searchScope returns the condition SearchProperties would use for the term: full text, or
+// the trigram fallback when full text finds nothing. It is nil for an empty term.
+func (s *PropertyService) searchScope(searchTerm string, filter schema.PropertyFilter) (func(db *gorm.DB) *gorm.DB, error) {
+	searchTerm = strings.TrimSpace(searchTerm)
+	if searchTerm == "" {
+		return nil, nil
+	}
+	var matches int64
+	err := applyPropertyFilters(s.DB.Model(&models.Property{}), filter).
+		Scopes(fullTextScope(searchTerm)).
+		Count(&matches).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+	if matches == 0 {
+		return fuzzyScope(searchTerm), nil
+	}
+	return fullTextScope(searchTerm), nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder keeps every statement GORM runs, with its arguments filled in.
+type sqlRecorder struct {
+	logger.Interface
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface { return r }
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, sql)
+}
+
+// touching returns the recorded statements that contain fragment.
+func (r *sqlRecorder) touching(fragment string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []string
+	for _, sql := range r.statements {
+		if strings.Contains(sql, fragment) {
+			found = append(found, sql)
+		}
+	}
+	return found
+}
+
+// setupRecordingDB returns a DB on which every statement succeeds with no rows, for tests that
+// only look at the SQL that was sent.
+func setupRecordingDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(string, string) error { return nil })))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	for i := 0; i < 50; i++ {
+		mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, PreferSimpleProtocol: true}), &gorm.Config{
+		Logger:                 recorder,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return gormDB, recorder
+}
+
+func TestGetFacets_EachFacetDropsOnlyItsOwnFilter(t *testing.T) {
+	db, recorder := setupRecordingDB(t)
+	minPrice, maxPrice := 1000.0, 900000.0
+	agentID := uint(12)
+	rooms := 4
+	filter := schema.PropertyFilter{
+		District:          strPtr("Zomba"),
+		Area:              strPtr("Area 47"),
+		PropertyType:      strPtr("House"),
+		ConstructionStage: strPtr("Complete"),
+		ListingType:       strPtr("Sale"),
+		Rooms:             &rooms,
+		AgentID:           &agentID,
+		MinPrice:          &minPrice,
+		MaxPrice:          &maxPrice,
+	}
+
+	_, err := NewPropertyService(db).GetFacets(filter, "")
+	require.NoError(t, err)
+
+	conditions := map[string][]string{
+		"district":           {`locations.district ILIKE '%Zomba%'`},
+		"area":               {`locations.area ILIKE '%Area 47%'`},
+		"property_type":      {`properties.property_type = 'House'`},
+		"construction_stage": {`properties.construction_stage = 'Complete'`},
+		"listing_type":       {`properties.listing_type = 'Sale'`},
+		"rooms":              {`properties.no_rooms = 4`},
+		"agent":              {`properties.agent_id = 12`},
+		"price":              {`properties.price >= 1000`, `properties.price <= 900000`},
+	}
+	statements := map[string]string{}
+	for _, facet := range propertyFacets {
+		found := recorder.touching(facet.value + " AS value")
+		require.Len(t, found, 1, facet.name)
+		statements[facet.name] = found[0]
+	}
+	found := recorder.touching(" AS bucket")
+	require.Len(t, found, 1)
+	statements["price"] = found[0]
+
+	for name, sql := range statements {
+		for other, clauses := range conditions {
+			for _, clause := range clauses {
+				if other == name {
+					assert.False(t, strings.Contains(sql, clause), "%s facet should drop %q: %s", name, clause, sql)
+				} else {
+					assert.True(t, strings.Contains(sql, clause), "%s facet should keep %q: %s", name, clause, sql)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// services/property_fields.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// Relations a property response can embed, named as in the JSON
+const (
+	includeLocation      = "location"
+	includeAgent         = "agent"
+	includeCoverPhoto    = "cover_photo"
+	includeQualityIssues = "quality_issues"
+)
+
+// propertyRelations maps each relation to the shape of its JSON, for checking sub-fields
+var propertyRelations = map[string]reflect.Type{
+	includeLocation:      reflect.TypeOf(models.Location{}),
+	includeAgent:         reflect.TypeOf(schema.AgentResponse{}),
+	includeCoverPhoto:    reflect.TypeOf(schema.CoverPhotoResponse{}),
+	includeQualityIssues: reflect.TypeOf(schema.QualityIssueResponse{}),
+}
+
+// Keys filled in by the handlers rather than loaded, kept whenever they are set
+var computedPropertyKeys = map[string]bool{"search": true, "distance_km": true}
+
+// jsonKeys lists the JSON names of a struct's fields.
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+var (
+	propertyColumnsOnce sync.Once
+	propertyColumns     map[string]string // Response key -> properties column
+)
+
+// propertyColumnFor returns the column behind a scalar response key. The model and the
+// response share JSON names, so the model's schema gives the mapping.
+func propertyColumnFor(key string) (string, bool) {
+	propertyColumnsOnce.Do(func() {
+		propertyColumns = map[string]string{}
+		s, err := gormschema.Parse(&models.Property{}, &sync.Map{}, gormschema.NamingStrategy{})
+		if err != nil {
+			return
+		}
+		for _, field := range s.Fields {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.DBName == "" || !field.Readable || name == "" || name == "-" {
+				continue
+			}
+			propertyColumns[name] = field.DBName
+		}
+	})
+	column, ok := propertyColumns[key]
+	return column, ok
+}
+
+// PropertyView is what the fields and include parameters ask for: the columns to load, the
+// relations to preload and the JSON keys to keep. The zero value is the full response.
+type PropertyView struct {
+	fields   map[string][]string // Top-level key -> sub-keys to keep, nil keeps the whole value. nil map keeps every key
+	includes map[string]bool     // Relations in the output
+	preloads map[string]bool     // Relations loaded, includes plus what the handlers need
+	columns  []string            // Columns to select, nil selects them all
+}
+
+func splitList(raw *string) []string {
+	if raw == nil {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(*raw, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedKeys(m map[string]reflect.Type) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ParsePropertyView reads fields (e.g. id,price,location.district) and include (e.g.
+// agent,cover_photo) from the filter. A relation is embedded when it is in include or named
+// in fields. Without either parameter the response is unchanged.
+func ParsePropertyView(filter schema.PropertyFilter) (*PropertyView, error) {
+	fields := splitList(filter.Fields)
+	includes := splitList(filter.Include)
+	view := &PropertyView{includes: map[string]bool{}, preloads: map[string]bool{}}
+
+	if filter.Include != nil {
+		for _, name := range includes {
+			if _, ok := propertyRelations[name]; !ok {
+				return nil, utils.NewBadRequestError(fmt.Sprintf("Unknown include '%s', use any of: %s", name, sortedKeys(propertyRelations)))
+			}
+			view.includes[name] = true
+		}
+	} else if len(fields) == 0 {
+		for name := range propertyRelations {
+			view.includes[name] = true
+		}
+	}
+
+	if filter.Fields != nil && len(fields) > 0 {
+		responseKeys := jsonKeys(reflect.TypeOf(schema.PropertyResponse{}))
+		view.fields = map[string][]string{"id": nil}
+		columns := map[string]bool{"id": true}
+		for _, field := range fields {
+			key, sub, nested := strings.Cut(field, ".")
+			if relation, ok := propertyRelations[key]; ok {
+				view.includes[key] = true
+				if !nested {
+					view.fields[key] = nil
+					continue
+				}
+				if !jsonKeys(relation)[sub] {
+					return nil, utils.NewBadRequestError(fmt.Sprintf("Unknown field '%s' in fields", field))
+				}
+				if kept, seen := view.fields[key]; !seen || kept != nil {
+					view.fields[key] = append(kept, sub)
+				}
+				continue
+			}
+			if nested || !responseKeys[key] {
+				return nil, utils.NewBadRequestError(fmt.Sprintf("Unknown field '%s' in fields", field))
+			}
+			view.fields[key] = nil
+			if column, ok := propertyColumnFor(key); ok {
+				columns[column] = true
+			}
+		}
+		if view.includes[includeAgent] {
+			columns["agent_id"] = true // The agent preload follows the foreign key
+		}
+		for column := range columns {
+			view.columns = append(view.columns, "properties."+column)
+		}
+		sort.Strings(view.columns)
+	}
+
+	for name := range view.includes {
+		view.preloads[name] = true
+	}
+	if filter.Lat != nil && filter.Lng != nil {
+		view.preloads[includeLocation] = true // distance_km is worked out from the location
+	}
+	return view, nil
+}
+
+// IsFull reports whether the view is the unchanged, full response.
+func (v *PropertyView) IsFull() bool {
+	return v == nil || (v.fields == nil && len(v.includes) == len(propertyRelations))
+}
+
+// SelectSQL is the select list for the view's columns.
+func (v *PropertyView) SelectSQL() string {
+	if v == nil || v.columns == nil {
+		return "properties.*"
+	}
+	return strings.Join(v.columns, ", ")
+}
+
+// propertyViewScope selects the view's columns plus an optional extra expression, such as a
+// search rank, and preloads only the view's relations.
+func propertyViewScope(v *PropertyView, extra string, args ...interface{}) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if extra != "" {
+			db = db.Select(v.SelectSQL()+", "+extra, args...)
+		} else if v != nil && v.columns != nil {
+			db = db.Select(v.SelectSQL())
+		}
+		if v == nil || v.preloads[includeLocation] {
+			db = db.Preload("Location")
+		}
+		if v == nil || v.preloads[includeAgent] {
+			db = db.Preload("Agent.User")
+		}
+		if v == nil || v.preloads[includeCoverPhoto] {
+			db = db.Preload("CoverPhoto")
+		}
+		if v == nil || v.preloads[includeQualityIssues] {
+			db = db.Preload("QualityIssues")
+		}
+		return db
+	}
+}
+
+// Apply trims mapped responses to the view. The full view returns them as they are,
+// otherwise each becomes a map holding only the requested keys.
+func (v *PropertyView) Apply(responses []schema.PropertyResponse) (interface{}, error) {
+	if v.IsFull() {
+		return responses, nil
+	}
+	// Relations that were loaded only for the handlers are dropped as well
+	needsTrim := v.fields != nil
+	for name := range v.preloads {
+		if !v.includes[name] {
+			needsTrim = true
+		}
+	}
+	if !needsTrim {
+		return responses, nil // Relations that were not preloaded are already left out
+	}
+
+	trimmed := make([]map[string]interface{}, len(responses))
+	for i := range responses {
+		data, err := json.Marshal(responses[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode property %d: %w", responses[i].ID, err)
+		}
+		var all map[string]interface{}
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, fmt.Errorf("failed to decode property %d: %w", responses[i].ID, err)
+		}
+
+		out := map[string]interface{}{}
+		for key, value := range all {
+			if computedPropertyKeys[key] {
+				out[key] = value
+				continue
+			}
+			if _, isRelation := propertyRelations[key]; isRelation {
+				if !v.includes[key] {
+					continue
+				}
+				out[key] = trimValue(value, v.fields[key])
+				continue
+			}
+			if v.fields == nil {
+				out[key] = value
+			} else if _, ok := v.fields[key]; ok {
+				out[key] = value
+			}
+		}
+		trimmed[i] = out
+	}
+	return trimmed, nil
+}
+
+// trimValue keeps only the given keys of an object, or of each object in a list.
+func trimValue(value interface{}, keep []string) interface{} {
+	if keep == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(keep))
+		for _, key := range keep {
+			if val, ok := v[key]; ok {
+				out[key] = val
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = trimValue(item, keep)
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropertyView(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      schema.PropertyFilter
+		wantFull    bool
+		wantSelect  string
+		wantFields  map[string][]string
+		wantInclude []string
+		wantPreload []string
+	}{
+		{
+			name:        "no parameters",
+			wantFull:    true,
+			wantSelect:  "properties.*",
+			wantInclude: []string{includeAgent, includeCoverPhoto, includeLocation, includeQualityIssues},
+			wantPreload: []string{includeAgent, includeCoverPhoto, includeLocation, includeQualityIssues},
+		},
+		{
+			name:        "fields with a relation sub-field",
+			filter:      schema.PropertyFilter{Fields: strPtr("Price, location.district,location.area")},
+			wantSelect:  "properties.id, properties.price",
+			wantFields:  map[string][]string{"id": nil, "price": nil, "location": {"district", "area"}},
+			wantInclude: []string{includeLocation},
+			wantPreload: []string{includeLocation},
+		},
+		{
+			name:        "whole relation wins over its sub-fields",
+			filter:      schema.PropertyFilter{Fields: strPtr("location.district,location")},
+			wantSelect:  "properties.id",
+			wantFields:  map[string][]string{"id": nil, "location": nil},
+			wantInclude: []string{includeLocation},
+			wantPreload: []string{includeLocation},
+		},
+		{
+			name:        "agent needs its foreign key",
+			filter:      schema.PropertyFilter{Fields: strPtr("price"), Include: strPtr("agent")},
+			wantSelect:  "properties.agent_id, properties.id, properties.price",
+			wantFields:  map[string][]string{"id": nil, "price": nil},
+			wantInclude: []string{includeAgent},
+			wantPreload: []string{includeAgent},
+		},
+		{
+			name:        "include only",
+			filter:      schema.PropertyFilter{Include: strPtr("cover_photo,agent")},
+			wantSelect:  "properties.*",
+			wantInclude: []string{includeAgent, includeCoverPhoto},
+			wantPreload: []string{includeAgent, includeCoverPhoto},
+		},
+		{
+			name:       "empty include",
+			filter:     schema.PropertyFilter{Include: strPtr("")},
+			wantSelect: "properties.*",
+		},
+		{
+			name:        "distance needs the location",
+			filter:      schema.PropertyFilter{Include: strPtr(""), Lat: floatPtr(-13.9), Lng: floatPtr(33.7)},
+			wantSelect:  "properties.*",
+			wantPreload: []string{includeLocation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := ParsePropertyView(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFull, view.IsFull())
+			assert.Equal(t, tt.wantSelect, view.SelectSQL())
+			assert.Equal(t, tt.wantFields, view.fields)
+			assert.ElementsMatch(t, tt.wantInclude, keysOf(view.includes))
+			assert.ElementsMatch(t, tt.wantPreload, keysOf(view.preloads))
+		})
+	}
+}
+
+func TestParsePropertyView_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter schema.PropertyFilter
+		want   string
+	}{
+		{"unknown field", schema.PropertyFilter{Fields: strPtr("bedrooms")}, "Unknown field 'bedrooms' in fields"},
+		{"unknown sub-field", schema.PropertyFilter{Fields: strPtr("location.bedrooms")}, "Unknown field 'location.bedrooms' in fields"},
+		{"sub-field of a scalar", schema.PropertyFilter{Fields: strPtr("price.amount")}, "Unknown field 'price.amount' in fields"},
+		{"unknown include", schema.PropertyFilter{Include: strPtr("photos")}, "Unknown include 'photos'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePropertyView(tt.filter)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestPropertyView_Apply(t *testing.T) {
+	price, distance := 45000000.0, 2.5
+	responses := []schema.PropertyResponse{{
+		ID:          12,
+		Price:       &price,
+		ListingType: "Sale",
+		Location:    &models.Location{ID: 3, District: "Lilongwe", Area: "Area 43"},
+		CoverPhoto:  &schema.CoverPhotoResponse{ID: 8, Url: "https://example.com/8.jpg"},
+		DistanceKm:  &distance,
+	}}
+
+	t.Run("full view is unchanged", func(t *testing.T) {
+		view, err := ParsePropertyView(schema.PropertyFilter{})
+		require.NoError(t, err)
+		out, err := view.Apply(responses)
+		require.NoError(t, err)
+		assert.Equal(t, responses, out)
+	})
+
+	t.Run("fields keep only their keys", func(t *testing.T) {
+		view, err := ParsePropertyView(schema.PropertyFilter{Fields: strPtr("price,location.district")})
+		require.NoError(t, err)
+		out, err := view.Apply(responses)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]interface{}{{
+			"id":          12.0,
+			"price":       45000000.0,
+			"location":    map[string]interface{}{"district": "Lilongwe"},
+			"distance_km": 2.5, // Computed keys are kept whenever set
+		}}, out)
+	})
+
+	t.Run("relations loaded for the handlers are dropped", func(t *testing.T) {
+		view, err := ParsePropertyView(schema.PropertyFilter{Include: strPtr("cover_photo"), Lat: floatPtr(-13.9), Lng: floatPtr(33.7)})
+		require.NoError(t, err)
+		out, err := view.Apply(responses)
+		require.NoError(t, err)
+		trimmed := out.([]map[string]interface{})
+		require.Len(t, trimmed, 1)
+		assert.NotContains(t, trimmed[0], "location")
+		assert.Contains(t, trimmed[0], "cover_photo")
+		assert.Equal(t, "Sale", trimmed[0]["listing_type"])
+	})
+
+	t.Run("include without extra preloads is unchanged", func(t *testing.T) {
+		view, err := ParsePropertyView(schema.PropertyFilter{Include: strPtr("location")})
+		require.NoError(t, err)
+		out, err := view.Apply(responses)
+		require.NoError(t, err)
+		assert.Equal(t, responses, out)
+	})
+}
+
+func keysOf(m map[string]bool) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
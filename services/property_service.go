@@ -2,8 +2,12 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/hopekali04/valuations/models"
 	"github.com/hopekali04/valuations/schema"
@@ -11,14 +15,56 @@ import (
 	"gorm.io/gorm"
 )
 
+// Full-text search settings. The same text search configuration must be used for the
+// stored documents and the queries or stemmed words will not match.
+const (
+	searchLanguage        = "english"
+	searchQuerySQL        = "websearch_to_tsquery('" + searchLanguage + "', ?)"
+	searchHighlightStart  = "<mark>"
+	searchHighlightStop   = "</mark>"
+	searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=\" … \""
+)
+
+const (
+	fuzzySearchThreshold = 0.4 // Minimum trigram word similarity for the fallback search and suggestions
+	DefaultSuggestLimit  = 10
+	MaxSuggestLimit      = 25
+)
+
+// maxBatchGetSize caps how many properties one batch fetch can ask for
+const maxBatchGetSize = 100
+
+// searchFields are the values shown in search highlights. Fuzzy fields are also used by the
+// trigram fallback, the description is left out as it is too long to compare usefully.
+var searchFields = []struct {
+	name  string
+	expr  string
+	fuzzy bool
+}{
+	{"owner_name", "coalesce(properties.owner_name, '')", true},
+	{"location", "concat_ws(', ', locations.sub_area, locations.area, locations.district)", true},
+	{"design", "coalesce(properties.property_design, '')", true},
+	{"agent_name", "coalesce(users.name, '')", true},
+	{"description", "coalesce(properties.description, '')", false},
+}
+
 type PropertyService struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Alerts *SavedSearchService // Optional, new properties are checked against saved searches
 }
 
 func NewPropertyService(db *gorm.DB) *PropertyService {
 	return &PropertyService{DB: db}
 }
 
+// WithContext returns a copy of the service whose queries run with ctx. Handlers pass the
+// request context, so the tenant stored on it by RequireAuth limits what they can see.
+func (s *PropertyService) WithContext(ctx context.Context) *PropertyService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
 // --- Create Operations ---
 
 // CreateProperty handles creation of a single property, checking for duplicates.
@@ -26,7 +72,8 @@ func (s *PropertyService) CreateProperty(req *schema.CreatePropertyRequest) (*mo
 	// 1. Check if property with this ID already exists
 	var existing models.Property
 	// Use .Unscoped() if you might have soft-deleted records with the same ID you want to prevent reusing
-	err := s.DB.Select("id").First(&existing, req.ID).Error
+	// Another bank's listing holds the ID as well, it gets the same conflict as one we can see
+	err := allTenants(s.DB).Select("id").First(&existing, req.ID).Error
 	if err == nil {
 		// Record found, return specific conflict error
 		return nil, utils.ErrPropertyExists
@@ -76,13 +123,21 @@ func (s *PropertyService) CreateProperty(req *schema.CreatePropertyRequest) (*mo
 		ApprovedAt:                   req.ApprovedAt,
 		Visibility:                   req.Visibility,
 		Views:                        req.Views,
+		AgentID:                      req.AgentID,
+	}
 
+	newProperty.AttributeNames = models.AttributeKeys(newProperty.Attributes)
+
+	// Normalise sizes to square metres, unknown units are left nil and show up in /properties/unit-issues
+	if !normalisePropertySizes(&newProperty) {
+		fmt.Printf("Warning: property %d has a size unit we could not convert (building '%s', land '%s')\n", newProperty.ID, newProperty.BuildingSizeUnit, newProperty.LandSizeUnit)
 	}
 
 	// If Location data is provided in the request, assign it. GORM handles association.
 	if req.Location != nil {
 
 		req.Location.PropertyID = newProperty.ID
+		req.Location.Lat, req.Location.Lng = utils.ParseLatLng(req.Location.Latitude, req.Location.Longitude)
 
 		newProperty.Location = *req.Location
 	}
@@ -100,10 +155,28 @@ func (s *PropertyService) CreateProperty(req *schema.CreatePropertyRequest) (*mo
 		return nil, fmt.Errorf("failed to create property: %w", result.Error)
 	}
 
+	if _, err := refreshSearchVectors(s.DB, "pr.id = ?", newProperty.ID); err != nil {
+		fmt.Printf("Warning: failed to index property %d for search: %v\n", newProperty.ID, err)
+	}
+
+	// Flag data problems, a failure here should not undo the create
+	if err := checkPropertyQuality(s.DB, &newProperty); err != nil {
+		fmt.Printf("Warning: failed to run quality checks for property %d: %v\n", newProperty.ID, err)
+	}
+
+	// Alert saved searches, sending happens in the background so slow mail or webhooks don't hold up the request
+	if s.Alerts != nil {
+		if result, err := s.Alerts.RecordMatches([]uint{newProperty.ID}); err != nil {
+			fmt.Printf("Warning: failed to check saved searches for property %d: %v\n", newProperty.ID, err)
+		} else if result.Matches > 0 {
+			s.Alerts.DeliverInBackground()
+		}
+	}
 
 	err = s.DB.Preload("Location").
 		Preload("Agent.User"). // Preload User within Agent
 		Preload("CoverPhoto").
+		Preload("QualityIssues").
 		First(&newProperty, newProperty.ID).Error
 	if err != nil {
 		// Log this error, but potentially still return the created property without associations
@@ -149,6 +222,126 @@ func (s *PropertyService) CreateMultipleProperties(requests []schema.CreatePrope
 	return successfulProperties, errorsReport
 }
 
+// --- Update Operations ---
+
+// UpdateProperty edits a listing. The size and price columns are normalised again when any of
+// them change, and the listing is re-indexed and re-checked the same way as on create.
+func (s *PropertyService) UpdateProperty(id uint, req *schema.UpdatePropertyRequest) (*models.Property, error) {
+	var property models.Property
+	if err := s.DB.First(&property, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Property")
+		}
+		return nil, fmt.Errorf("database error retrieving property: %w", err)
+	}
+
+	updates := map[string]interface{}{}
+	if req.PropertyType != nil {
+		updates["property_type"] = *req.PropertyType
+	}
+	if req.PropertyDesign != nil {
+		updates["property_design"] = *req.PropertyDesign
+	}
+	if req.ConstructionStage != nil {
+		updates["construction_stage"] = *req.ConstructionStage
+	}
+	if req.NoRooms != nil {
+		updates["no_rooms"] = *req.NoRooms
+	}
+	if req.NoOfBathrooms != nil {
+		updates["no_of_bathrooms"] = *req.NoOfBathrooms
+	}
+	if req.Occupancy != nil {
+		updates["occupancy"] = *req.Occupancy
+	}
+	if req.Attributes != nil {
+		updates["attributes"] = *req.Attributes
+		updates["attribute_names"] = models.AttributeKeys(*req.Attributes)
+	}
+	if req.Defects != nil {
+		updates["defects"] = *req.Defects
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ListingType != nil {
+		updates["listing_type"] = *req.ListingType
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+	if req.AgentID != nil {
+		if *req.AgentID == 0 {
+			updates["agent_id"] = nil
+		} else {
+			updates["agent_id"] = *req.AgentID
+		}
+	}
+
+	// Sizes and price feed the square-metre columns, so those are worked out again from the new values
+	if req.BuildingSize != nil || req.BuildingSizeUnit != nil || req.LandSize != nil || req.LandSizeUnit != nil || req.Price != nil {
+		if req.BuildingSize != nil {
+			property.BuildingSize = req.BuildingSize
+		}
+		if req.BuildingSizeUnit != nil {
+			property.BuildingSizeUnit = *req.BuildingSizeUnit
+		}
+		if req.LandSize != nil {
+			property.LandSize = req.LandSize
+		}
+		if req.LandSizeUnit != nil {
+			property.LandSizeUnit = *req.LandSizeUnit
+		}
+		if req.Price != nil {
+			property.Price = req.Price
+		}
+		if !normalisePropertySizes(&property) {
+			fmt.Printf("Warning: property %d has a size unit we could not convert (building '%s', land '%s')\n", property.ID, property.BuildingSizeUnit, property.LandSizeUnit)
+		}
+		updates["building_size"] = property.BuildingSize
+		updates["building_size_unit"] = property.BuildingSizeUnit
+		updates["land_size"] = property.LandSize
+		updates["land_size_unit"] = property.LandSizeUnit
+		updates["price"] = property.Price
+		updates["building_size_sqm"] = property.BuildingSizeSqm
+		updates["land_size_sqm"] = property.LandSizeSqm
+		updates["building_price_per_sqm"] = property.BuildingPricePerSqm
+		updates["land_price_per_sqm"] = property.LandPricePerSqm
+	}
+	if len(updates) == 0 {
+		return s.GetPropertyByID(id)
+	}
+
+	if err := s.DB.Model(&property).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update property: %w", err)
+	}
+
+	if _, err := refreshSearchVectors(s.DB, "pr.id = ?", id); err != nil {
+		fmt.Printf("Warning: failed to index property %d for search: %v\n", id, err)
+	}
+
+	updated, err := s.GetPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flag data problems against the edited listing, a failure here should not undo the update
+	if err := checkPropertyQuality(s.DB, updated); err != nil {
+		fmt.Printf("Warning: failed to run quality checks for property %d: %v\n", id, err)
+	}
+
+	// An edited listing can start matching saved searches, alerts already sent are not sent again
+	if s.Alerts != nil {
+		if result, err := s.Alerts.RecordMatches([]uint{id}); err != nil {
+			fmt.Printf("Warning: failed to check saved searches for property %d: %v\n", id, err)
+		} else if result.Matches > 0 {
+			s.Alerts.DeliverInBackground()
+		}
+	}
+
+	return s.GetPropertyByID(id)
+}
+
 // --- Read Operations ---
 
 // GetPropertyByID retrieves a single property by its ID with associations.
@@ -158,6 +351,7 @@ func (s *PropertyService) GetPropertyByID(id uint) (*models.Property, error) {
 	err := s.DB.Preload("Location").
 		Preload("Agent.User").
 		Preload("CoverPhoto").
+		Preload("QualityIssues").
 		// Preload("OpenHouses"). // Add if OpenHouse model exists
 		First(&property, id).Error
 
@@ -170,8 +364,64 @@ func (s *PropertyService) GetPropertyByID(id uint) (*models.Property, error) {
 	return &property, nil
 }
 
+// GetPropertiesByIDs loads the given properties in one query, returned in the order asked for.
+// Repeated IDs are returned once and IDs with no property come back as missing. The fields and
+// include parameters of the filter decide what is loaded, the other filters are not used.
+func (s *PropertyService) GetPropertiesByIDs(ids []uint, filter schema.PropertyFilter) ([]models.Property, []uint, error) {
+	if len(ids) == 0 {
+		return nil, nil, utils.NewBadRequestError("At least one property ID is required")
+	}
+	if len(ids) > maxBatchGetSize {
+		return nil, nil, utils.NewBadRequestError(fmt.Sprintf("At most %d property IDs can be fetched at once", maxBatchGetSize))
+	}
+	view, err := ParsePropertyView(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Drop repeats, keeping the first position
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	var found []models.Property
+	err = s.DB.Model(&models.Property{}).
+		Where("properties.id IN ?", unique).
+		Scopes(propertyViewScope(view, "")).
+		Find(&found).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error retrieving properties: %w", err)
+	}
+
+	byID := make(map[uint]models.Property, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	properties := make([]models.Property, 0, len(found))
+	missing := []uint{}
+	for _, id := range unique {
+		if p, ok := byID[id]; ok {
+			properties = append(properties, p)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return properties, missing, nil
+}
+
 // GetAllProperties retrieves properties with pagination and filtering.
 func (s *PropertyService) GetAllProperties(pag schema.PaginationRequest, filter schema.PropertyFilter) ([]models.Property, int64, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	sortKeys, _ := parsePropertySort(filter) // Already checked by validatePropertyFilter
+	view, _ := ParsePropertyView(filter)
+
 	var properties []models.Property
 	var totalItems int64
 
@@ -186,12 +436,15 @@ func (s *PropertyService) GetAllProperties(pag schema.PaginationRequest, filter
 		return nil, 0, fmt.Errorf("failed to count properties: %w", err)
 	}
 
-	// Apply pagination and preloads
-	err = query.Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
-		Order("created_at DESC"). // Example ordering
-		Preload("Location").
-		Preload("Agent.User").
-		Preload("CoverPhoto").
+	// Newest first unless a sort is given
+	if len(sortKeys) > 0 {
+		query = applyPropertySort(query, sortKeys)
+	} else {
+		query = query.Order("created_at DESC")
+	}
+
+	// Apply pagination, and load only what the fields and include parameters ask for
+	err = query.Scopes(utils.PaginateScope(pag.Page, pag.PageSize), propertyViewScope(view, "")).
 		Find(&properties).Error
 
 	if err != nil {
@@ -201,33 +454,26 @@ func (s *PropertyService) GetAllProperties(pag schema.PaginationRequest, filter
 	return properties, totalItems, nil
 }
 
-// SearchProperties performs a simple text search across relevant fields.
-func (s *PropertyService) SearchProperties(searchTerm string, pag schema.PaginationRequest) ([]models.Property, int64, error) {
+// SearchProperties runs a full-text search using websearch syntax ("quoted phrases", OR, -exclude)
+// and orders matches by relevance. When nothing matches, it falls back to trigram similarity so
+// misspelt names still find something. An empty term lists every property, newest first.
+// The listing filters apply on top of the search.
+func (s *PropertyService) SearchProperties(searchTerm string, pag schema.PaginationRequest, filter schema.PropertyFilter) ([]models.Property, int64, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	sortKeys, _ := parsePropertySort(filter)
+	view, _ := ParsePropertyView(filter)
+
 	var properties []models.Property
 	var totalItems int64
 
 	// Base query
-	query := s.DB.Model(&models.Property{})
+	query := applyPropertyFilters(s.DB.Model(&models.Property{}), filter)
 
-	// Apply search condition (case-insensitive)
+	searchTerm = strings.TrimSpace(searchTerm)
 	if searchTerm != "" {
-		// Join with location to search location fields too
-		query = query.Joins("JOIN locations ON locations.property_id = properties.id").
-			Joins("LEFT JOIN agents ON agents.id = properties.agent_id"). // LEFT JOIN in case agent is null
-			Joins("LEFT JOIN users ON users.id = agents.user_id")         // LEFT JOIN for agent's user
-
-		// Use ILIKE for case-insensitive search in PostgreSQL
-		// Adjust fields as needed
-		searchPattern := "%" + searchTerm + "%"
-		query = query.Where(
-			s.DB.Where("properties.owner_name ILIKE ?", searchPattern).
-				Or("properties.description ILIKE ?", searchPattern).
-				Or("properties.property_design ILIKE ?", searchPattern).
-				Or("locations.district ILIKE ?", searchPattern).
-				Or("locations.area ILIKE ?", searchPattern).
-				Or("locations.sub_area ILIKE ?", searchPattern).
-				Or("users.name ILIKE ?", searchPattern), // Search by agent name
-		)
+		query = query.Scopes(fullTextScope(searchTerm))
 	}
 
 	// Count total matching items
@@ -237,12 +483,25 @@ func (s *PropertyService) SearchProperties(searchTerm string, pag schema.Paginat
 		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
 	}
 
-	// Apply pagination, ordering, and preloads to the original query
+	if searchTerm != "" && totalItems == 0 {
+		return s.fuzzySearchProperties(searchTerm, pag, filter)
+	}
+
+	// A requested sort comes first, relevance then only breaks ties
+	for _, key := range sortKeys {
+		query = query.Order(key.OrderSQL())
+	}
+	if searchTerm != "" {
+		query = query.Scopes(propertyViewScope(view, "ts_rank(properties.search_vector, "+searchQuerySQL+") AS search_rank", searchTerm)).
+			Order("search_rank DESC")
+	} else {
+		query = query.Scopes(propertyViewScope(view, ""))
+	}
+
+	// Apply pagination and ordering to the original query
 	err = query.Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
-		Order("properties.created_at DESC"). // Order by property creation date
-		Preload("Location").
-		Preload("Agent.User").
-		Preload("CoverPhoto").
+		Order("properties.created_at DESC"). // Newest first among equally relevant matches
+		Order("properties.id ASC").          // Then by ID, so pages don't shift between requests
 		Find(&properties).Error
 
 	if err != nil {
@@ -252,6 +511,435 @@ func (s *PropertyService) SearchProperties(searchTerm string, pag schema.Paginat
 	return properties, totalItems, nil
 }
 
+// fuzzySearchProperties matches the term against names and places by trigram word similarity.
+// It only runs when the full-text search found nothing.
+func (s *PropertyService) fuzzySearchProperties(searchTerm string, pag schema.PaginationRequest, filter schema.PropertyFilter) ([]models.Property, int64, error) {
+	var properties []models.Property
+	var totalItems int64
+
+	query := applyPropertyFilters(s.DB.Model(&models.Property{}), filter).Scopes(fuzzyScope(searchTerm))
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count fuzzy search results: %w", err)
+	}
+
+	sortKeys, _ := parsePropertySort(filter)
+	for _, key := range sortKeys {
+		query = query.Order(key.OrderSQL())
+	}
+
+	view, _ := ParsePropertyView(filter)
+	score, args := fuzzyScoreSQL(searchTerm)
+	err := query.Scopes(propertyViewScope(view, score+" AS search_rank", args...)).
+		Order("search_rank DESC").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Order("properties.created_at DESC").
+		Order("properties.id ASC").
+		Find(&properties).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve fuzzy search results: %w", err)
+	}
+	return properties, totalItems, nil
+}
+
+// fullTextScope limits a property query to full-text matches of the term.
+func fullTextScope(searchTerm string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("properties.search_vector @@ "+searchQuerySQL, searchTerm)
+	}
+}
+
+// fuzzyScope limits a property query to trigram matches of the term. It joins locations and
+// the agent's user, so it can't be combined with other joins on those tables.
+func fuzzyScope(searchTerm string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		score, args := fuzzyScoreSQL(searchTerm)
+		return db.Joins("LEFT JOIN locations ON locations.property_id = properties.id").
+			Joins("LEFT JOIN agents ON agents.id = properties.agent_id").
+			Joins("LEFT JOIN users ON users.id = agents.user_id").
+			Where(score+" >= ?", append(args, fuzzySearchThreshold)...)
+	}
+}
+
+// fuzzyScoreSQL is the best trigram word similarity of the term across the fuzzy-searched
+// fields, with one argument per field. word_similarity finds the best matching stretch of a
+// longer value, so "Blantire" still scores well against "Chilomoni, Blantyre".
+func fuzzyScoreSQL(searchTerm string) (string, []interface{}) {
+	parts := make([]string, 0, len(searchFields))
+	args := make([]interface{}, 0, len(searchFields))
+	for _, f := range searchFields {
+		if !f.fuzzy {
+			continue
+		}
+		parts = append(parts, "word_similarity(?, "+f.expr+")")
+		args = append(args, searchTerm)
+	}
+	return "greatest(" + strings.Join(parts, ", ") + ")", args
+}
+
+// SearchMatches returns the relevance rank and highlighted fragments of each property for a
+// search term. It is run on one page of results only, ts_headline is costly on large sets.
+// Properties found by the trigram fallback get their similarity as rank and the whole
+// matching field highlighted.
+func (s *PropertyService) SearchMatches(searchTerm string, propertyIDs []uint) (map[uint]schema.SearchMatch, error) {
+	searchTerm = strings.TrimSpace(searchTerm)
+	if searchTerm == "" || len(propertyIDs) == 0 {
+		return nil, nil
+	}
+
+	columns := []string{"properties.id", "properties.search_vector @@ q.query AS matched", "ts_rank(properties.search_vector, q.query) AS rank"}
+	var args []interface{}
+	for _, f := range searchFields {
+		columns = append(columns,
+			fmt.Sprintf("%s AS %s_text", f.expr, f.name),
+			fmt.Sprintf("ts_headline('%s', %s, q.query, '%s') AS %s_headline", searchLanguage, f.expr, searchHeadlineOptions, f.name))
+		if f.fuzzy {
+			columns = append(columns, fmt.Sprintf("word_similarity(?, %s) AS %s_similarity", f.expr, f.name))
+			args = append(args, searchTerm)
+		}
+	}
+	args = append(args, searchTerm, propertyIDs)
+
+	var rows []map[string]interface{}
+	err := s.DB.Raw(`SELECT `+strings.Join(columns, ", ")+`
+		FROM properties
+		CROSS JOIN (SELECT `+searchQuerySQL+` AS query) q
+		LEFT JOIN locations ON locations.property_id = properties.id
+		LEFT JOIN agents ON agents.id = properties.agent_id
+		LEFT JOIN users ON users.id = agents.user_id
+		WHERE properties.id IN ? AND ?`, append(args, tenantExpr(s.DB, "properties"))...).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to highlight search results: %w", err)
+	}
+
+	matches := make(map[uint]schema.SearchMatch, len(rows))
+	for _, r := range rows {
+		matched, _ := r["matched"].(bool)
+		match := schema.SearchMatch{Highlights: map[string]string{}, Fuzzy: !matched}
+		if matched {
+			match.Rank = roundTo(toFloat(r["rank"]), 6)
+		}
+		for _, f := range searchFields {
+			if matched {
+				// ts_headline returns the start of the text even when nothing matched, keep real hits only
+				if text, _ := r[f.name+"_headline"].(string); strings.Contains(text, searchHighlightStart) {
+					match.Highlights[f.name] = text
+				}
+				continue
+			}
+			if !f.fuzzy {
+				continue
+			}
+			similarity := toFloat(r[f.name+"_similarity"])
+			match.Rank = math.Max(match.Rank, roundTo(similarity, 6))
+			if text, _ := r[f.name+"_text"].(string); similarity >= fuzzySearchThreshold && text != "" {
+				match.Highlights[f.name] = searchHighlightStart + text + searchHighlightStop
+			}
+		}
+		matches[uint(toFloat(r["id"]))] = match
+	}
+	return matches, nil
+}
+
+// toFloat reads a numeric column scanned into a map, the driver type depends on the column.
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	}
+	return 0
+}
+
+// SuggestCompletions returns places, agents and designs that start with or closely resemble q,
+// with the number of listings behind each. Prefix matches come first.
+func (s *PropertyService) SuggestCompletions(q string, limit int) ([]schema.Suggestion, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return []schema.Suggestion{}, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	if limit > MaxSuggestLimit {
+		limit = MaxSuggestLimit
+	}
+
+	suggestions := []schema.Suggestion{}
+	err := s.DB.Raw(`SELECT kind, min(text) AS text, count(*) AS count, max(word_similarity(@q, text)) AS score,
+			bool_or(text ILIKE @prefix) AS prefix
+		FROM (
+			SELECT 'district' AS kind, trim(locations.district) AS text FROM locations
+				JOIN properties ON properties.id = locations.property_id WHERE @tenant
+			UNION ALL SELECT 'area', trim(locations.area) FROM locations
+				JOIN properties ON properties.id = locations.property_id WHERE @tenant
+			UNION ALL SELECT 'sub_area', trim(locations.sub_area) FROM locations
+				JOIN properties ON properties.id = locations.property_id WHERE @tenant
+			UNION ALL SELECT 'agent', trim(users.name) FROM properties
+				JOIN agents ON agents.id = properties.agent_id
+				JOIN users ON users.id = agents.user_id
+				WHERE @tenant
+			UNION ALL SELECT 'design', trim(properties.property_design) FROM properties WHERE @tenant
+		) candidates
+		WHERE text <> '' AND (text ILIKE @prefix OR word_similarity(@q, text) >= @threshold)
+		GROUP BY kind, lower(text)
+		ORDER BY prefix DESC, score DESC, count DESC, text
+		LIMIT @limit`, map[string]interface{}{
+		"q":         q,
+		"prefix":    escapeLike(q) + "%",
+		"threshold": fuzzySearchThreshold,
+		"limit":     limit,
+		"tenant":    tenantExpr(s.DB, "properties"), // Raw SQL, the tenant callbacks don't apply
+	}).Scan(&suggestions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search suggestions: %w", err)
+	}
+	for i := range suggestions {
+		suggestions[i].Score = roundTo(suggestions[i].Score, 4)
+	}
+	return suggestions, nil
+}
+
+// escapeLike stops % and _ typed by a user from acting as wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// RefreshSearchIndex rebuilds the full-text document of stored properties. By default only rows
+// that have never been indexed are touched, all=true redoes every row.
+func (s *PropertyService) RefreshSearchIndex(all bool) (*schema.SearchIndexResult, error) {
+	where := "pr.search_vector IS NULL"
+	if all {
+		where = "TRUE"
+	}
+	updated, err := refreshSearchVectors(s.DB, where)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.SearchIndexResult{Updated: updated}, nil
+}
+
+// refreshSearchVectors recomputes the weighted search document for the properties matching
+// where, written against the alias pr. Owner and location weigh most, then design and agent,
+// then the free-text description.
+func refreshSearchVectors(db *gorm.DB, where string, args ...interface{}) (int64, error) {
+	doc := func(expr, weight string) string {
+		return fmt.Sprintf("setweight(to_tsvector('%s', %s), '%s')", searchLanguage, expr, weight)
+	}
+	sql := `UPDATE properties SET search_vector = d.document
+		FROM (
+			SELECT pr.id, ` + strings.Join([]string{
+		doc("coalesce(pr.owner_name, '')", "A"),
+		doc("concat_ws(' ', l.district, l.area, l.sub_area)", "A"),
+		doc("concat_ws(' ', pr.property_design, u.name)", "B"),
+		doc("coalesce(pr.description, '')", "C"),
+	}, " || ") + ` AS document
+			FROM properties pr
+			LEFT JOIN locations l ON l.property_id = pr.id
+			LEFT JOIN agents a ON a.id = pr.agent_id
+			LEFT JOIN users u ON u.id = a.user_id
+			WHERE ` + where + `
+		) d
+		WHERE properties.id = d.id`
+	result := db.Exec(sql, args...)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to refresh search index: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// --- Attributes ---
+
+const (
+	attributesMatchAny = "any"
+	attributesMatchAll = "all"
+)
+
+// filterAttributeKeys returns the distinct attribute keys asked for in the filter.
+func filterAttributeKeys(filter schema.PropertyFilter) []string {
+	if filter.Attributes == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, part := range strings.Split(*filter.Attributes, ",") {
+		if key := models.AttributeKey(part); key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// attributeKeyJSON is a one-element JSON array for a containment test on attribute_names.
+func attributeKeyJSON(key string) string {
+	data, _ := json.Marshal([]string{key}) // A []string always marshals
+	return string(data)
+}
+
+// validatePropertyFilter rejects filter values applyPropertyFilters cannot honour.
+func validatePropertyFilter(filter schema.PropertyFilter) error {
+	if err := validateGeoFilter(filter); err != nil {
+		return err
+	}
+	if _, err := parsePropertySort(filter); err != nil {
+		return err
+	}
+	if filter.Filter != nil {
+		if _, _, err := compileFilterExpr(*filter.Filter); err != nil {
+			return err
+		}
+	}
+	if _, err := ParsePropertyView(filter); err != nil {
+		return err
+	}
+	if filter.AttributesMatch != nil {
+		switch strings.ToLower(strings.TrimSpace(*filter.AttributesMatch)) {
+		case "", attributesMatchAny, attributesMatchAll:
+		default:
+			return utils.NewBadRequestError(fmt.Sprintf("Invalid attributesMatch '%s', use any or all", *filter.AttributesMatch))
+		}
+	}
+	return nil
+}
+
+// GetAttributeCounts lists every attribute name used by the filtered properties, most common first.
+func (s *PropertyService) GetAttributeCounts(filter schema.PropertyFilter) ([]schema.AttributeCount, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, err
+	}
+	counts := []schema.AttributeCount{}
+	err := applyPropertyFilters(s.DB.Model(&models.Property{}), filter).
+		Joins("CROSS JOIN LATERAL jsonb_array_elements_text(properties.attribute_names) AS attribute(name)").
+		Where("jsonb_typeof(properties.attribute_names) = 'array'").
+		Select("attribute.name AS name, COUNT(*) AS count").
+		Group("attribute.name").
+		Order("count DESC, name").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attributes: %w", err)
+	}
+	return counts, nil
+}
+
+// --- Size Normalisation ---
+
+// normalisePropertySizes fills the square-metre and price-per-area columns from the raw sizes.
+// The original size and unit are kept as they are. Returns false if a size is in a unit we don't recognise.
+func normalisePropertySizes(p *models.Property) bool {
+	buildingSqm, buildingOK := utils.AreaToSqm(p.BuildingSize, p.BuildingSizeUnit)
+	landSqm, landOK := utils.AreaToSqm(p.LandSize, p.LandSizeUnit)
+
+	p.BuildingSizeSqm = buildingSqm
+	p.LandSizeSqm = landSqm
+	p.BuildingPricePerSqm = pricePerArea(p.Price, buildingSqm)
+	p.LandPricePerSqm = pricePerArea(p.Price, landSqm)
+
+	return buildingOK && landOK
+}
+
+func pricePerArea(price, sqm *float64) *float64 {
+	if price == nil || sqm == nil || *price <= 0 || *sqm <= 0 {
+		return nil
+	}
+	v := roundTo(*price / *sqm, 2)
+	return &v
+}
+
+// unitIssueCondition matches properties with a size that could not be converted to square metres.
+const unitIssueCondition = "(properties.building_size > 0 AND properties.building_size_sqm IS NULL) OR (properties.land_size > 0 AND properties.land_size_sqm IS NULL)"
+
+// NormaliseStoredSizes recomputes the square-metre columns for stored properties.
+// By default only rows still missing a conversion are touched, all=true redoes every row
+// (useful after new units are added to the conversion table).
+func (s *PropertyService) NormaliseStoredSizes(all bool) (*schema.NormaliseSizesResult, error) {
+	result := &schema.NormaliseSizesResult{}
+
+	query := s.DB.Model(&models.Property{}).
+		Select("id", "price", "building_size", "building_size_unit", "land_size", "land_size_unit",
+			"building_size_sqm", "land_size_sqm", "building_price_per_sqm", "land_price_per_sqm")
+	if !all {
+		query = query.Where(unitIssueCondition + " OR (properties.price > 0 AND properties.building_size_sqm > 0 AND properties.building_price_per_sqm IS NULL)")
+	}
+
+	var batch []models.Property
+	err := query.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			p := &batch[i]
+			result.Processed++
+			before := [4]*float64{p.BuildingSizeSqm, p.LandSizeSqm, p.BuildingPricePerSqm, p.LandPricePerSqm}
+			if !normalisePropertySizes(p) {
+				result.Unrecognised++
+			}
+			after := [4]*float64{p.BuildingSizeSqm, p.LandSizeSqm, p.BuildingPricePerSqm, p.LandPricePerSqm}
+			if sameFloatPtrs(before[:], after[:]) {
+				continue
+			}
+			err := s.DB.Model(&models.Property{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"building_size_sqm":      p.BuildingSizeSqm,
+				"land_size_sqm":          p.LandSizeSqm,
+				"building_price_per_sqm": p.BuildingPricePerSqm,
+				"land_price_per_sqm":     p.LandPricePerSqm,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update sizes for property %d: %w", p.ID, err)
+			}
+			result.Updated++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise property sizes: %w", err)
+	}
+	return result, nil
+}
+
+// GetUnitIssues lists properties whose building or land size unit could not be recognised.
+func (s *PropertyService) GetUnitIssues(pag schema.PaginationRequest) ([]schema.UnitIssue, int64, error) {
+	var properties []models.Property
+	var totalItems int64
+
+	query := s.DB.Model(&models.Property{}).Where(unitIssueCondition)
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count unit issues: %w", err)
+	}
+
+	err := query.Select("id", "building_size", "building_size_unit", "building_size_sqm", "land_size", "land_size_unit", "land_size_sqm").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Order("properties.id").
+		Find(&properties).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve unit issues: %w", err)
+	}
+
+	issues := []schema.UnitIssue{}
+	for _, p := range properties {
+		if p.BuildingSize != nil && *p.BuildingSize > 0 && p.BuildingSizeSqm == nil {
+			issues = append(issues, schema.UnitIssue{PropertyID: p.ID, Field: "building_size", Size: *p.BuildingSize, Unit: p.BuildingSizeUnit})
+		}
+		if p.LandSize != nil && *p.LandSize > 0 && p.LandSizeSqm == nil {
+			issues = append(issues, schema.UnitIssue{PropertyID: p.ID, Field: "land_size", Size: *p.LandSize, Unit: p.LandSizeUnit})
+		}
+	}
+	return issues, totalItems, nil
+}
+
+func sameFloatPtrs(a, b []*float64) bool {
+	for i := range a {
+		if (a[i] == nil) != (b[i] == nil) {
+			return false
+		}
+		if a[i] != nil && *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // applyPropertyFilters builds the WHERE clauses based on filter criteria.
 func applyPropertyFilters(query *gorm.DB, filter schema.PropertyFilter) *gorm.DB {
 	if filter.OwnerName != nil && *filter.OwnerName != "" {
@@ -275,19 +963,67 @@ func applyPropertyFilters(query *gorm.DB, filter schema.PropertyFilter) *gorm.DB
 	if filter.AgentID != nil {
 		query = query.Where("properties.agent_id = ?", *filter.AgentID)
 	}
+	if filter.Rooms != nil {
+		query = query.Where("properties.no_rooms = ?", *filter.Rooms)
+	}
+	if filter.MinBuildingSqm != nil {
+		query = query.Where("properties.building_size_sqm >= ?", *filter.MinBuildingSqm)
+	}
+	if filter.MaxBuildingSqm != nil {
+		query = query.Where("properties.building_size_sqm <= ?", *filter.MaxBuildingSqm)
+	}
+	if filter.MinLandSqm != nil {
+		query = query.Where("properties.land_size_sqm >= ?", *filter.MinLandSqm)
+	}
+	if filter.MaxLandSqm != nil {
+		query = query.Where("properties.land_size_sqm <= ?", *filter.MaxLandSqm)
+	}
 
-	// For location filters, we need to join the tables
-	needsLocationJoin := (filter.District != nil && *filter.District != "") || (filter.Area != nil && *filter.Area != "")
-	if needsLocationJoin {
-		query = query.Joins("JOIN locations ON locations.property_id = properties.id")
-		if filter.District != nil && *filter.District != "" {
-			query = query.Where("locations.district ILIKE ?", "%"+*filter.District+"%")
+	// Attributes are matched on the GIN-indexed keys, one containment test per name so
+	// "any" can still use the index
+	if keys := filterAttributeKeys(filter); len(keys) > 0 {
+		conditions := make([]string, len(keys))
+		args := make([]interface{}, len(keys))
+		for i, key := range keys {
+			conditions[i] = "properties.attribute_names @> ?::jsonb"
+			args[i] = attributeKeyJSON(key)
+		}
+		joiner := " OR "
+		if filter.AttributesMatch != nil && strings.EqualFold(strings.TrimSpace(*filter.AttributesMatch), attributesMatchAll) {
+			joiner = " AND "
 		}
-		if filter.Area != nil && *filter.Area != "" {
-			query = query.Where("locations.area ILIKE ?", "%"+*filter.Area+"%")
+		query = query.Where("("+strings.Join(conditions, joiner)+")", args...)
+	}
+
+	// The filter expression is checked by validatePropertyFilter, a caller that skipped it
+	// gets the parse error back from the query
+	if filter.Filter != nil {
+		condition, args, err := compileFilterExpr(*filter.Filter)
+		if err != nil {
+			query.AddError(err)
+		} else if condition != "" {
+			query = query.Where(condition, args...)
 		}
 	}
 
+	// Location filters use a subquery rather than a join so callers that already join
+	// locations (search, stats) can combine them with these filters
+	if filter.District != nil && *filter.District != "" {
+		query = query.Where("properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE ?)", "%"+*filter.District+"%")
+	}
+	if filter.Area != nil && *filter.Area != "" {
+		query = query.Where("properties.id IN (SELECT property_id FROM locations WHERE locations.area ILIKE ?)", "%"+*filter.Area+"%")
+	}
+	if filter.Lat != nil && filter.Lng != nil && filter.RadiusKm != nil {
+		// The bounding box lets the lat/lng indexes narrow the rows before the exact distance check
+		minLat, maxLat, minLng, maxLng := utils.BoundingBox(*filter.Lat, *filter.Lng, *filter.RadiusKm)
+		query = query.Where(
+			"properties.id IN (SELECT property_id FROM locations WHERE lat BETWEEN ? AND ? AND lng BETWEEN ? AND ? AND "+
+				haversineSQL("lat", "lng", *filter.Lat, *filter.Lng)+" <= ?)",
+			minLat, maxLat, minLng, maxLng, *filter.RadiusKm,
+		)
+	}
+
 	return query
 }
 
@@ -323,6 +1059,10 @@ func MapPropertyToResponse(p *models.Property) schema.PropertyResponse {
 		BuildingSizeUnit:             p.BuildingSizeUnit,
 		LandSize:                     p.LandSize,
 		LandSizeUnit:                 p.LandSizeUnit,
+		BuildingSizeSqm:              p.BuildingSizeSqm,
+		LandSizeSqm:                  p.LandSizeSqm,
+		BuildingPricePerSqm:          p.BuildingPricePerSqm,
+		LandPricePerSqm:              p.LandPricePerSqm,
 		EntryType:                    p.EntryType,
 		Price:                        p.Price,
 		ListingType:                  p.ListingType,
@@ -337,8 +1077,11 @@ func MapPropertyToResponse(p *models.Property) schema.PropertyResponse {
 		ApprovedAt:                   p.ApprovedAt,
 		Visibility:                   p.Visibility,
 		Views:                        p.Views,
-		// Embed associated data if it was preloaded
-		Location: &p.Location, // Embed directly if not null
+	}
+
+	// Embed associated data if it was preloaded
+	if p.Location.ID != 0 {
+		resp.Location = &p.Location
 	}
 
 	if p.Agent.ID != 0 { // Check if Agent was preloaded
@@ -370,6 +1113,17 @@ func MapPropertyToResponse(p *models.Property) schema.PropertyResponse {
 			Description: p.CoverPhoto.Description,
 		}
 	}
+
+	for _, issue := range p.QualityIssues {
+		resp.QualityIssues = append(resp.QualityIssues, schema.QualityIssueResponse{
+			Code:       issue.Code,
+			Kind:       issue.Kind,
+			Severity:   issue.Severity,
+			Field:      issue.Field,
+			Message:    issue.Message,
+			DetectedAt: issue.UpdatedAt,
+		})
+	}
 	return resp
 }
 
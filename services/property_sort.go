@@ -0,0 +1,181 @@
+// services/property_sort.go
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// Largest radius a geo search may ask for
+const maxGeoRadiusKm = 500.0
+
+// propertySortColumns maps the public sort names to the column they order by.
+// distance is not listed here, its expression depends on the search centre.
+var propertySortColumns = map[string]string{
+	"price":              "properties.price",
+	"price_per_sqm":      "properties.building_price_per_sqm",
+	"land_price_per_sqm": "properties.land_price_per_sqm",
+	"land_size":          "properties.land_size_sqm",
+	"building_size":      "properties.building_size_sqm",
+	"views":              "properties.views",
+	"created_at":         "properties.created_at",
+	"approved_at":        "properties.approved_at",
+	"age":                "properties.age",
+}
+
+const sortFieldDistance = "distance"
+
+// propertySortKey is one parsed entry of the sort parameter.
+type propertySortKey struct {
+	Field string
+	Expr  string
+	Desc  bool
+}
+
+// OrderSQL renders the key for ORDER BY. Missing values always go last.
+func (k propertySortKey) OrderSQL() string {
+	if k.Desc {
+		return k.Expr + " DESC NULLS LAST"
+	}
+	return k.Expr + " ASC NULLS LAST"
+}
+
+// sortFieldNames lists the accepted sort fields for error messages.
+func sortFieldNames() string {
+	names := make([]string, 0, len(propertySortColumns)+1)
+	for name := range propertySortColumns {
+		names = append(names, name)
+	}
+	names = append(names, sortFieldDistance)
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parsePropertySort reads the comma separated sort parameter. Each key is a field name,
+// descending when prefixed with "-" or suffixed with ":desc", e.g. "-price,created_at"
+// or "price:desc,created_at:asc". An empty parameter returns no keys.
+func parsePropertySort(filter schema.PropertyFilter) ([]propertySortKey, error) {
+	if filter.Sort == nil || strings.TrimSpace(*filter.Sort) == "" {
+		return nil, nil
+	}
+
+	var keys []propertySortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(*filter.Sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		desc := false
+		if strings.HasPrefix(part, "-") {
+			desc = true
+			part = strings.TrimSpace(part[1:])
+		} else if strings.HasPrefix(part, "+") {
+			part = strings.TrimSpace(part[1:])
+		}
+		if field, dir, ok := strings.Cut(part, ":"); ok {
+			switch strings.ToLower(strings.TrimSpace(dir)) {
+			case "asc":
+			case "desc":
+				desc = true
+			default:
+				return nil, utils.NewBadRequestError(fmt.Sprintf("Invalid sort direction '%s' for %s, use asc or desc", dir, field))
+			}
+			part = strings.TrimSpace(field)
+		}
+		field := strings.ToLower(part)
+
+		if seen[field] {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("Sort field '%s' is given more than once", field))
+		}
+		seen[field] = true
+
+		if field == sortFieldDistance {
+			if filter.Lat == nil || filter.Lng == nil {
+				return nil, utils.NewBadRequestError("Sorting by distance needs lat and lng")
+			}
+			keys = append(keys, propertySortKey{Field: field, Expr: distanceSQL(*filter.Lat, *filter.Lng), Desc: desc})
+			continue
+		}
+		column, ok := propertySortColumns[field]
+		if !ok {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("Unknown sort field '%s', use one of: %s", part, sortFieldNames()))
+		}
+		keys = append(keys, propertySortKey{Field: field, Expr: column, Desc: desc})
+	}
+	return keys, nil
+}
+
+// applyPropertySort orders the query by the parsed keys, with the id as a final tie-break
+// so pages stay stable when sorted values repeat.
+func applyPropertySort(query *gorm.DB, keys []propertySortKey) *gorm.DB {
+	for _, key := range keys {
+		query = query.Order(key.OrderSQL())
+	}
+	return query.Order("properties.id DESC")
+}
+
+// validateGeoFilter checks the lat, lng and radiusKm parameters belong together and are in range.
+func validateGeoFilter(filter schema.PropertyFilter) error {
+	if (filter.Lat == nil) != (filter.Lng == nil) {
+		return utils.NewBadRequestError("lat and lng must be given together")
+	}
+	if filter.Lat != nil {
+		if *filter.Lat < -90 || *filter.Lat > 90 {
+			return utils.NewBadRequestError("lat must be between -90 and 90")
+		}
+		if *filter.Lng < -180 || *filter.Lng > 180 {
+			return utils.NewBadRequestError("lng must be between -180 and 180")
+		}
+	}
+	if filter.RadiusKm != nil {
+		if filter.Lat == nil {
+			return utils.NewBadRequestError("radiusKm needs lat and lng")
+		}
+		if *filter.RadiusKm <= 0 || *filter.RadiusKm > maxGeoRadiusKm {
+			return utils.NewBadRequestError(fmt.Sprintf("radiusKm must be greater than 0 and at most %g", maxGeoRadiusKm))
+		}
+	}
+	return nil
+}
+
+// haversineSQL is the great-circle distance in km from the given point to the lat/lng columns.
+// The coordinates are validated numbers, so they are written into the SQL directly, which
+// lets the expression be used in ORDER BY.
+func haversineSQL(latColumn, lngColumn string, lat, lng float64) string {
+	latStr := "(" + strconv.FormatFloat(lat, 'f', -1, 64) + ")" // Parenthesised so a negative value never reads as "--"
+	lngStr := "(" + strconv.FormatFloat(lng, 'f', -1, 64) + ")"
+	return fmt.Sprintf(
+		"(2 * 6371 * asin(sqrt(power(sin(radians(%[1]s - %[3]s) / 2), 2) + cos(radians(%[3]s)) * cos(radians(%[1]s)) * power(sin(radians(%[2]s - %[4]s) / 2), 2))))",
+		latColumn, lngColumn, latStr, lngStr,
+	)
+}
+
+// distanceSQL is the distance from the point to a property's location, NULL without coordinates.
+func distanceSQL(lat, lng float64) string {
+	return "(SELECT " + haversineSQL("l.lat", "l.lng", lat, lng) +
+		" FROM locations l WHERE l.property_id = properties.id AND l.lat IS NOT NULL AND l.lng IS NOT NULL LIMIT 1)"
+}
+
+// AttachDistances sets the distance from the filter's centre on each response. Nothing is
+// set when the filter has no centre or a property has no coordinates.
+func AttachDistances(responses []schema.PropertyResponse, filter schema.PropertyFilter) {
+	if filter.Lat == nil || filter.Lng == nil {
+		return
+	}
+	for i := range responses {
+		loc := responses[i].Location
+		if loc == nil || loc.Lat == nil || loc.Lng == nil {
+			continue
+		}
+		d := utils.HaversineKm(*filter.Lat, *filter.Lng, *loc.Lat, *loc.Lng)
+		responses[i].DistanceKm = &d
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropertySort(t *testing.T) {
+	tests := []struct {
+		name string
+		sort *string
+		want []propertySortKey
+	}{
+		{"no parameter", nil, nil},
+		{"blank", strPtr("  "), nil},
+		{"ascending by default", strPtr("price"), []propertySortKey{{Field: "price", Expr: "properties.price"}}},
+		{"minus prefix", strPtr("-price"), []propertySortKey{{Field: "price", Expr: "properties.price", Desc: true}}},
+		{"plus prefix", strPtr("+views"), []propertySortKey{{Field: "views", Expr: "properties.views"}}},
+		{"direction suffix", strPtr("price:desc, created_at:ASC"), []propertySortKey{
+			{Field: "price", Expr: "properties.price", Desc: true},
+			{Field: "created_at", Expr: "properties.created_at"},
+		}},
+		{"case and empty entries", strPtr("Land_Size,,-AGE"), []propertySortKey{
+			{Field: "land_size", Expr: "properties.land_size_sqm"},
+			{Field: "age", Expr: "properties.age", Desc: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parsePropertySort(schema.PropertyFilter{Sort: tt.sort})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
+
+func TestParsePropertySort_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter schema.PropertyFilter
+	}{
+		{"unknown field", schema.PropertyFilter{Sort: strPtr("bedrooms")}},
+		{"bad direction", schema.PropertyFilter{Sort: strPtr("price:up")}},
+		{"repeated field", schema.PropertyFilter{Sort: strPtr("price,-price")}},
+		{"distance without a centre", schema.PropertyFilter{Sort: strPtr("distance")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePropertySort(tt.filter)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePropertySort_Distance(t *testing.T) {
+	keys, err := parsePropertySort(schema.PropertyFilter{Sort: strPtr("distance"), Lat: floatPtr(-13.96), Lng: floatPtr(33.78)})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, sortFieldDistance, keys[0].Field)
+	assert.Contains(t, keys[0].Expr, "(-13.96)")
+	assert.Contains(t, keys[0].Expr, "l.property_id = properties.id")
+}
+
+func TestPropertySortKey_OrderSQL(t *testing.T) {
+	assert.Equal(t, "properties.price ASC NULLS LAST", propertySortKey{Expr: "properties.price"}.OrderSQL())
+	assert.Equal(t, "properties.price DESC NULLS LAST", propertySortKey{Expr: "properties.price", Desc: true}.OrderSQL())
+}
+
+func TestValidateGeoFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  schema.PropertyFilter
+		wantErr bool
+	}{
+		{"none", schema.PropertyFilter{}, false},
+		{"centre and radius", schema.PropertyFilter{Lat: floatPtr(-15.78), Lng: floatPtr(35.0), RadiusKm: floatPtr(5)}, false},
+		{"lat alone", schema.PropertyFilter{Lat: floatPtr(-15.78)}, true},
+		{"lat out of range", schema.PropertyFilter{Lat: floatPtr(91), Lng: floatPtr(0)}, true},
+		{"lng out of range", schema.PropertyFilter{Lat: floatPtr(0), Lng: floatPtr(-181)}, true},
+		{"radius without centre", schema.PropertyFilter{RadiusKm: floatPtr(5)}, true},
+		{"radius too large", schema.PropertyFilter{Lat: floatPtr(0), Lng: floatPtr(0), RadiusKm: floatPtr(maxGeoRadiusKm + 1)}, true},
+		{"zero radius", schema.PropertyFilter{Lat: floatPtr(0), Lng: floatPtr(0), RadiusKm: floatPtr(0)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGeoFilter(tt.filter)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
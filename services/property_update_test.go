@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectPropertyReload is GetPropertyByID on a listing with no agent.
+func expectPropertyReload(mock sqlmock.Sqlmock, id uint) {
+	mock.ExpectQuery(`SELECT \* FROM "properties"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectQuery(`SELECT \* FROM "cover_photos"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "locations"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "quality_issues"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestUpdateProperty_RecomputesSizesAndClearsAgent(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "properties" WHERE "properties"."id" = \$1`).
+		WithArgs(99, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "price", "building_size", "building_size_unit", "agent_id"}).
+			AddRow(99, 150000000, 200, "sqm", 4))
+	// The price moves the price per sqm with it, sizes left out keep their stored value
+	mock.ExpectExec(`UPDATE "properties" SET "agent_id"=\$1,"building_price_per_sqm"=\$2,"building_size"=\$3,"building_size_sqm"=\$4,"building_size_unit"=\$5,`+
+		`"land_price_per_sqm"=\$6,"land_size"=\$7,"land_size_sqm"=\$8,"land_size_unit"=\$9,"price"=\$10,"updated_at"=\$11 WHERE "id" = \$12`).
+		WithArgs(nil, 925000.0, 200.0, 200.0, "sqm", nil, nil, nil, "", 185000000.0, sqlmock.AnyArg(), 99).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE properties SET search_vector`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPropertyReload(mock, 99)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "quality_issues"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	for range outlierMetrics {
+		mock.ExpectQuery(`WITH grouped AS`).WillReturnRows(sqlmock.NewRows([]string{"property_id"}))
+	}
+	mock.ExpectExec(`DELETE FROM "quality_issues"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectPropertyReload(mock, 99)
+
+	_, err := NewPropertyService(db).UpdateProperty(99, &schema.UpdatePropertyRequest{Price: floatPtr(185000000), AgentID: uintPtr(0)})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateProperty_Missing(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT \* FROM "properties"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := NewPropertyService(db).UpdateProperty(99, &schema.UpdatePropertyRequest{Description: strPtr("Freshly painted")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Property not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
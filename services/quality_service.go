@@ -0,0 +1,381 @@
+// services/quality_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Defaults used when the quality section is missing from config.yaml
+var defaultQualityConfig = config.QualityConfig{
+	IQRMultiplier: 3,
+	MinGroupSize:  8,
+}
+
+// Sizes outside these bounds are almost always a unit mix-up
+const (
+	minPlausibleBuildingSqm = 10
+	maxPlausibleBuildingSqm = 100000
+	maxPlausibleRooms       = 50
+	maxPlausibleBathrooms   = 30
+)
+
+// outlierMetrics are the values compared against similar listings. Columns are fixed
+// here, never taken from a request.
+var outlierMetrics = []struct {
+	code   string
+	column string
+	label  string
+}{
+	{"price_per_sqm_outlier", "properties.building_price_per_sqm", "Price per sqm"},
+	{"land_price_per_sqm_outlier", "properties.land_price_per_sqm", "Land price per sqm"},
+	{"price_outlier", "properties.price", "Price"},
+}
+
+type QualityService struct {
+	DB *gorm.DB
+}
+
+func NewQualityService(db *gorm.DB) *QualityService {
+	return &QualityService{DB: db}
+}
+
+func (s *QualityService) WithContext(ctx context.Context) *QualityService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+func qualitySettings() config.QualityConfig {
+	settings := defaultQualityConfig
+	if config.Cfg == nil {
+		return settings
+	}
+	if config.Cfg.Quality.IQRMultiplier > 0 {
+		settings.IQRMultiplier = config.Cfg.Quality.IQRMultiplier
+	}
+	if config.Cfg.Quality.MinGroupSize > 0 {
+		settings.MinGroupSize = config.Cfg.Quality.MinGroupSize
+	}
+	return settings
+}
+
+func ruleIssue(p *models.Property, code, severity, field, message string) models.QualityIssue {
+	return models.QualityIssue{
+		PropertyID: p.ID,
+		Code:       code,
+		Kind:       models.QualityKindRule,
+		Severity:   severity,
+		Field:      field,
+		Message:    message,
+	}
+}
+
+// qualityRuleIssues checks a property's fields against each other. It needs no other
+// listings, so it runs on every create and sync.
+func qualityRuleIssues(p *models.Property, now time.Time) []models.QualityIssue {
+	var issues []models.QualityIssue
+
+	if p.Price != nil && *p.Price <= 0 {
+		issues = append(issues, ruleIssue(p, "non_positive_price", models.QualitySeverityError, "price", fmt.Sprintf("Price is %.2f", *p.Price)))
+	}
+
+	for _, v := range []struct {
+		field string
+		value *int
+	}{{"age", p.Age}, {"eul", p.Eul}, {"rel", p.Rel}} {
+		if v.value != nil && *v.value < 0 {
+			issues = append(issues, ruleIssue(p, "negative_"+v.field, models.QualitySeverityError, v.field, fmt.Sprintf("%s is negative (%d)", v.field, *v.value)))
+		}
+	}
+	if p.Age != nil && p.Eul != nil && *p.Eul > 0 && *p.Age > *p.Eul {
+		issues = append(issues, ruleIssue(p, "age_exceeds_eul", models.QualitySeverityError, "age",
+			fmt.Sprintf("Age %d is greater than the estimated useful life %d", *p.Age, *p.Eul)))
+	}
+	if p.Rel != nil && p.Eul != nil && *p.Eul > 0 && *p.Rel > *p.Eul {
+		issues = append(issues, ruleIssue(p, "rel_exceeds_eul", models.QualitySeverityError, "rel",
+			fmt.Sprintf("Remaining economic life %d is greater than the estimated useful life %d", *p.Rel, *p.Eul)))
+	}
+
+	if p.YearBuilt != nil && strings.TrimSpace(*p.YearBuilt) != "" {
+		if year, err := strconv.Atoi(strings.TrimSpace(*p.YearBuilt)); err == nil {
+			switch {
+			case year > now.Year():
+				issues = append(issues, ruleIssue(p, "year_built_in_future", models.QualitySeverityError, "year_built", fmt.Sprintf("Year built %d is in the future", year)))
+			case p.Age != nil && absInt(now.Year()-year-*p.Age) > 2:
+				issues = append(issues, ruleIssue(p, "age_year_built_mismatch", models.QualitySeverityWarning, "age",
+					fmt.Sprintf("Age %d does not match year built %d (%d years ago)", *p.Age, year, now.Year()-year)))
+			}
+		}
+	}
+
+	if p.BuildingSize != nil && *p.BuildingSize > 0 && p.BuildingSizeSqm == nil {
+		issues = append(issues, ruleIssue(p, "unrecognised_building_unit", models.QualitySeverityWarning, "building_size_unit",
+			fmt.Sprintf("Building size unit '%s' could not be converted to sqm", p.BuildingSizeUnit)))
+	}
+	if p.LandSize != nil && *p.LandSize > 0 && p.LandSizeSqm == nil {
+		issues = append(issues, ruleIssue(p, "unrecognised_land_unit", models.QualitySeverityWarning, "land_size_unit",
+			fmt.Sprintf("Land size unit '%s' could not be converted to sqm", p.LandSizeUnit)))
+	}
+	if p.BuildingSizeSqm != nil && (*p.BuildingSizeSqm < minPlausibleBuildingSqm || *p.BuildingSizeSqm > maxPlausibleBuildingSqm) {
+		issues = append(issues, ruleIssue(p, "implausible_building_size", models.QualitySeverityWarning, "building_size",
+			fmt.Sprintf("Building size of %.1f sqm is implausible, check the unit (%s)", *p.BuildingSizeSqm, p.BuildingSizeUnit)))
+	}
+	// Multi-storey buildings can exceed their plot, but not by this much
+	if p.BuildingSizeSqm != nil && p.LandSizeSqm != nil && *p.LandSizeSqm > 0 && *p.BuildingSizeSqm > *p.LandSizeSqm*5 {
+		issues = append(issues, ruleIssue(p, "building_larger_than_land", models.QualitySeverityWarning, "building_size",
+			fmt.Sprintf("Building size %.1f sqm is more than five times the land size %.1f sqm", *p.BuildingSizeSqm, *p.LandSizeSqm)))
+	}
+
+	if p.NoRooms != nil && (*p.NoRooms < 0 || *p.NoRooms > maxPlausibleRooms) {
+		issues = append(issues, ruleIssue(p, "implausible_rooms", models.QualitySeverityWarning, "no_rooms", fmt.Sprintf("%d rooms is implausible", *p.NoRooms)))
+	}
+	if p.NoOfBathrooms != nil && (*p.NoOfBathrooms < 0 || *p.NoOfBathrooms > maxPlausibleBathrooms) {
+		issues = append(issues, ruleIssue(p, "implausible_bathrooms", models.QualitySeverityWarning, "no_of_bathrooms", fmt.Sprintf("%d bathrooms is implausible", *p.NoOfBathrooms)))
+	}
+
+	loc := p.Location
+	if (loc.Latitude != nil && strings.TrimSpace(*loc.Latitude) != "" || loc.Longitude != nil && strings.TrimSpace(*loc.Longitude) != "") && loc.Lat == nil {
+		issues = append(issues, ruleIssue(p, "invalid_coordinates", models.QualitySeverityWarning, "location",
+			fmt.Sprintf("Coordinates '%s, %s' are not a usable position", derefString(loc.Latitude), derefString(loc.Longitude))))
+	}
+
+	return issues
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// replaceQualityIssues swaps the issues of one kind on the given properties for a new set.
+func replaceQualityIssues(tx *gorm.DB, kind string, propertyIDs []uint, issues []models.QualityIssue) error {
+	query := tx.Where("kind = ?", kind)
+	if propertyIDs != nil {
+		if len(propertyIDs) == 0 {
+			return nil
+		}
+		query = query.Where("property_id IN ?", propertyIDs)
+	}
+	if err := query.Delete(&models.QualityIssue{}).Error; err != nil {
+		return fmt.Errorf("failed to clear %s quality issues: %w", kind, err)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	if err := tx.CreateInBatches(issues, 500).Error; err != nil {
+		return fmt.Errorf("failed to save %s quality issues: %w", kind, err)
+	}
+	return nil
+}
+
+// saveRuleIssues re-runs the rule checks for one property. p.Location should be set so
+// the coordinate check can run.
+func saveRuleIssues(tx *gorm.DB, p *models.Property) error {
+	return replaceQualityIssues(tx, models.QualityKindRule, []uint{p.ID}, qualityRuleIssues(p, time.Now()))
+}
+
+type outlierRow struct {
+	PropertyID   uint
+	Value        float64
+	Q1           float64
+	Q3           float64
+	GroupSize    int64
+	District     string
+	PropertyType string
+	ListingType  string
+}
+
+// findOutliers compares listings with others of the same district, property type and
+// listing type using the interquartile range. The bounds come from the listings the tenant on
+// db can see, only picks the listings to report, as a condition on the grouped rows.
+func findOutliers(db *gorm.DB, settings config.QualityConfig, only clause.Expr) ([]models.QualityIssue, error) {
+	var issues []models.QualityIssue
+	for _, metric := range outlierMetrics {
+		sql := fmt.Sprintf(`WITH grouped AS (
+			SELECT properties.id, properties.financial_institution_id, UPPER(TRIM(locations.district)) AS district, COALESCE(properties.property_type, '') AS property_type,
+				properties.listing_type, %s AS value
+			FROM properties JOIN locations ON locations.property_id = properties.id
+			WHERE %s > 0 AND TRIM(locations.district) <> '' AND ?
+		), bounds AS (
+			SELECT district, property_type, listing_type, COUNT(*) AS group_size,
+				percentile_cont(0.25) WITHIN GROUP (ORDER BY value) AS q1,
+				percentile_cont(0.75) WITHIN GROUP (ORDER BY value) AS q3
+			FROM grouped
+			GROUP BY district, property_type, listing_type
+			HAVING COUNT(*) >= ?
+		)
+		SELECT grouped.id AS property_id, grouped.value, bounds.q1, bounds.q3, bounds.group_size,
+			grouped.district, grouped.property_type, grouped.listing_type
+		FROM grouped JOIN bounds USING (district, property_type, listing_type)
+		WHERE (grouped.value < bounds.q1 - ? * (bounds.q3 - bounds.q1) OR grouped.value > bounds.q3 + ? * (bounds.q3 - bounds.q1))
+			AND ?`,
+			metric.column, metric.column)
+		// Raw SQL, the tenant callbacks don't apply. A bank's listings are only compared with
+		// what it can see, so other banks' private prices never shape its bounds.
+		args := []interface{}{tenantExpr(db, "properties"), settings.MinGroupSize, settings.IQRMultiplier, settings.IQRMultiplier, only}
+
+		var rows []outlierRow
+		if err := db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to scan %s outliers: %w", metric.code, err)
+		}
+		for _, r := range rows {
+			iqr := r.Q3 - r.Q1
+			low := r.Q1 - settings.IQRMultiplier*iqr
+			high := r.Q3 + settings.IQRMultiplier*iqr
+			direction := "above"
+			if r.Value < low {
+				direction = "below"
+			}
+			issues = append(issues, models.QualityIssue{
+				PropertyID: r.PropertyID,
+				Code:       metric.code,
+				Kind:       models.QualityKindOutlier,
+				Severity:   models.QualitySeverityWarning,
+				Field:      strings.TrimPrefix(metric.column, "properties."),
+				Message: fmt.Sprintf("%s %.2f is %s the usual range %.2f to %.2f for %d %s %s listings in %s",
+					metric.label, r.Value, direction, low, high, r.GroupSize, r.ListingType, r.PropertyType, r.District),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// asInstitution scopes db to what institutionID can see, the shared listings alone when nil.
+// Outlier bounds are computed this way so the message on a listing only reflects prices its
+// readers could see anyway.
+func asInstitution(db *gorm.DB, institutionID *uint) *gorm.DB {
+	return db.WithContext(WithTenant(db.Statement.Context, Tenant{InstitutionID: institutionID}))
+}
+
+// checkPropertyQuality runs the rule checks and the outlier checks for a single property.
+func checkPropertyQuality(db *gorm.DB, p *models.Property) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := saveRuleIssues(tx, p); err != nil {
+			return err
+		}
+		outliers, err := findOutliers(asInstitution(tx, p.FinancialInstitutionID), qualitySettings(), clause.Expr{SQL: "grouped.id = ?", Vars: []interface{}{p.ID}})
+		if err != nil {
+			return err
+		}
+		return replaceQualityIssues(tx, models.QualityKindOutlier, []uint{p.ID}, outliers)
+	})
+}
+
+// ScanOutliers replaces every outlier issue with a fresh scan of all listings. Shared listings
+// are compared with the shared ones only, each bank's own listings with the shared ones and
+// its own.
+func (s *QualityService) ScanOutliers() (int, error) {
+	settings := qualitySettings()
+	outliers, err := findOutliers(asInstitution(s.DB, nil), settings, clause.Expr{SQL: "grouped.financial_institution_id IS NULL"})
+	if err != nil {
+		return 0, err
+	}
+
+	var institutionIDs []uint
+	err = allTenants(s.DB).Model(&models.Property{}).
+		Where("financial_institution_id IS NOT NULL").
+		Distinct().Order("financial_institution_id").
+		Pluck("financial_institution_id", &institutionIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list institutions with listings: %w", err)
+	}
+	for i := range institutionIDs {
+		own, err := findOutliers(asInstitution(s.DB, &institutionIDs[i]), settings,
+			clause.Expr{SQL: "grouped.financial_institution_id = ?", Vars: []interface{}{institutionIDs[i]}})
+		if err != nil {
+			return 0, err
+		}
+		outliers = append(outliers, own...)
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceQualityIssues(tx, models.QualityKindOutlier, nil, outliers)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(outliers), nil
+}
+
+// RunChecks re-runs the rule checks on every property and then rescans for outliers.
+func (s *QualityService) RunChecks() (*schema.QualityScanResult, error) {
+	result := &schema.QualityScanResult{}
+	now := time.Now()
+
+	var batch []models.Property
+	err := s.DB.Preload("Location").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		ids := make([]uint, len(batch))
+		var issues []models.QualityIssue
+		for i := range batch {
+			ids[i] = batch[i].ID
+			issues = append(issues, qualityRuleIssues(&batch[i], now)...)
+		}
+		result.Properties += len(batch)
+		result.RuleIssues += len(issues)
+		return s.DB.Transaction(func(inner *gorm.DB) error {
+			return replaceQualityIssues(inner, models.QualityKindRule, ids, issues)
+		})
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rule checks: %w", err)
+	}
+
+	result.OutlierIssues, err = s.ScanOutliers()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Quality checks finished: %d properties, %d rule issues, %d outliers.\n", result.Properties, result.RuleIssues, result.OutlierIssues)
+	return result, nil
+}
+
+// GetIssues lists quality issues with pagination and filtering, errors first.
+func (s *QualityService) GetIssues(pag schema.PaginationRequest, filter schema.QualityIssueFilter) ([]models.QualityIssue, int64, error) {
+	var issues []models.QualityIssue
+	var totalItems int64
+
+	query := s.DB.Model(&models.QualityIssue{})
+	if isTenantScoped(s.DB) {
+		query = query.Where("quality_issues.property_id IN (?)", visiblePropertyIDs(s.DB))
+	}
+	if filter.PropertyID != nil {
+		query = query.Where("quality_issues.property_id = ?", *filter.PropertyID)
+	}
+	if filter.Code != nil && *filter.Code != "" {
+		query = query.Where("quality_issues.code = ?", *filter.Code)
+	}
+	if filter.Kind != nil && *filter.Kind != "" {
+		query = query.Where("quality_issues.kind = ?", *filter.Kind)
+	}
+	if filter.Severity != nil && *filter.Severity != "" {
+		query = query.Where("quality_issues.severity = ?", *filter.Severity)
+	}
+	if filter.District != nil && *filter.District != "" {
+		query = query.Where("quality_issues.property_id IN (SELECT property_id FROM locations WHERE locations.district ILIKE ?)", "%"+*filter.District+"%")
+	}
+
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count quality issues: %w", err)
+	}
+
+	err := query.Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Order("CASE WHEN quality_issues.severity = 'error' THEN 0 ELSE 1 END, quality_issues.updated_at DESC, quality_issues.id DESC").
+		Find(&issues).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve quality issues: %w", err)
+	}
+	return issues, totalItems, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func strPtr(v string) *string { return &v }
+
+func TestQualityRuleIssues(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		property models.Property
+		want     []string
+	}{
+		{name: "clean listing", property: models.Property{
+			Price: floatPtr(1000), Age: intPtr(10), Eul: intPtr(50), Rel: intPtr(40), YearBuilt: strPtr("2015"),
+			BuildingSize: floatPtr(200), BuildingSizeSqm: floatPtr(200), LandSizeSqm: floatPtr(800), NoRooms: intPtr(4),
+		}},
+		{name: "non-positive price", property: models.Property{Price: floatPtr(0)}, want: []string{"non_positive_price"}},
+		{name: "negative lives", property: models.Property{Age: intPtr(-1), Rel: intPtr(-2)}, want: []string{"negative_age", "negative_rel"}},
+		{name: "age and rel past eul", property: models.Property{Age: intPtr(60), Eul: intPtr(50), Rel: intPtr(55)}, want: []string{"age_exceeds_eul", "rel_exceeds_eul"}},
+		{name: "zero eul is not compared", property: models.Property{Age: intPtr(60), Eul: intPtr(0)}},
+		{name: "built in the future", property: models.Property{YearBuilt: strPtr(" 2030 ")}, want: []string{"year_built_in_future"}},
+		{name: "age within two years of year built", property: models.Property{YearBuilt: strPtr("2015"), Age: intPtr(8)}},
+		{name: "age off from year built", property: models.Property{YearBuilt: strPtr("2015"), Age: intPtr(3)}, want: []string{"age_year_built_mismatch"}},
+		{name: "year built not a number", property: models.Property{YearBuilt: strPtr("circa 1990"), Age: intPtr(3)}},
+		{name: "unknown units", property: models.Property{BuildingSize: floatPtr(2), BuildingSizeUnit: "plots", LandSize: floatPtr(1), LandSizeUnit: "lima"},
+			want: []string{"unrecognised_building_unit", "unrecognised_land_unit"}},
+		{name: "implausibly small building", property: models.Property{BuildingSizeSqm: floatPtr(2)}, want: []string{"implausible_building_size"}},
+		{name: "building five times the land", property: models.Property{BuildingSizeSqm: floatPtr(600), LandSizeSqm: floatPtr(100)}, want: []string{"building_larger_than_land"}},
+		{name: "implausible counts", property: models.Property{NoRooms: intPtr(80), NoOfBathrooms: intPtr(-1)}, want: []string{"implausible_rooms", "implausible_bathrooms"}},
+		{name: "unparsed coordinates", property: models.Property{Location: models.Location{Latitude: strPtr("13°57'S"), Longitude: strPtr("")}},
+			want: []string{"invalid_coordinates"}},
+		{name: "blank coordinates", property: models.Property{Location: models.Location{Latitude: strPtr(" "), Longitude: strPtr("")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.property.ID = 5
+			var codes []string
+			for _, issue := range qualityRuleIssues(&tt.property, now) {
+				codes = append(codes, issue.Code)
+				assert.Equal(t, uint(5), issue.PropertyID)
+				assert.Equal(t, models.QualityKindRule, issue.Kind)
+				assert.NotEmpty(t, issue.Message)
+			}
+			assert.Equal(t, tt.want, codes)
+		})
+	}
+}
+
+func TestFindOutliers_IQRBounds(t *testing.T) {
+	db, mock := setupMockDB(t)
+	settings := config.QualityConfig{MinGroupSize: 8, IQRMultiplier: 1.5}
+	columns := []string{"property_id", "value", "q1", "q3", "group_size", "district", "property_type", "listing_type"}
+
+	// Only the price per sqm query finds anything, one listing either side of the range
+	for i, metric := range outlierMetrics {
+		rows := sqlmock.NewRows(columns)
+		if i == 0 {
+			rows.AddRow(1, 100, 1000, 1200, 12, "LILONGWE", "House", "Sale").
+				AddRow(2, 9000, 1000, 1200, 12, "LILONGWE", "House", "Sale")
+		}
+		mock.ExpectQuery(`(?s)`+metric.column+` AS value.*WHERE `+metric.column+` > 0 .*`+
+			`GROUP BY district, property_type, listing_type\s+HAVING COUNT\(\*\) >= \$1.*`+
+			`WHERE \(grouped.value < bounds.q1 - \$2 \* \(bounds.q3 - bounds.q1\) OR grouped.value > bounds.q3 \+ \$3 \* \(bounds.q3 - bounds.q1\)\)\s+AND grouped.id = \$4`).
+			WithArgs(8, 1.5, 1.5, 3).
+			WillReturnRows(rows)
+	}
+
+	issues, err := findOutliers(db, settings, clause.Expr{SQL: "grouped.id = ?", Vars: []interface{}{3}})
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "price_per_sqm_outlier", issues[0].Code)
+	assert.Equal(t, "building_price_per_sqm", issues[0].Field)
+	assert.Equal(t, models.QualityKindOutlier, issues[0].Kind)
+	// An IQR of 200, so the usual range is 700 to 1500
+	assert.Equal(t, "Price per sqm 100.00 is below the usual range 700.00 to 1500.00 for 12 Sale House listings in LILONGWE", issues[0].Message)
+	assert.Contains(t, issues[1].Message, "9000.00 is above the usual range")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
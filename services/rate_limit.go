@@ -0,0 +1,81 @@
+// services/rate_limit.go
+package services
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking a token, with what the X-RateLimit-* headers report.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // Bucket size
+	Remaining  int           // Whole tokens left after this request
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, only set when not allowed
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucketLimiter keeps one bucket per key in memory. Each bucket holds up to burst tokens
+// and refills at ratePerMinute, a request takes one token. Buckets are per process, so with
+// several instances each one allows the full rate.
+type TokenBucketLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*tokenBucket
+	now     func() time.Time
+}
+
+func NewTokenBucketLimiter() *TokenBucketLimiter {
+	return &TokenBucketLimiter{buckets: map[uint]*tokenBucket{}, now: time.Now}
+}
+
+// Take tries to take a token from the key's bucket. A new bucket starts full.
+func (l *TokenBucketLimiter) Take(key uint, ratePerMinute, burst int) RateLimitResult {
+	if burst < 1 {
+		burst = 1
+	}
+	perSecond := float64(ratePerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*perSecond)
+	}
+	b.last = now
+
+	result := RateLimitResult{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else if perSecond > 0 {
+		result.RetryAfter = secondsDuration((1 - b.tokens) / perSecond)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	if perSecond > 0 {
+		result.Reset = secondsDuration((float64(burst) - b.tokens) / perSecond)
+	}
+	return result
+}
+
+// Forget drops a key's bucket, e.g. when its limits change.
+func (l *TokenBucketLimiter) Forget(key uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, key)
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock lets a test move the limiter's time by hand.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestLimiter() (*TokenBucketLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucketLimiter()
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func TestTokenBucketLimiter_Take(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	start := clock.now
+
+	// 60 a minute is one token a second, with room for two
+	steps := []struct {
+		at   time.Duration
+		want RateLimitResult
+	}{
+		{0, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}},
+		{0, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}},
+		{0, RateLimitResult{Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second}},
+		{500 * time.Millisecond, RateLimitResult{Limit: 2, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{time.Second, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}},
+		{10 * time.Second, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}}, // Refills no further than burst
+	}
+	for i, step := range steps {
+		clock.now = start.Add(step.at)
+		assert.Equal(t, step.want, limiter.Take(1, 60, 2), "step %d", i)
+	}
+}
+
+func TestTokenBucketLimiter_Edges(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  int
+		burst int
+		takes int
+		want  RateLimitResult
+	}{
+		{"burst below one is one", 60, 0, 1, RateLimitResult{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}},
+		{"over the burst", 120, 3, 4, RateLimitResult{Limit: 3, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{"no refill", 0, 1, 2, RateLimitResult{Limit: 1, Remaining: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, _ := newTestLimiter()
+			var got RateLimitResult
+			for i := 0; i < tt.takes; i++ {
+				got = limiter.Take(1, tt.rate, tt.burst)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTokenBucketLimiter_KeysAndForget(t *testing.T) {
+	limiter, _ := newTestLimiter()
+
+	assert.True(t, limiter.Take(1, 60, 1).Allowed)
+	assert.False(t, limiter.Take(1, 60, 1).Allowed)
+	assert.True(t, limiter.Take(2, 60, 1).Allowed, "each key has its own bucket")
+
+	limiter.Forget(1)
+	assert.True(t, limiter.Take(1, 60, 1).Allowed, "a forgotten key starts full")
+}
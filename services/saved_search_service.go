@@ -0,0 +1,491 @@
+// services/saved_search_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const alertEventMatch = "saved_search.match"
+
+// Notifications handed to the notifiers in one delivery pass
+const maxDeliveriesPerRun = 500
+
+type SavedSearchService struct {
+	DB        *gorm.DB
+	Notifiers map[string]Notifier // By channel, see DefaultNotifiers
+
+	deliveryOnce sync.Once
+	deliveryWake chan struct{} // Wakes the background delivery worker, see DeliverInBackground
+}
+
+func NewSavedSearchService(db *gorm.DB) *SavedSearchService {
+	return &SavedSearchService{
+		DB:        db,
+		Notifiers: DefaultNotifiers(),
+	}
+}
+
+// validateAlertTarget checks the target suits the channel and that the channel can deliver.
+func (s *SavedSearchService) validateAlertTarget(channel, target string) error {
+	switch channel {
+	case models.AlertChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return utils.NewBadRequestError(fmt.Sprintf("Invalid email address '%s'", target))
+		}
+	case models.AlertChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return utils.NewBadRequestError(fmt.Sprintf("Invalid webhook URL '%s', use an http or https URL", target))
+		}
+		if !notificationsSettings().Webhook.AllowPrivateNetworks {
+			if err := checkWebhookHost(u.Hostname()); err != nil {
+				return utils.NewBadRequestError(fmt.Sprintf("Invalid webhook URL '%s': %v", target, err))
+			}
+		}
+	}
+	if _, ok := s.Notifiers[channel]; !ok {
+		return utils.NewBadRequestError(fmt.Sprintf("%s alerts are not configured on this server", channel))
+	}
+	return nil
+}
+
+// applySavedSearchRequest validates the request and copies it onto the model.
+func (s *SavedSearchService) applySavedSearchRequest(search *models.SavedSearch, req *schema.SavedSearchRequest) error {
+	if err := validatePropertyFilter(req.Filter); err != nil {
+		return err
+	}
+	target := strings.TrimSpace(req.Target)
+	if err := s.validateAlertTarget(req.Channel, target); err != nil {
+		return err
+	}
+
+	// Without a user the search keeps its owner
+	if req.UserID != 0 {
+		search.UserID = req.UserID
+	}
+	var user models.User
+	if err := s.DB.Select("id").First(&user, search.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewNotFoundError("User")
+		}
+		return fmt.Errorf("database error retrieving user: %w", err)
+	}
+
+	filter, err := json.Marshal(req.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter: %w", err)
+	}
+
+	search.Name = strings.TrimSpace(req.Name)
+	search.SearchTerm = strings.TrimSpace(req.SearchTerm)
+	search.Filter = filter
+	search.Channel = req.Channel
+	search.Target = target
+	search.Active = req.Active == nil || *req.Active
+	return nil
+}
+
+// Saved searches belong to a user. The methods below take the owner whose searches they may
+// see and change, nil for every user's, which only managers get.
+
+// ownedBy limits a saved search query to the owner's searches.
+func ownedBy(query *gorm.DB, owner *uint) *gorm.DB {
+	if owner == nil {
+		return query
+	}
+	return query.Where("saved_searches.user_id = ?", *owner)
+}
+
+// CreateSavedSearch stores a new saved search. Only listings added or changed from now on
+// raise alerts.
+func (s *SavedSearchService) CreateSavedSearch(req *schema.SavedSearchRequest) (*schema.SavedSearchResponse, error) {
+	var search models.SavedSearch
+	if err := s.applySavedSearchRequest(&search, req); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(&search).Error; err != nil {
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+	return mapSavedSearchToResponse(&search)
+}
+
+// GetSavedSearches retrieves saved searches, newest first.
+func (s *SavedSearchService) GetSavedSearches(pag schema.PaginationRequest, filter schema.SavedSearchFilter, owner *uint) ([]schema.SavedSearchResponse, int64, error) {
+	query := ownedBy(s.DB.Model(&models.SavedSearch{}), owner)
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count saved searches: %w", err)
+	}
+
+	var searches []models.SavedSearch
+	err := query.Order("created_at DESC, id DESC").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Find(&searches).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve saved searches: %w", err)
+	}
+
+	responses := make([]schema.SavedSearchResponse, len(searches))
+	for i := range searches {
+		resp, err := mapSavedSearchToResponse(&searches[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		responses[i] = *resp
+	}
+	return responses, totalItems, nil
+}
+
+// findSavedSearch returns the owner's saved search. Someone else's is not found, the same as
+// one that doesn't exist.
+func (s *SavedSearchService) findSavedSearch(id uint, owner *uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := ownedBy(s.DB, owner).First(&search, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Saved search")
+		}
+		return nil, fmt.Errorf("database error retrieving saved search: %w", err)
+	}
+	return &search, nil
+}
+
+// GetSavedSearchByID retrieves a single saved search.
+func (s *SavedSearchService) GetSavedSearchByID(id uint, owner *uint) (*schema.SavedSearchResponse, error) {
+	search, err := s.findSavedSearch(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	return mapSavedSearchToResponse(search)
+}
+
+// UpdateSavedSearch replaces a saved search. Properties it has already notified about are
+// not notified again.
+func (s *SavedSearchService) UpdateSavedSearch(id uint, owner *uint, req *schema.SavedSearchRequest) (*schema.SavedSearchResponse, error) {
+	search, err := s.findSavedSearch(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySavedSearchRequest(search, req); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Save(search).Error; err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+	return mapSavedSearchToResponse(search)
+}
+
+// DeleteSavedSearch removes a saved search and its notifications.
+func (s *SavedSearchService) DeleteSavedSearch(id uint, owner *uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := ownedBy(tx, owner).Delete(&models.SavedSearch{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete saved search: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewNotFoundError("Saved search")
+		}
+		if err := tx.Where("saved_search_id = ?", id).Delete(&models.Notification{}).Error; err != nil {
+			return fmt.Errorf("failed to delete notifications: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetNotifications retrieves recorded notifications of the owner's saved searches, newest first.
+func (s *SavedSearchService) GetNotifications(pag schema.PaginationRequest, filter schema.NotificationFilter, owner *uint) ([]models.Notification, int64, error) {
+	query := s.DB.Model(&models.Notification{})
+	if owner != nil {
+		query = query.Where("saved_search_id IN (?)", ownedBy(s.DB.Model(&models.SavedSearch{}), owner).Select("saved_searches.id"))
+	}
+	if filter.SavedSearchID != nil {
+		query = query.Where("saved_search_id = ?", *filter.SavedSearchID)
+	}
+	if filter.PropertyID != nil {
+		query = query.Where("property_id = ?", *filter.PropertyID)
+	}
+	if filter.Status != nil && *filter.Status != "" {
+		query = query.Where("status = ?", strings.ToLower(*filter.Status))
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	notifications := []models.Notification{}
+	err := query.Order("created_at DESC, id DESC").
+		Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve notifications: %w", err)
+	}
+	return notifications, totalItems, nil
+}
+
+// --- Matching ---
+
+// RecordMatches checks the active saved searches of active users against the given properties
+// and records a pending notification for each new match.
+func (s *SavedSearchService) RecordMatches(propertyIDs []uint) (*schema.AlertRunResult, error) {
+	result := &schema.AlertRunResult{Properties: len(propertyIDs)}
+	if len(propertyIDs) == 0 {
+		return result, nil
+	}
+
+	var searches []models.SavedSearch
+	if err := s.DB.Where("active = ?", true).Order("id").Find(&searches).Error; err != nil {
+		return nil, fmt.Errorf("failed to load saved searches: %w", err)
+	}
+	result.Searches = len(searches)
+
+	// Each search only matches what its owner may see
+	userIDs := make([]uint, 0, len(searches))
+	for _, search := range searches {
+		userIDs = append(userIDs, search.UserID)
+	}
+	var owners []models.User
+	if err := s.DB.Where("id IN ?", userIDs).Find(&owners).Error; err != nil {
+		return nil, fmt.Errorf("failed to load saved search owners: %w", err)
+	}
+	// Owners who can no longer log in get no alerts
+	tenants := make(map[uint]Tenant, len(owners))
+	inactive := map[uint]bool{}
+	for i := range owners {
+		tenants[owners[i].ID] = TenantForUser(&owners[i])
+		inactive[owners[i].ID] = !userActive(&owners[i])
+	}
+
+	for _, search := range searches {
+		var filter schema.PropertyFilter
+		if len(search.Filter) > 0 {
+			if err := json.Unmarshal(search.Filter, &filter); err != nil {
+				log.Printf("Warning: saved search %d has an unreadable filter, skipping: %v\n", search.ID, err)
+				continue
+			}
+		}
+		if err := validatePropertyFilter(filter); err != nil {
+			log.Printf("Warning: saved search %d has an invalid filter, skipping: %v\n", search.ID, err)
+			continue
+		}
+
+		tenant, ok := tenants[search.UserID]
+		if !ok {
+			log.Printf("Warning: saved search %d belongs to a missing user, skipping\n", search.ID)
+			continue
+		}
+		if inactive[search.UserID] {
+			continue
+		}
+		db := s.DB.WithContext(WithTenant(s.DB.Statement.Context, tenant))
+		query := applyPropertyFilters(db.Model(&models.Property{}), filter).
+			Where("properties.id IN ?", propertyIDs)
+		if search.SearchTerm != "" {
+			query = query.Scopes(fullTextScope(search.SearchTerm))
+		}
+		var matched []uint
+		if err := query.Pluck("properties.id", &matched).Error; err != nil {
+			return nil, fmt.Errorf("failed to match saved search %d: %w", search.ID, err)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		notifications := make([]models.Notification, len(matched))
+		for i, propertyID := range matched {
+			notifications[i] = models.Notification{
+				SavedSearchID: search.ID,
+				PropertyID:    propertyID,
+				Channel:       search.Channel,
+				Target:        search.Target,
+				Status:        models.NotificationStatusPending,
+			}
+		}
+		// A property that changes again keeps its first notification
+		created := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications)
+		if created.Error != nil {
+			return nil, fmt.Errorf("failed to record notifications for saved search %d: %w", search.ID, created.Error)
+		}
+		if created.RowsAffected > 0 {
+			result.Matches += int(created.RowsAffected)
+			now := time.Now()
+			if err := s.DB.Model(&search).Update("last_matched_at", now).Error; err != nil {
+				log.Printf("Warning: failed to update saved search %d: %v\n", search.ID, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// DeliverInBackground asks the background worker for a delivery pass and returns straight away.
+// There is only one worker, so a burst of new listings doesn't start a pass each, requests made
+// while a pass is running are folded into a single follow-up pass.
+func (s *SavedSearchService) DeliverInBackground() {
+	s.deliveryOnce.Do(func() {
+		s.deliveryWake = make(chan struct{}, 1)
+		go s.deliveryWorker()
+	})
+	select {
+	case s.deliveryWake <- struct{}{}:
+	default: // A pass is already queued
+	}
+}
+
+func (s *SavedSearchService) deliveryWorker() {
+	for range s.deliveryWake {
+		if _, err := s.DeliverPending(); err != nil {
+			log.Printf("Warning: failed to deliver saved search alerts: %v\n", err)
+		}
+	}
+}
+
+// EvaluateProperties records matches for the properties and delivers them straight away.
+// Used after a sync, CreateProperty records its matches and calls DeliverInBackground instead.
+func (s *SavedSearchService) EvaluateProperties(propertyIDs []uint) (*schema.AlertRunResult, error) {
+	result, err := s.RecordMatches(propertyIDs)
+	if err != nil {
+		return nil, err
+	}
+	if result.Matches > 0 {
+		delivery, err := s.DeliverPending()
+		if err != nil {
+			return result, err
+		}
+		result.Delivery = delivery
+	}
+	return result, nil
+}
+
+// --- Delivery ---
+
+// DeliverPending sends the pending notifications that are due, oldest first. Each one is claimed
+// by bumping its attempt count first, so two passes running at once don't send it twice. A
+// failed attempt is retried after retryDelay.
+func (s *SavedSearchService) DeliverPending() (*schema.NotificationDeliveryResult, error) {
+	settings := notificationsSettings()
+	result := &schema.NotificationDeliveryResult{}
+
+	var pending []models.Notification
+	err := s.DB.Where("status = ?", models.NotificationStatusPending).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+		Order("id").
+		Limit(maxDeliveriesPerRun).
+		Find(&pending).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending notifications: %w", err)
+	}
+
+	searchNames := map[uint]string{}
+	for _, n := range pending {
+		claim := s.DB.Model(&models.Notification{}).
+			Where("id = ? AND status = ? AND attempts = ?", n.ID, models.NotificationStatusPending, n.Attempts).
+			Update("attempts", gorm.Expr("attempts + 1"))
+		if claim.Error != nil {
+			return nil, fmt.Errorf("failed to claim notification %d: %w", n.ID, claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue // Another pass has it
+		}
+		n.Attempts++
+
+		sendErr := s.sendNotification(&n, searchNames)
+		updates := map[string]interface{}{}
+		switch {
+		case sendErr == nil:
+			now := time.Now()
+			updates["status"] = models.NotificationStatusSent
+			updates["sent_at"] = &now
+			updates["last_error"] = ""
+			updates["next_attempt_at"] = nil
+			result.Sent++
+		case n.Attempts >= settings.MaxAttempts:
+			updates["status"] = models.NotificationStatusFailed
+			updates["last_error"] = sendErr.Error()
+			result.Failed++
+		default:
+			updates["last_error"] = sendErr.Error()
+			updates["next_attempt_at"] = time.Now().Add(retryDelay(settings, n.Attempts))
+			result.Retry++
+		}
+		if err := s.DB.Model(&models.Notification{}).Where("id = ?", n.ID).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update notification %d: %w", n.ID, err)
+		}
+	}
+	return result, nil
+}
+
+// sendNotification builds the alert for one notification and hands it to its channel's notifier.
+func (s *SavedSearchService) sendNotification(n *models.Notification, searchNames map[uint]string) error {
+	notifier, ok := s.Notifiers[n.Channel]
+	if !ok {
+		return fmt.Errorf("no notifier configured for %s", n.Channel)
+	}
+
+	name, ok := searchNames[n.SavedSearchID]
+	if !ok {
+		var search models.SavedSearch
+		if err := s.DB.Select("id", "name").First(&search, n.SavedSearchID).Error; err != nil {
+			return fmt.Errorf("failed to load saved search %d: %w", n.SavedSearchID, err)
+		}
+		name = search.Name
+		searchNames[n.SavedSearchID] = name
+	}
+
+	var property models.Property
+	err := s.DB.Preload("Location").
+		Preload("Agent.User").
+		Preload("CoverPhoto").
+		First(&property, n.PropertyID).Error
+	if err != nil {
+		return fmt.Errorf("failed to load property %d: %w", n.PropertyID, err)
+	}
+
+	return notifier.Send(n.Target, schema.AlertMessage{
+		Event:           alertEventMatch,
+		NotificationID:  n.ID,
+		SavedSearchID:   n.SavedSearchID,
+		SavedSearchName: name,
+		Property:        MapPropertyToResponse(&property),
+	})
+}
+
+func mapSavedSearchToResponse(search *models.SavedSearch) (*schema.SavedSearchResponse, error) {
+	resp := &schema.SavedSearchResponse{
+		ID:            search.ID,
+		UserID:        search.UserID,
+		Name:          search.Name,
+		SearchTerm:    search.SearchTerm,
+		Channel:       search.Channel,
+		Target:        search.Target,
+		Active:        search.Active,
+		LastMatchedAt: search.LastMatchedAt,
+		CreatedAt:     search.CreatedAt,
+		UpdatedAt:     search.UpdatedAt,
+	}
+	if len(search.Filter) > 0 {
+		if err := json.Unmarshal(search.Filter, &resp.Filter); err != nil {
+			return nil, fmt.Errorf("failed to decode saved search filter: %w", err)
+		}
+	}
+	return resp, nil
+}
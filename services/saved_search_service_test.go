@@ -0,0 +1,128 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearches_LimitedToOwner(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSavedSearchService(db)
+	owner := uint(12)
+	pag := schema.PaginationRequest{Page: 1, PageSize: 10}
+
+	_, _, err := service.GetSavedSearches(pag, schema.SavedSearchFilter{}, &owner)
+	require.NoError(t, err)
+	_, err = service.GetSavedSearchByID(3, &owner)
+	assert.Error(t, err) // No rows, so not found
+	_, _, err = service.GetNotifications(pag, schema.NotificationFilter{}, &owner)
+	require.NoError(t, err)
+
+	searches := recorder.touching(`FROM "saved_searches"`)
+	require.NotEmpty(t, searches)
+	for _, sql := range searches {
+		assert.Contains(t, sql, "saved_searches.user_id = 12", sql)
+	}
+	notifications := recorder.touching(`FROM "notifications"`)
+	require.NotEmpty(t, notifications)
+	for _, sql := range notifications {
+		assert.Contains(t, sql, `saved_search_id IN (SELECT saved_searches.id FROM "saved_searches" WHERE saved_searches.user_id = 12)`, sql)
+	}
+}
+
+func TestSavedSearches_ManagersSeeEveryone(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSavedSearchService(db)
+	pag := schema.PaginationRequest{Page: 1, PageSize: 10}
+
+	_, _, err := service.GetSavedSearches(pag, schema.SavedSearchFilter{}, nil)
+	require.NoError(t, err)
+	_, _, err = service.GetNotifications(pag, schema.NotificationFilter{}, nil)
+	require.NoError(t, err)
+
+	statements := append(recorder.touching(`FROM "saved_searches"`), recorder.touching(`FROM "notifications"`)...)
+	require.NotEmpty(t, statements)
+	for _, sql := range statements {
+		assert.NotContains(t, sql, "user_id", sql)
+	}
+}
+
+func TestDeliverPending_WaitsForRetryDelay(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSavedSearchService(db)
+
+	_, err := service.DeliverPending()
+	require.NoError(t, err)
+
+	statements := recorder.touching(`FROM "notifications"`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], "next_attempt_at IS NULL OR next_attempt_at <=")
+}
+
+func TestRecordMatches_FiltersOwnersAndDuplicates(t *testing.T) {
+	db, mock := setupMockDB(t)
+	searches := sqlmock.NewRows([]string{"id", "user_id", "filter", "search_term", "channel", "target", "active"}).
+		AddRow(1, 12, `{"district": "Zomba", "maxPrice": 200000}`, "", models.AlertChannelEmail, "viewer@example.com", true).
+		AddRow(2, 13, `{}`, "", models.AlertChannelEmail, "gone@example.com", true).
+		AddRow(3, 12, `{"propertyType": "House"}`, "", models.AlertChannelWebhook, "https://example.com/hook", true)
+	mock.ExpectQuery(`SELECT \* FROM "saved_searches" WHERE active = \$1 ORDER BY id`).
+		WithArgs(true).
+		WillReturnRows(searches)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN \(\$1,\$2,\$3\)`).
+		WithArgs(12, 13, 12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role", "status"}).
+			AddRow(12, models.RoleViewer, "Active").
+			AddRow(13, models.RoleViewer, "inactive"))
+
+	// Each search runs its own filter over the new listings
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "properties"."id" FROM "properties" WHERE properties.price <= $1 AND `+
+		`properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE $2) AND properties.id IN ($3,$4)`)).
+		WithArgs(200000.0, "%Zomba%", 5, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`INSERT INTO "notifications" .* ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(1, 5, models.AlertChannelEmail, "viewer@example.com", models.NotificationStatusPending,
+			0, "", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+	mock.ExpectExec(`UPDATE "saved_searches" SET "last_matched_at"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Search 2's owner is inactive, so it is never run. Search 3 matches both listings, but 5 and
+	// 6 were already notified and the conflict leaves them be.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "properties"."id" FROM "properties" WHERE properties.property_type = $1 AND properties.id IN ($2,$3)`)).
+		WithArgs("House", 5, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	mock.ExpectQuery(`INSERT INTO "notifications" .* ON CONFLICT DO NOTHING RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	result, err := NewSavedSearchService(db).RecordMatches([]uint{5, 6})
+	require.NoError(t, err)
+	assert.Equal(t, &schema.AlertRunResult{Properties: 2, Searches: 3, Matches: 1}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluateProperties_NothingNewSkipsDelivery(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(`FROM "saved_searches"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "filter", "channel", "target", "active"}).
+			AddRow(1, 12, `{}`, models.AlertChannelEmail, "viewer@example.com", true))
+	mock.ExpectQuery(`FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role", "status"}).AddRow(12, models.RoleViewer, models.UserStatusActive))
+	mock.ExpectQuery(`SELECT "properties"."id" FROM "properties" WHERE properties.id IN \(\$1\)`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	// Already notified, so nothing is recorded and no notifications are loaded for delivery
+	mock.ExpectQuery(`INSERT INTO "notifications"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	result, err := NewSavedSearchService(db).EvaluateProperties([]uint{5})
+	require.NoError(t, err)
+	assert.Zero(t, result.Matches)
+	assert.Nil(t, result.Delivery)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchProperties_Success(t *testing.T) {
+	db, mock := setupMockDB(t)
+	service := NewPropertyService(db)
+	pagination := schema.PaginationRequest{Page: 1, PageSize: 5}
+	searchTerm := "Zingwangwa"
+
+	expectedMatch := `WHERE properties.search_vector @@ websearch_to_tsquery('english', $1)`
+
+	// Mock Count Query
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "properties" ` + expectedMatch)).
+		WithArgs(searchTerm).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1)) // Found 1 match
+
+	// Mock Data Query, ranked by relevance, then newest, then ID so equal ranks keep their order
+	mockProperties := []models.Property{{ID: 154, OwnerName: "Some Match"}}
+	rows := createMockPropertyRows(mockProperties...)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT properties.*, ts_rank(properties.search_vector, websearch_to_tsquery('english', $1)) AS search_rank FROM "properties" WHERE properties.search_vector @@ websearch_to_tsquery('english', $2) ORDER BY search_rank DESC,properties.created_at DESC,properties.id ASC LIMIT $3`)).
+		WithArgs(searchTerm, searchTerm, pagination.PageSize).
+		WillReturnRows(rows)
+
+	// No agent on the row, so only the other associations are preloaded
+	for _, table := range []string{"cover_photos", "locations", "quality_issues"} {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "`+table+`" WHERE "`+table+`"."property_id" = $1`)).
+			WithArgs(154).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	// --- Act ---
+	properties, total, err := service.SearchProperties(searchTerm, pagination, schema.PropertyFilter{})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, properties, 1)
+	assert.Equal(t, uint(154), properties[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProperties_NoResults(t *testing.T) {
+	db, mock := setupMockDB(t)
+	service := NewPropertyService(db)
+	pagination := schema.PaginationRequest{Page: 1, PageSize: 5}
+	searchTerm := "xyzNonExistent123"
+
+	expectedMatch := `WHERE properties.search_vector @@ websearch_to_tsquery('english', $1)`
+
+	// Mock Count Query returning 0
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "properties" ` + expectedMatch)).
+		WithArgs(searchTerm).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// Nothing matched the full-text index, so the trigram fallback runs
+	expectedJoins := `LEFT JOIN locations ON locations.property_id = properties.id LEFT JOIN agents ON agents.id = properties.agent_id LEFT JOIN users ON users.id = agents.user_id`
+	fuzzyScore := `greatest(word_similarity($1, coalesce(properties.owner_name, '')), word_similarity($2, concat_ws(', ', locations.sub_area, locations.area, locations.district)), word_similarity($3, coalesce(properties.property_design, '')), word_similarity($4, coalesce(users.name, '')))`
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "properties" `+expectedJoins+` WHERE `+fuzzyScore+` >= $5`)).
+		WithArgs(searchTerm, searchTerm, searchTerm, searchTerm, fuzzySearchThreshold).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	// Mock Data Query returning no rows
+	rows := createMockPropertyRows() // Empty
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT properties.*, `) + `.*` + regexp.QuoteMeta(` AS search_rank FROM "properties" `+expectedJoins) + `.*` + regexp.QuoteMeta(`ORDER BY search_rank DESC,properties.created_at DESC,properties.id ASC`)).
+		WillReturnRows(rows)
+
+	// No preloads expected
+
+	// --- Act ---
+	properties, total, err := service.SearchProperties(searchTerm, pagination, schema.PropertyFilter{})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+	assert.Len(t, properties, 0)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchMatches_Highlights(t *testing.T) {
+	db, mock := setupMockDB(t)
+	searchTerm := "Banda"
+
+	// Four fuzzy fields take the term for word_similarity, then the tsquery and the IDs
+	mock.ExpectQuery(regexp.QuoteMeta(`ts_headline('english', coalesce(properties.owner_name, ''), q.query, '`+searchHeadlineOptions+`') AS owner_name_headline`) +
+		`.*` + regexp.QuoteMeta(`CROSS JOIN (SELECT websearch_to_tsquery('english', $5) AS query) q`) +
+		`.*` + regexp.QuoteMeta(`WHERE properties.id IN ($6,$7) AND TRUE`)).
+		WithArgs(searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, 154, 200).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "matched", "rank",
+			"owner_name_headline", "description_headline", "location_text", "location_similarity", "design_text", "design_similarity"}).
+			// A full-text match, the description headline is only the start of the text
+			AddRow(int64(154), true, 0.0759, "John <mark>Banda</mark>", "Three bedroom house", "", 0.0, "", 0.0).
+			// A trigram match, the place is close enough and the design is not
+			AddRow(int64(200), false, 0.0, "Bandawe Road", "", "Bandawe, Nkhata Bay", 0.6, "Bungalow", 0.1))
+
+	matches, err := NewPropertyService(db).SearchMatches(searchTerm, []uint{154, 200})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+
+	assert.Equal(t, schema.SearchMatch{Rank: 0.0759, Highlights: map[string]string{"owner_name": "John <mark>Banda</mark>"}}, matches[154])
+	assert.Equal(t, schema.SearchMatch{Rank: 0.6, Fuzzy: true, Highlights: map[string]string{"location": "<mark>Bandawe, Nkhata Bay</mark>"}}, matches[200])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchMatches_NothingToHighlight(t *testing.T) {
+	db, mock := setupMockDB(t)
+	service := NewPropertyService(db)
+
+	matches, err := service.SearchMatches("  ", []uint{1})
+	require.NoError(t, err)
+	assert.Nil(t, matches)
+	matches, err = service.SearchMatches("Banda", nil)
+	require.NoError(t, err)
+	assert.Nil(t, matches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSuggestCompletions_RawSQL(t *testing.T) {
+	db, mock := setupMockDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+	term, prefix := "50%_off", `50\%\_off%`
+
+	// The tenant condition stands in for each @tenant, the LIKE wildcards in the term are escaped
+	// and the limit is capped
+	tenant := regexp.QuoteMeta(`WHERE (properties.financial_institution_id IS NULL OR properties.financial_institution_id = $`)
+	mock.ExpectQuery(regexp.QuoteMeta(`max(word_similarity($1, text)) AS score, bool_or(text ILIKE $2) AS prefix`) +
+		`.*` + tenant + `3\)` + `.*` + tenant + `4\)` + `.*` + tenant + `5\)` + `.*` + tenant + `6\)` + `.*` + tenant + `7\)` +
+		`.*` + regexp.QuoteMeta(`WHERE text <> '' AND (text ILIKE $8 OR word_similarity($9, text) >= $10)`) +
+		`.*` + regexp.QuoteMeta(`LIMIT $11`)).
+		WithArgs(term, prefix, bankA, bankA, bankA, bankA, bankA, prefix, term, fuzzySearchThreshold, MaxSuggestLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "text", "count", "score", "prefix"}).
+			AddRow("design", "50% off bungalow", 3, 0.4166666, false))
+
+	suggestions, err := service.SuggestCompletions(" "+term+" ", 40)
+	require.NoError(t, err)
+	assert.Equal(t, []schema.Suggestion{{Kind: "design", Text: "50% off bungalow", Count: 3, Score: 0.4167}}, suggestions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSuggestCompletions_DefaultLimit(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE TRUE`) + `.*` + regexp.QuoteMeta(`LIMIT $6`)).
+		WithArgs("lil", "lil%", "lil%", "lil", fuzzySearchThreshold, DefaultSuggestLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "text", "count", "score", "prefix"}))
+
+	suggestions, err := NewPropertyService(db).SuggestCompletions("lil", 0)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+
+	// Nothing typed, nothing asked of the database
+	suggestions, err = NewPropertyService(db).SuggestCompletions("  ", 5)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
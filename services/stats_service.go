@@ -0,0 +1,381 @@
+// services/stats_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+// marketStatsDimensions whitelists the groupBy values and the column each one groups on.
+var marketStatsDimensions = map[string]string{
+	"region":        "locations.region",
+	"district":      "locations.district",
+	"area":          "locations.area",
+	"property_type": "properties.property_type",
+	"listing_type":  "properties.listing_type",
+}
+
+// Order dimensions are reported in, regardless of the order they were requested
+var marketStatsDimensionOrder = []string{"region", "district", "area", "property_type", "listing_type"}
+
+// Sale and rent prices are not comparable, so they are kept apart unless asked otherwise
+var DefaultMarketStatsGroupBy = []string{"district", "listing_type"}
+
+var defaultPriceBuckets = []float64{0, 5000000, 10000000, 25000000, 50000000, 100000000, 250000000}
+
+const maxPriceBuckets = 50
+
+type StatsService struct {
+	DB *gorm.DB
+}
+
+func NewStatsService(db *gorm.DB) *StatsService {
+	return &StatsService{DB: db}
+}
+
+func (s *StatsService) WithContext(ctx context.Context) *StatsService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// marketStatsKey holds the dimension values of a group, only the grouped ones are selected.
+// Rows hold it in an exported field tagged embedded, GORM skips unexported embedded structs.
+type marketStatsKey struct {
+	Region       *string `gorm:"column:region"`
+	District     *string `gorm:"column:district"`
+	Area         *string `gorm:"column:area"`
+	PropertyType *string `gorm:"column:property_type"`
+	ListingType  *string `gorm:"column:listing_type"`
+}
+
+func (k marketStatsKey) values() map[string]*string {
+	return map[string]*string{
+		"region":        k.Region,
+		"district":      k.District,
+		"area":          k.Area,
+		"property_type": k.PropertyType,
+		"listing_type":  k.ListingType,
+	}
+}
+
+// id joins the grouped values so rows from the different aggregate queries can be matched up.
+func (k marketStatsKey) id(groupBy []string) string {
+	values := k.values()
+	parts := make([]string, len(groupBy))
+	for i, dim := range groupBy {
+		if v := values[dim]; v != nil {
+			parts[i] = "=" + *v
+		} else {
+			parts[i] = "null"
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+type marketStatsRow struct {
+	Key           marketStatsKey `gorm:"embedded"`
+	ListingCount  int64          `gorm:"column:listing_count"`
+	PricedCount   int64          `gorm:"column:priced_count"`
+	SoldCount     int64          `gorm:"column:sold_count"`
+	PriceAvg      *float64       `gorm:"column:price_avg"`
+	PriceMin      *float64       `gorm:"column:price_min"`
+	PriceP10      *float64       `gorm:"column:price_p10"`
+	PriceP50      *float64       `gorm:"column:price_p50"`
+	PriceP90      *float64       `gorm:"column:price_p90"`
+	PriceMax      *float64       `gorm:"column:price_max"`
+	BuildingAvg   *float64       `gorm:"column:building_ppsqm_avg"`
+	BuildingMin   *float64       `gorm:"column:building_ppsqm_min"`
+	BuildingP10   *float64       `gorm:"column:building_ppsqm_p10"`
+	BuildingP50   *float64       `gorm:"column:building_ppsqm_p50"`
+	BuildingP90   *float64       `gorm:"column:building_ppsqm_p90"`
+	BuildingMax   *float64       `gorm:"column:building_ppsqm_max"`
+	LandAvg       *float64       `gorm:"column:land_ppsqm_avg"`
+	LandMin       *float64       `gorm:"column:land_ppsqm_min"`
+	LandP10       *float64       `gorm:"column:land_ppsqm_p10"`
+	LandP50       *float64       `gorm:"column:land_ppsqm_p50"`
+	LandP90       *float64       `gorm:"column:land_ppsqm_p90"`
+	LandMax       *float64       `gorm:"column:land_ppsqm_max"`
+	DaysOnMarket  *float64       `gorm:"column:dom_avg"`
+	DaysOnMarketM *float64       `gorm:"column:dom_p50"`
+}
+
+type marketBucketRow struct {
+	Key    marketStatsKey `gorm:"embedded"`
+	Bucket int            `gorm:"column:bucket"`
+	Count  int64          `gorm:"column:count"`
+}
+
+type marketRoomRow struct {
+	Key   marketStatsKey `gorm:"embedded"`
+	Rooms *int           `gorm:"column:rooms"`
+	Count int64          `gorm:"column:count"`
+}
+
+// sqlTruthy matches the string flags the upstream API uses for booleans ("1", "true", "yes").
+func sqlTruthy(column string) string {
+	return fmt.Sprintf("LOWER(COALESCE(%s, '')) IN ('1', 'true', 'yes', 'y')", column)
+}
+
+// summarySelects returns avg/min/p10/p50/p90/max of a column, ignoring empty and zero values.
+func summarySelects(column, alias string) []string {
+	positive := fmt.Sprintf("FILTER (WHERE %s > 0)", column)
+	return []string{
+		fmt.Sprintf("AVG(%s) %s AS %s_avg", column, positive, alias),
+		fmt.Sprintf("MIN(%s) %s AS %s_min", column, positive, alias),
+		fmt.Sprintf("percentile_cont(0.1) WITHIN GROUP (ORDER BY %s) %s AS %s_p10", column, positive, alias),
+		fmt.Sprintf("percentile_cont(0.5) WITHIN GROUP (ORDER BY %s) %s AS %s_p50", column, positive, alias),
+		fmt.Sprintf("percentile_cont(0.9) WITHIN GROUP (ORDER BY %s) %s AS %s_p90", column, positive, alias),
+		fmt.Sprintf("MAX(%s) %s AS %s_max", column, positive, alias),
+	}
+}
+
+// ParseMarketStatsGroupBy validates a comma separated groupBy value. Both property_type
+// and propertyType spellings are accepted.
+func ParseMarketStatsGroupBy(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultMarketStatsGroupBy, nil
+	}
+	requested := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		switch name {
+		case "propertytype":
+			name = "property_type"
+		case "listingtype":
+			name = "listing_type"
+		}
+		if _, ok := marketStatsDimensions[name]; !ok {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("Cannot group by '%s', use one of %s", strings.TrimSpace(part), strings.Join(marketStatsDimensionOrder, ", ")))
+		}
+		requested[name] = true
+	}
+
+	groupBy := make([]string, 0, len(requested))
+	for _, dim := range marketStatsDimensionOrder {
+		if requested[dim] {
+			groupBy = append(groupBy, dim)
+		}
+	}
+	if len(groupBy) == 0 {
+		return DefaultMarketStatsGroupBy, nil
+	}
+	return groupBy, nil
+}
+
+// ParsePriceBuckets reads comma separated bucket edges. An empty value uses the configured edges.
+func ParsePriceBuckets(raw string) ([]float64, error) {
+	if strings.TrimSpace(raw) == "" {
+		if config.Cfg != nil && len(config.Cfg.Stats.PriceBuckets) > 0 {
+			return config.Cfg.Stats.PriceBuckets, nil
+		}
+		return defaultPriceBuckets, nil
+	}
+	var edges []float64
+	for _, part := range strings.Split(raw, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("Invalid price bucket edge '%s'", strings.TrimSpace(part)))
+		}
+		if len(edges) > 0 && v <= edges[len(edges)-1] {
+			return nil, utils.NewBadRequestError("Price bucket edges must be in ascending order")
+		}
+		edges = append(edges, v)
+	}
+	if len(edges) > maxPriceBuckets {
+		return nil, utils.NewBadRequestError(fmt.Sprintf("At most %d price bucket edges are allowed", maxPriceBuckets))
+	}
+	return edges, nil
+}
+
+// GetMarketStats aggregates the filtered listings by the groupBy dimensions. All the work
+// is done by SQL aggregates, one query for the summaries and one for each distribution.
+func (s *StatsService) GetMarketStats(filter schema.PropertyFilter, groupBy []string, priceBuckets []float64) (*schema.MarketStatsResponse, error) {
+	if err := validatePropertyFilter(filter); err != nil {
+		return nil, err
+	}
+	if len(groupBy) == 0 {
+		groupBy = DefaultMarketStatsGroupBy
+	}
+	if len(priceBuckets) == 0 {
+		priceBuckets = defaultPriceBuckets
+	}
+
+	dimSelects := make([]string, len(groupBy))
+	groupCols := make([]string, len(groupBy))
+	for i, dim := range groupBy {
+		column, ok := marketStatsDimensions[dim]
+		if !ok {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("Cannot group by '%s'", dim))
+		}
+		dimSelects[i] = fmt.Sprintf("%s AS %s", column, dim)
+		groupCols[i] = column
+	}
+	groupClause := strings.Join(groupCols, ", ")
+
+	base := func() *gorm.DB {
+		query := s.DB.Model(&models.Property{}).
+			Joins("LEFT JOIN locations ON locations.property_id = properties.id")
+		return applyPropertyFilters(query, filter)
+	}
+
+	// 1. Counts, price spreads and days on market. Listings go on the market when approved. Upstream
+	// sends no sale date, so a completed sale ends at its last update, which runs long if it was edited later
+	daysOnMarket := fmt.Sprintf("EXTRACT(EPOCH FROM (CASE WHEN %s THEN properties.updated_at ELSE NOW() END - COALESCE(properties.approved_at, properties.created_at))) / 86400", sqlTruthy("properties.is_sale_completed"))
+	selects := append([]string{}, dimSelects...)
+	selects = append(selects,
+		"COUNT(*) AS listing_count",
+		"COUNT(*) FILTER (WHERE properties.price > 0) AS priced_count",
+		fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS sold_count", sqlTruthy("properties.is_sale_completed")),
+	)
+	selects = append(selects, summarySelects("properties.price", "price")...)
+	selects = append(selects, summarySelects("properties.building_price_per_sqm", "building_ppsqm")...)
+	selects = append(selects, summarySelects("properties.land_price_per_sqm", "land_ppsqm")...)
+	selects = append(selects,
+		fmt.Sprintf("AVG(%s) AS dom_avg", daysOnMarket),
+		fmt.Sprintf("percentile_cont(0.5) WITHIN GROUP (ORDER BY %s) AS dom_p50", daysOnMarket),
+	)
+
+	var rows []marketStatsRow
+	err := base().Select(strings.Join(selects, ", ")).
+		Group(groupClause).
+		Order("listing_count DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute market statistics: %w", err)
+	}
+
+	// 2. Price distribution, width_bucket gives 0 below the first edge and len(edges) at or above the last
+	bucketExpr := priceBucketSQL(priceBuckets)
+	var bucketRows []marketBucketRow
+	err = base().Select(strings.Join(append(append([]string{}, dimSelects...), bucketExpr+" AS bucket", "COUNT(*) AS count"), ", ")).
+		Where("properties.price > 0").
+		Group(groupClause + ", bucket").
+		Scan(&bucketRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute price distribution: %w", err)
+	}
+
+	// 3. Room distribution
+	var roomRows []marketRoomRow
+	err = base().Select(strings.Join(append(append([]string{}, dimSelects...), "properties.no_rooms AS rooms", "COUNT(*) AS count"), ", ")).
+		Group(groupClause + ", properties.no_rooms").
+		Scan(&roomRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute room distribution: %w", err)
+	}
+
+	bucketsByGroup := map[string]map[int]int64{}
+	for _, r := range bucketRows {
+		id := r.Key.id(groupBy)
+		if bucketsByGroup[id] == nil {
+			bucketsByGroup[id] = map[int]int64{}
+		}
+		bucketsByGroup[id][r.Bucket] += r.Count
+	}
+	roomsByGroup := map[string][]schema.RoomCount{}
+	for _, r := range roomRows {
+		id := r.Key.id(groupBy)
+		roomsByGroup[id] = append(roomsByGroup[id], schema.RoomCount{Rooms: r.Rooms, Count: r.Count})
+	}
+
+	response := &schema.MarketStatsResponse{
+		GroupBy: groupBy,
+		Groups:  make([]schema.MarketStatsGroup, 0, len(rows)),
+	}
+	for _, r := range rows {
+		id := r.Key.id(groupBy)
+		key := make(map[string]*string, len(groupBy))
+		values := r.Key.values()
+		for _, dim := range groupBy {
+			key[dim] = values[dim]
+		}
+
+		rooms := roomsByGroup[id]
+		sort.Slice(rooms, func(i, j int) bool {
+			if rooms[i].Rooms == nil || rooms[j].Rooms == nil {
+				return rooms[j].Rooms == nil && rooms[i].Rooms != nil
+			}
+			return *rooms[i].Rooms < *rooms[j].Rooms
+		})
+		if rooms == nil {
+			rooms = []schema.RoomCount{}
+		}
+
+		response.TotalListings += r.ListingCount
+		response.Groups = append(response.Groups, schema.MarketStatsGroup{
+			Key:                 key,
+			ListingCount:        r.ListingCount,
+			PricedCount:         r.PricedCount,
+			SoldCount:           r.SoldCount,
+			ActiveCount:         r.ListingCount - r.SoldCount,
+			Price:               priceSummary(r.PriceAvg, r.PriceMin, r.PriceP10, r.PriceP50, r.PriceP90, r.PriceMax),
+			BuildingPricePerSqm: priceSummary(r.BuildingAvg, r.BuildingMin, r.BuildingP10, r.BuildingP50, r.BuildingP90, r.BuildingMax),
+			LandPricePerSqm:     priceSummary(r.LandAvg, r.LandMin, r.LandP10, r.LandP50, r.LandP90, r.LandMax),
+			DaysOnMarket: schema.DaysOnMarketSummary{
+				Avg: roundPtr(r.DaysOnMarket, 1),
+				P50: roundPtr(r.DaysOnMarketM, 1),
+			},
+			PriceDistribution: priceDistribution(priceBuckets, bucketsByGroup[id]),
+			RoomDistribution:  rooms,
+		})
+	}
+	return response, nil
+}
+
+func priceSummary(avg, min, p10, p50, p90, max *float64) schema.PriceSummary {
+	return schema.PriceSummary{
+		Avg: roundPtr(avg, 2),
+		Min: roundPtr(min, 2),
+		P10: roundPtr(p10, 2),
+		P50: roundPtr(p50, 2),
+		P90: roundPtr(p90, 2),
+		Max: roundPtr(max, 2),
+	}
+}
+
+// priceBucketSQL is the width_bucket expression for the price edges. The edges are written as
+// literals, they are parsed numbers so there is nothing to inject.
+func priceBucketSQL(edges []float64) string {
+	literals := make([]string, len(edges))
+	for i, e := range edges {
+		literals[i] = strconv.FormatFloat(e, 'f', -1, 64)
+	}
+	return fmt.Sprintf("width_bucket(properties.price, ARRAY[%s]::float8[])", strings.Join(literals, ", "))
+}
+
+// priceDistribution expands width_bucket counts into one entry per bucket, including empty
+// ones so every group has the same shape. The open bucket below the first edge is only
+// included when something falls in it.
+func priceDistribution(edges []float64, counts map[int]int64) []schema.DistributionBucket {
+	buckets := make([]schema.DistributionBucket, 0, len(edges)+1)
+	if counts[0] > 0 {
+		buckets = append(buckets, schema.DistributionBucket{Max: &edges[0], Count: counts[0]})
+	}
+	for i := 1; i < len(edges); i++ {
+		buckets = append(buckets, schema.DistributionBucket{Min: &edges[i-1], Max: &edges[i], Count: counts[i]})
+	}
+	buckets = append(buckets, schema.DistributionBucket{Min: &edges[len(edges)-1], Count: counts[len(edges)]})
+	return buckets
+}
+
+func roundPtr(v *float64, places int) *float64 {
+	if v == nil {
+		return nil
+	}
+	r := roundTo(*v, places)
+	return &r
+}
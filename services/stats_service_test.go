@@ -0,0 +1,96 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriceBuckets(t *testing.T) {
+	edges, err := ParsePriceBuckets(" 1000, 5000 ,20000")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1000, 5000, 20000}, edges)
+
+	for _, raw := range []string{"1000,abc", "NaN", "1000,Inf", "-Inf,1000", "5000,1000", "1000,1000"} {
+		_, err := ParsePriceBuckets(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestGetMarketStats_GroupsAndDistributions(t *testing.T) {
+	db, mock := setupMockDB(t)
+	group := regexp.QuoteMeta(`GROUP BY locations.district, properties.listing_type`)
+	from := regexp.QuoteMeta(`FROM "properties" LEFT JOIN locations ON locations.property_id = properties.id`)
+	keyColumns := []string{"district", "listing_type"}
+
+	// Summaries, medians come from percentile_cont over positive prices only
+	mock.ExpectQuery(`^` + regexp.QuoteMeta(`SELECT locations.district AS district, properties.listing_type AS listing_type, COUNT(*) AS listing_count,`) +
+		`.*` + regexp.QuoteMeta(`percentile_cont(0.5) WITHIN GROUP (ORDER BY properties.price) FILTER (WHERE properties.price > 0) AS price_p50`) +
+		`.*` + regexp.QuoteMeta(`COALESCE(properties.approved_at, properties.created_at)`) + `.*` + regexp.QuoteMeta(`AS dom_p50 `) +
+		from + ` ` + group + regexp.QuoteMeta(` ORDER BY listing_count DESC`) + `$`).
+		WillReturnRows(sqlmock.NewRows(append(keyColumns, "listing_count", "priced_count", "sold_count", "price_avg", "price_p50", "dom_avg", "dom_p50")).
+			AddRow("LILONGWE", "Sale", 4, 3, 1, 8333.333, 4000.004, 12.345, 10).
+			AddRow("ZOMBA", nil, 1, 0, 0, nil, nil, 3, 3))
+
+	// Price histogram, 0 is below the first edge and 3 at or above the last
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT locations.district AS district, properties.listing_type AS listing_type, width_bucket(properties.price, ARRAY[1000, 5000, 20000]::float8[]) AS bucket, COUNT(*) AS count `) +
+		from + regexp.QuoteMeta(` WHERE properties.price > 0 `) + group + regexp.QuoteMeta(`, bucket`) + `$`).
+		WillReturnRows(sqlmock.NewRows(append(keyColumns, "bucket", "count")).
+			AddRow("LILONGWE", "Sale", 0, 1).
+			AddRow("LILONGWE", "Sale", 1, 1).
+			AddRow("LILONGWE", "Sale", 3, 1))
+
+	// Rooms, listings without a count are grouped under nil
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT locations.district AS district, properties.listing_type AS listing_type, properties.no_rooms AS rooms, COUNT(*) AS count `) +
+		from + ` ` + group + regexp.QuoteMeta(`, properties.no_rooms`) + `$`).
+		WillReturnRows(sqlmock.NewRows(append(keyColumns, "rooms", "count")).
+			AddRow("LILONGWE", "Sale", nil, 1).
+			AddRow("LILONGWE", "Sale", 4, 2).
+			AddRow("LILONGWE", "Sale", 2, 1).
+			AddRow("ZOMBA", nil, 3, 1))
+
+	stats, err := NewStatsService(db).GetMarketStats(schema.PropertyFilter{}, nil, []float64{1000, 5000, 20000})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, []string{"district", "listing_type"}, stats.GroupBy)
+	assert.Equal(t, int64(5), stats.TotalListings)
+	require.Len(t, stats.Groups, 2)
+
+	lilongwe := stats.Groups[0]
+	assert.Equal(t, map[string]*string{"district": strPtr("LILONGWE"), "listing_type": strPtr("Sale")}, lilongwe.Key)
+	assert.Equal(t, int64(3), lilongwe.ActiveCount)
+	assert.Equal(t, 4000.0, *lilongwe.Price.P50)
+	assert.Equal(t, 8333.33, *lilongwe.Price.Avg)
+	assert.Nil(t, lilongwe.LandPricePerSqm.P50)
+	assert.Equal(t, 12.3, *lilongwe.DaysOnMarket.Avg)
+	assert.Equal(t, []schema.DistributionBucket{
+		{Max: floatPtr(1000), Count: 1},
+		{Min: floatPtr(1000), Max: floatPtr(5000), Count: 1},
+		{Min: floatPtr(5000), Max: floatPtr(20000), Count: 0},
+		{Min: floatPtr(20000), Count: 1},
+	}, lilongwe.PriceDistribution)
+	assert.Equal(t, []schema.RoomCount{{Rooms: intPtr(2), Count: 1}, {Rooms: intPtr(4), Count: 2}, {Rooms: nil, Count: 1}}, lilongwe.RoomDistribution)
+
+	// A group with no priced listings still has every bucket, and is matched up by its nil key
+	zomba := stats.Groups[1]
+	assert.Equal(t, map[string]*string{"district": strPtr("ZOMBA"), "listing_type": nil}, zomba.Key)
+	assert.Nil(t, zomba.Price.P50)
+	require.Len(t, zomba.PriceDistribution, 3)
+	for _, bucket := range zomba.PriceDistribution {
+		assert.Zero(t, bucket.Count)
+	}
+	assert.Equal(t, []schema.RoomCount{{Rooms: intPtr(3), Count: 1}}, zomba.RoomDistribution)
+}
+
+func TestGetMarketStats_UnknownDimension(t *testing.T) {
+	db, mock := setupMockDB(t)
+	_, err := NewStatsService(db).GetMarketStats(schema.PropertyFilter{}, []string{"owner_name"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Cannot group by 'owner_name'")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -14,14 +14,18 @@ import (
 	"github.com/hopekali04/valuations/config"
 	"github.com/hopekali04/valuations/models"
 	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type SyncService struct {
-	DB     *gorm.DB
-	Client *http.Client 
+	DB         *gorm.DB
+	Client     *http.Client 
+	PriceIndex *PriceIndexService  // Optional, refreshed after a sync that brought in new listings
+	Quality    *QualityService     // Optional, outliers are rescanned after a sync that brought in new listings
+	Alerts     *SavedSearchService // Optional, saved searches are checked against the synced listings
 }
 
 func NewSyncService(db *gorm.DB) *SyncService {
@@ -43,6 +47,7 @@ func (s *SyncService) FetchAndSyncProperties() (*schema.SyncResult, error) {
 	result := &schema.SyncResult{Status: "in_progress"}
 	var allErrors []string
 	processedIDs := make(map[uint]bool) // Keep track of processed properties across pages
+	var syncedIDs []uint
 
 	nextURL := cfg.ExternalAPI.PropertiesURL // Start with the base URL
 
@@ -108,7 +113,7 @@ func (s *SyncService) FetchAndSyncProperties() (*schema.SyncResult, error) {
 			syncErr := s.syncSingleProperty(&extProp)
 			if syncErr != nil {
 				if errors.Is(syncErr, gorm.ErrRecordNotFound) { // Specific check for "already exists"
-					log.Printf("Property ID %d already exists, skipping.\n", extProp.ID)
+					log.Printf("Property ID %d already exists or was merged, skipping.\n", extProp.ID)
 					result.SkippedCount++
 				} else {
 					errorMsg := fmt.Sprintf("Failed to sync property ID %d: %v", extProp.ID, syncErr)
@@ -119,6 +124,11 @@ func (s *SyncService) FetchAndSyncProperties() (*schema.SyncResult, error) {
 			} else {
 				log.Printf("Successfully synced property ID %d.\n", extProp.ID)
 				result.SyncedCount++
+				syncedIDs = append(syncedIDs, extProp.ID)
+				if hasUnrecognisedSizeUnit(&extProp) {
+					log.Printf("Warning: property ID %d has a size unit we could not convert (building '%s', land '%s').\n", extProp.ID, extProp.BuildingSizeUnit, extProp.LandSizeUnit)
+					result.UnrecognisedUnits++
+				}
 			}
 			processedIDs[extProp.ID] = true // Mark as processed
 		}
@@ -137,6 +147,36 @@ func (s *SyncService) FetchAndSyncProperties() (*schema.SyncResult, error) {
 	}
 	result.Errors = allErrors
 
+	// Upstream IDs don't move the sequences, keep local inserts clear of them
+	if err := ReserveLocalIDs(s.DB); err != nil {
+		log.Printf("Warning: %v\n", err)
+	}
+
+	// Keep the price index in step with the listings, a failure here should not fail the sync
+	if s.PriceIndex != nil && result.SyncedCount > 0 {
+		if _, err := s.PriceIndex.Refresh(); err != nil {
+			log.Printf("Warning: price index refresh after sync failed: %v\n", err)
+		} else {
+			result.PriceIndexRefreshed = true
+		}
+	}
+
+	if s.Quality != nil && result.SyncedCount > 0 {
+		if _, err := s.Quality.ScanOutliers(); err != nil {
+			log.Printf("Warning: outlier scan after sync failed: %v\n", err)
+		}
+	}
+
+	if s.Alerts != nil && len(syncedIDs) > 0 {
+		alerts, err := s.Alerts.EvaluateProperties(syncedIDs)
+		if err != nil {
+			log.Printf("Warning: saved search alerts after sync failed: %v\n", err)
+		}
+		if alerts != nil {
+			result.AlertMatches = alerts.Matches
+		}
+	}
+
 	log.Printf("Sync finished. Fetched: %d, Synced: %d, Skipped: %d, Errors: %d\n",
 		result.FetchedCount, result.SyncedCount, result.SkippedCount, result.ErrorCount)
 
@@ -144,7 +184,7 @@ func (s *SyncService) FetchAndSyncProperties() (*schema.SyncResult, error) {
 }
 
 // syncSingleProperty handles the logic for checking and inserting/updating one property and its relations.
-// Returns gorm.ErrRecordNotFound if the property already exists (used as a signal to skip).
+// Returns gorm.ErrRecordNotFound if the property already exists or was merged away (used as a signal to skip).
 func (s *SyncService) syncSingleProperty(extProp *schema.ExternalProperty) error {
     // Check if Property already exists by ID
     var existingProperty models.Property
@@ -155,6 +195,15 @@ func (s *SyncService) syncSingleProperty(extProp *schema.ExternalProperty) error
         return fmt.Errorf("failed to check for existing property: %w", err)
     }
 
+    // Properties merged into another one stay gone
+    var mergedCount int64
+    if err := s.DB.Model(&models.PropertyMerge{}).Where("merged_property_id = ?", extProp.ID).Count(&mergedCount).Error; err != nil {
+        return fmt.Errorf("failed to check for merged property: %w", err)
+    }
+    if mergedCount > 0 {
+        return gorm.ErrRecordNotFound
+    }
+
     // Use a transaction
     return s.DB.Transaction(func(tx *gorm.DB) error {
         var mappedUser *models.User
@@ -198,10 +247,20 @@ func (s *SyncService) syncSingleProperty(extProp *schema.ExternalProperty) error
         // Now that property exists, upsert Location
         if extProp.Location != nil {
             extProp.Location.PropertyID = extProp.ID
-            _, txErr = s.upsertLocation(tx, extProp.Location)
+            location, txErr := s.upsertLocation(tx, extProp.Location)
             if txErr != nil {
                 return fmt.Errorf("failed to upsert location %d for property %d: %w", extProp.Location.ID, extProp.ID, txErr)
             }
+            dbProperty.Location = *location
+        }
+
+        if _, txErr = refreshSearchVectors(tx, "pr.id = ?", extProp.ID); txErr != nil {
+            return fmt.Errorf("failed to index property %d for search: %w", extProp.ID, txErr)
+        }
+
+        // Rule checks only need this property, outliers are rescanned once the sync finishes
+        if txErr = saveRuleIssues(tx, &dbProperty); txErr != nil {
+            return fmt.Errorf("failed to run quality checks for property %d: %w", extProp.ID, txErr)
         }
 
         // Now that property exists, upsert CoverPhoto
@@ -218,6 +277,13 @@ func (s *SyncService) syncSingleProperty(extProp *schema.ExternalProperty) error
     })
 }
 
+// hasUnrecognisedSizeUnit reports whether a size on the external property is in a unit we can't convert.
+func hasUnrecognisedSizeUnit(extProp *schema.ExternalProperty) bool {
+	_, buildingOK := utils.AreaToSqm(extProp.BuildingSize, extProp.BuildingSizeUnit)
+	_, landOK := utils.AreaToSqm(extProp.LandSize, extProp.LandSizeUnit)
+	return !buildingOK || !landOK
+}
+
 // Helper to parse string dates from API (adjust format if needed)
 func parseAPITime(apiTime *string) (*time.Time, error) {
 	if apiTime == nil || *apiTime == "" {
@@ -282,6 +348,10 @@ func mapExternalToDBProperty(extProp *schema.ExternalProperty, agent *models.Age
 		Views:                        extProp.Views,
 	}
 
+	// Square-metre sizes and price per area; unknown units are counted in the sync result
+	normalisePropertySizes(&prop)
+	prop.AttributeNames = models.AttributeKeys(prop.Attributes)
+
 	// Parse time strings
 	createdAt, err := parseAPITime(&extProp.CreatedAt)
 	if err != nil {
@@ -320,6 +390,9 @@ func mapExternalToDBProperty(extProp *schema.ExternalProperty, agent *models.Age
 
 // --- Upsert Helper Functions ---
 
+// userHasLocalLogin is true in the upsert for users who have a password set here.
+const userHasLocalLogin = "(users.password_hash IS NOT NULL AND users.password_hash <> '')"
+
 func (s *SyncService) upsertUser(tx *gorm.DB, extUser *schema.ExternalUser) (*models.User, error) {
 	if extUser == nil {
 		return nil, errors.New("cannot upsert nil user")
@@ -361,14 +434,37 @@ func (s *SyncService) upsertUser(tx *gorm.DB, extUser *schema.ExternalUser) (*mo
 	}
 	// Handle DeletedAt if needed
 
+	// Institutions not set up here yet are left out, the user sees shared data until the
+	// institution is created with its upstream ID and the next sync runs
+	if user.FinancialInstitutionID != nil {
+		var count int64
+		if err := tx.Model(&models.FinancialInstitution{}).Where("id = ?", *user.FinancialInstitutionID).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check financial institution: %w", err)
+		}
+		if count == 0 {
+			log.Printf("Warning: user %d has unknown financial institution %d, storing none.\n", user.ID, *user.FinancialInstitutionID)
+			user.FinancialInstitutionID = nil
+		}
+	}
+
 	// Use Clauses(clause.OnConflict) for Upsert
 	err = tx.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "id"}}, // Conflict on primary key
-		DoUpdates: clause.AssignmentColumns([]string{ // List columns to update on conflict
-			"name", "email", "status", "phone", "financial_institution_id", "role",
+		DoUpdates: append(clause.AssignmentColumns([]string{ // List columns to update on conflict
+			"status", "phone",
 			"signature_storage_url", "profile_image_storage_url", "updated_at",
-			"email_verified_at", "password_last_updated_at", // Add other updatable fields
+			"email_verified_at", // Add other updatable fields
 		}),
+			// A user who can log in here keeps their email and name, otherwise whoever holds the
+			// upstream account with the same ID could take over the login
+			clause.Assignment{Column: clause.Column{Name: "name"}, Value: gorm.Expr("CASE WHEN " + userHasLocalLogin + " THEN users.name ELSE excluded.name END")},
+			clause.Assignment{Column: clause.Column{Name: "email"}, Value: gorm.Expr("CASE WHEN " + userHasLocalLogin + " THEN users.email ELSE excluded.email END")},
+			// Upstream owns the role and the institution until they are set here
+			clause.Assignment{Column: clause.Column{Name: "financial_institution_id"}, Value: gorm.Expr("CASE WHEN users.local_institution THEN users.financial_institution_id ELSE excluded.financial_institution_id END")},
+			clause.Assignment{Column: clause.Column{Name: "role"}, Value: gorm.Expr("CASE WHEN users.local_role THEN users.role ELSE excluded.role END")},
+			// Keep the later of the two, a password set here must not be rolled back by the upstream value
+			clause.Assignment{Column: clause.Column{Name: "password_last_updated_at"}, Value: gorm.Expr("GREATEST(users.password_last_updated_at, excluded.password_last_updated_at)")},
+		),
 	}).Create(&user).Error // Create attempts insert, OnConflict handles update
 
 	if err != nil {
@@ -433,6 +529,9 @@ func (s *SyncService) upsertAgent(tx *gorm.DB, extAgent *schema.ExternalAgent, u
 			"account_number", "account_type", "account_branch", "linkedin",
 			"address", "coverage_area", "updated_at",
 		}),
+		// An agent created or edited here keeps its profile, otherwise the next sync would undo
+		// the edits made through PUT /agents/:id
+		Where: clause.Where{Exprs: []clause.Expression{gorm.Expr("NOT agents.local_edit")}},
 	}).Create(&agent).Error
 
 	if err != nil {
@@ -460,6 +559,7 @@ func (s *SyncService) upsertLocation(tx *gorm.DB, extLocation *schema.ExternalLo
 		ZoneCategory:  extLocation.ZoneCategory,
 		Zoning:        extLocation.Zoning,
 	}
+	location.Lat, location.Lng = utils.ParseLatLng(extLocation.Latitude, extLocation.Longitude)
 
 	// Parse time strings for Location
 	createdAt, err := parseAPITime(&extLocation.CreatedAt)
@@ -480,7 +580,7 @@ func (s *SyncService) upsertLocation(tx *gorm.DB, extLocation *schema.ExternalLo
 		// Columns:   []clause.Column{{Name: "property_id"}}, // Example: conflict on property_id
 		DoUpdates: clause.AssignmentColumns([]string{
 			"property_id", "region", "district", "area", "postcode", "sub_area",
-			"google_map_link", "latitude", "longitude", "zone_category", "zoning",
+			"google_map_link", "latitude", "longitude", "lat", "lng", "zone_category", "zoning",
 			"updated_at",
 		}),
 	}).Create(&location).Error
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUpsertUser_LeavesLocalLoginsAndRoles(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSyncService(db)
+
+	_, err := service.upsertUser(db, &schema.ExternalUser{ID: 1, Name: "Upstream", Email: "someone@example.com", Role: "admin"})
+	require.NoError(t, err)
+
+	statements := recorder.touching(`INSERT INTO "users"`)
+	require.Len(t, statements, 1)
+	sql := statements[0]
+	assert.Contains(t, sql, `"role"=CASE WHEN users.local_role THEN users.role ELSE excluded.role END`)
+	assert.NotContains(t, sql, `"role"="excluded"."role"`)
+	assert.Contains(t, sql, `"email"=CASE WHEN (users.password_hash IS NOT NULL AND users.password_hash <> '') THEN users.email ELSE excluded.email END`)
+	assert.Contains(t, sql, `"name"=CASE WHEN (users.password_hash IS NOT NULL AND users.password_hash <> '') THEN users.name ELSE excluded.name END`)
+	// The rest of the row still updates for users who can log in here
+	assert.Contains(t, sql, `"status"="excluded"."status"`)
+	assert.NotContains(t, sql, `WHERE users.password_hash`)
+}
+
+func TestUpsertUser_UpstreamStatusLocksOutLocalLogin(t *testing.T) {
+	db, mock := setupMockDB(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("change-me"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// The upsert writes the upstream status whether or not the user has a password here
+	mock.ExpectQuery(`INSERT INTO "users" .* ON CONFLICT \("id"\) DO UPDATE SET "status"="excluded"."status",.*RETURNING`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	_, err = NewSyncService(db).upsertUser(db, &schema.ExternalUser{ID: 12, Email: "valuer@example.com", Status: "inactive", Role: models.RoleValuer})
+	require.NoError(t, err)
+
+	// So the stored row, password and all, is inactive on the next login
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "status", "password_hash"}).
+			AddRow(12, "valuer@example.com", models.RoleValuer, "inactive", string(hash)))
+	auth := &AuthService{DB: db, secret: []byte("test-secret"), accessTTL: time.Minute, refreshTTL: time.Hour}
+	_, err = auth.Login(&schema.LoginRequest{Email: "valuer@example.com", Password: "change-me"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Your account is not active")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertUser_UnknownInstitution(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSyncService(db)
+
+	// No institutions stored, so the upstream one is left out
+	user, err := service.upsertUser(db, &schema.ExternalUser{ID: 1, Email: "someone@example.com", FinancialInstitutionID: uintPtr(bankA)})
+	require.NoError(t, err)
+	assert.Nil(t, user.FinancialInstitutionID)
+
+	require.NotEmpty(t, recorder.touching(`FROM "financial_institutions" WHERE id = 7`))
+	statements := recorder.touching(`INSERT INTO "users"`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `"financial_institution_id"=CASE WHEN users.local_institution THEN users.financial_institution_id ELSE excluded.financial_institution_id END`)
+}
+
+func TestUpsertAgent_LeavesLocalEdits(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewSyncService(db)
+
+	_, err := service.upsertAgent(db, &schema.ExternalAgent{ID: 3, UserID: "12", AgentType: "Company"}, 12)
+	require.NoError(t, err)
+
+	statements := recorder.touching(`INSERT INTO "agents"`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `"coverage_area"="excluded"."coverage_area"`)
+	assert.Contains(t, statements[0], `WHERE NOT agents.local_edit`)
+}
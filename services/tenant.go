@@ -0,0 +1,182 @@
+// services/tenant.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/hopekali04/valuations/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantField is the field that marks a model's rows as belonging to a financial institution.
+// Rows without one are shared market data, everyone sees them.
+const (
+	tenantField         = "FinancialInstitutionID"
+	tenantScopedSetting = "tenant:scoped"
+)
+
+// Tenant is whose data a request may see. The zero value sees shared rows only.
+type Tenant struct {
+	InstitutionID *uint // The principal's financial institution, nil for none
+	AllTenants    bool  // Admins see every institution's rows
+}
+
+// TenantForUser is the tenant of a logged in user. AuthService.Authenticate has already refused
+// users of an inactive institution.
+func TenantForUser(user *models.User) Tenant {
+	return Tenant{InstitutionID: user.FinancialInstitutionID, AllTenants: HasPermission(user.Role, PermAllTenants)}
+}
+
+// TenantForAPIKey is the tenant of an API key. Keys never see across institutions, and
+// APIKeyService.Authenticate has already refused those of an inactive one.
+func TenantForAPIKey(key *models.APIKey) Tenant {
+	return Tenant{InstitutionID: key.FinancialInstitutionID}
+}
+
+// checkInstitutionActive refuses a principal whose financial institution is inactive or gone.
+// Principals without one only see shared rows, and admins see every institution anyway.
+func checkInstitutionActive(db *gorm.DB, institutionID *uint) error {
+	if institutionID == nil {
+		return nil
+	}
+	var institution models.FinancialInstitution
+	err := db.Select("id", "active").First(&institution, *institutionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !institution.Active) {
+		return unauthorized("Your financial institution is not active")
+	}
+	if err != nil {
+		return fmt.Errorf("database error retrieving financial institution: %w", err)
+	}
+	return nil
+}
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context whose queries RegisterTenantScope limits to the tenant's rows.
+func WithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant stored by WithTenant. Without one nothing is filtered,
+// which is what the sync and other background jobs need.
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	if ctx == nil {
+		return Tenant{}, false
+	}
+	tenant, ok := ctx.Value(tenantContextKey{}).(Tenant)
+	return tenant, ok
+}
+
+// tenantCondition is the condition limiting table to the tenant's rows and the shared ones.
+// It reports false when nothing needs filtering.
+func tenantCondition(ctx context.Context, table string) (clause.Expr, bool) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok || tenant.AllTenants {
+		return clause.Expr{}, false
+	}
+	column := table + ".financial_institution_id"
+	if tenant.InstitutionID == nil {
+		return clause.Expr{SQL: column + " IS NULL"}, true
+	}
+	return clause.Expr{SQL: "(" + column + " IS NULL OR " + column + " = ?)", Vars: []interface{}{*tenant.InstitutionID}}, true
+}
+
+// tenantExpr is tenantCondition for raw SQL, which the callbacks never see. It is TRUE when
+// nothing needs filtering.
+func tenantExpr(db *gorm.DB, table string) clause.Expr {
+	if cond, ok := tenantCondition(db.Statement.Context, table); ok {
+		return cond
+	}
+	return clause.Expr{SQL: "TRUE"}
+}
+
+// isTenantScoped reports whether queries on db are limited to one tenant.
+func isTenantScoped(db *gorm.DB) bool {
+	_, ok := tenantCondition(db.Statement.Context, "")
+	return ok
+}
+
+// visiblePropertyIDs is a subquery of the properties the tenant on db may see, for tables that
+// only point at properties, like quality issues.
+func visiblePropertyIDs(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.Property{}).Select("properties.id")
+}
+
+// sharedOnly limits db to shared rows whoever is asking. Used for what is computed once for
+// everyone, like the price index, so no institution's private figures leak into it.
+func sharedOnly(db *gorm.DB) *gorm.DB {
+	return db.WithContext(WithTenant(db.Statement.Context, Tenant{}))
+}
+
+// allTenants lifts the tenant filter on db. Only for checks that reveal nothing of the rows,
+// like whether an ID is taken.
+func allTenants(db *gorm.DB) *gorm.DB {
+	return db.WithContext(WithTenant(db.Statement.Context, Tenant{AllTenants: true}))
+}
+
+// RegisterTenantScope adds callbacks that limit every query, update and delete on models with
+// a FinancialInstitutionID to the tenant in the statement's context, and stamp new rows with
+// the tenant's institution. Raw SQL is not covered, use tenantExpr there.
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeToTenant); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", scopeToTenant); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scopeToTenant); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeToTenant); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", stampTenant); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+	return nil
+}
+
+func scopeToTenant(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 || stmt.Schema.LookUpField(tenantField) == nil {
+		return
+	}
+	// Count followed by Find runs the same statement twice, filter it once
+	if _, scoped := stmt.Settings.LoadOrStore(tenantScopedSetting, true); scoped {
+		return
+	}
+	if cond, ok := tenantCondition(stmt.Context, stmt.Quote(stmt.Table)); ok {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{cond}})
+	}
+}
+
+func stampTenant(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	field := stmt.Schema.LookUpField(tenantField)
+	tenant, ok := TenantFromContext(stmt.Context)
+	if field == nil || !ok || tenant.InstitutionID == nil {
+		return
+	}
+
+	stamp := func(row reflect.Value) {
+		if _, isZero := field.ValueOf(stmt.Context, row); isZero {
+			db.AddError(field.Set(stmt.Context, row, *tenant.InstitutionID))
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			stamp(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(stmt.ReflectValue)
+	}
+}
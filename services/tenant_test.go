@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/config"
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	bankA uint = 7
+	bankB uint = 9
+)
+
+// setupTenantDB returns a recording DB with the tenant scope registered.
+func setupTenantDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	gormDB, recorder := setupRecordingDB(t)
+	require.NoError(t, RegisterTenantScope(gormDB))
+	return gormDB, recorder
+}
+
+func tenantContext(institutionID uint) context.Context {
+	return WithTenant(context.Background(), Tenant{InstitutionID: &institutionID})
+}
+
+// assertOnlyTenant checks every statement on table is limited to the shared rows and bank A's.
+func assertOnlyTenant(t *testing.T, statements []string, column string) {
+	t.Helper()
+	require.NotEmpty(t, statements)
+	for _, sql := range statements {
+		assert.Contains(t, sql, "("+column+" IS NULL OR "+column+" = 7)", sql)
+		assert.NotContains(t, sql, "= 9", sql)
+	}
+}
+
+func TestTenantScope_ListProperties(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+
+	_, _, err := service.GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+
+	statements := recorder.touching(`FROM "properties"`)
+	assertOnlyTenant(t, statements, `"properties".financial_institution_id`)
+	for _, sql := range statements {
+		assert.Equal(t, 1, strings.Count(sql, "financial_institution_id IS NULL"), "the count and the page share a statement, filter it once: %s", sql)
+	}
+}
+
+func TestTenantScope_ListCursorAndBatch(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+
+	_, _, err := service.GetPropertiesByCursor(schema.CursorRequest{Limit: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+	_, _, err = service.GetPropertiesByIDs([]uint{1, 2}, schema.PropertyFilter{})
+	require.NoError(t, err)
+
+	assertOnlyTenant(t, recorder.touching(`FROM "properties"`), `"properties".financial_institution_id`)
+}
+
+func TestTenantScope_SearchProperties(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+
+	// No full-text hits, so the trigram fallback runs as well
+	_, _, err := service.SearchProperties("lilongwe", schema.PaginationRequest{Page: 1, PageSize: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+	assertOnlyTenant(t, recorder.touching(`FROM "properties"`), `"properties".financial_institution_id`)
+}
+
+func TestTenantScope_SearchRawSQL(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+
+	_, err := service.SearchMatches("lilongwe", []uint{1, 2})
+	require.NoError(t, err)
+	_, err = service.SuggestCompletions("lil", 5)
+	require.NoError(t, err)
+
+	statements := recorder.touching("FROM properties")
+	assertOnlyTenant(t, statements, "properties.financial_institution_id")
+	require.Len(t, statements, 2)
+	// One condition for each of the five kinds of suggestion
+	assert.Equal(t, 5, strings.Count(statements[1], "financial_institution_id IS NULL"))
+}
+
+func TestTenantScope_MarketStats(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewStatsService(db).WithContext(tenantContext(bankA))
+
+	_, err := service.GetMarketStats(schema.PropertyFilter{}, nil, nil)
+	require.NoError(t, err)
+	assertOnlyTenant(t, recorder.touching(`FROM "properties"`), `"properties".financial_institution_id`)
+}
+
+func TestTenantScope_Valuations(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewValuationService(db).WithContext(tenantContext(bankA))
+
+	_, _, err := service.GetAllValuations(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.ValuationFilter{})
+	require.NoError(t, err)
+	_, err = service.GetValuationByID(3)
+	assert.Error(t, err) // No rows, so not found
+
+	assertOnlyTenant(t, recorder.touching(`FROM "valuations"`), `"valuations".financial_institution_id`)
+}
+
+func TestTenantScope_AVMEstimateProperty(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	propertyID := uint(3)
+
+	// Another bank's property is not found rather than valued
+	_, err := NewAVMService(db).WithContext(tenantContext(bankA)).estimateInput(&schema.AVMEstimateRequest{PropertyID: &propertyID})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Property")
+
+	assertOnlyTenant(t, recorder.touching(`FROM "properties"`), `"properties".financial_institution_id`)
+}
+
+func TestTenantScope_QualityIssuesFollowProperties(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewQualityService(db).WithContext(tenantContext(bankA))
+
+	_, _, err := service.GetIssues(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.QualityIssueFilter{})
+	require.NoError(t, err)
+
+	statements := recorder.touching(`FROM "quality_issues"`)
+	require.NotEmpty(t, statements)
+	for _, sql := range statements {
+		assert.Contains(t, sql, `property_id IN (SELECT properties.id FROM "properties" WHERE ("properties".financial_institution_id IS NULL OR "properties".financial_institution_id = 7))`, sql)
+	}
+}
+
+func TestTenantScope_OutlierBounds(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	propertyID := uint(3)
+
+	// A new listing is only compared with the listings its bank can see
+	_, err := findOutliers(db.WithContext(tenantContext(bankA)), config.QualityConfig{MinGroupSize: 5, IQRMultiplier: 1.5},
+		clause.Expr{SQL: "grouped.id = ?", Vars: []interface{}{propertyID}})
+	require.NoError(t, err)
+
+	statements := recorder.touching("FROM properties JOIN locations")
+	require.Len(t, statements, len(outlierMetrics))
+	assertOnlyTenant(t, statements, "properties.financial_institution_id")
+}
+
+func TestTenantScope_OutlierFullScan(t *testing.T) {
+	db, mock := setupMockDB(t)
+	noRows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"property_id"}) }
+	settings := qualitySettings()
+
+	// Shared listings are only compared with shared ones
+	for range outlierMetrics {
+		mock.ExpectQuery(`AND properties\.financial_institution_id IS NULL\s+\), bounds AS .* AND grouped\.financial_institution_id IS NULL`).
+			WithArgs(settings.MinGroupSize, settings.IQRMultiplier, settings.IQRMultiplier).
+			WillReturnRows(noRows())
+	}
+	mock.ExpectQuery(`SELECT DISTINCT "financial_institution_id" FROM "properties" WHERE financial_institution_id IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"financial_institution_id"}).AddRow(bankA).AddRow(bankB))
+	// Each bank's listings with the shared ones and its own, never another bank's
+	for _, bank := range []uint{bankA, bankB} {
+		for i := range outlierMetrics {
+			rows := noRows()
+			if bank == bankB && i == len(outlierMetrics)-1 {
+				rows = sqlmock.NewRows([]string{"property_id", "value", "q1", "q3", "group_size", "district", "property_type", "listing_type"}).
+					AddRow(42, 900000, 100000, 150000, 6, "AREA 47", "house", "sale")
+			}
+			mock.ExpectQuery(`\(properties\.financial_institution_id IS NULL OR properties\.financial_institution_id = \$1\).* AND grouped\.financial_institution_id = \$5`).
+				WithArgs(bank, settings.MinGroupSize, settings.IQRMultiplier, settings.IQRMultiplier, bank).
+				WillReturnRows(rows)
+		}
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "quality_issues" WHERE kind = \$1`).
+		WithArgs(models.QualityKindOutlier).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO "quality_issues"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	found, err := NewQualityService(db).ScanOutliers()
+	require.NoError(t, err)
+	assert.Equal(t, 1, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantScope_NoInstitutionSeesSharedOnly(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(WithTenant(context.Background(), Tenant{}))
+
+	_, _, err := service.GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+
+	statements := recorder.touching(`FROM "properties"`)
+	require.NotEmpty(t, statements)
+	for _, sql := range statements {
+		assert.Contains(t, sql, `"properties".financial_institution_id IS NULL`, sql)
+		assert.NotContains(t, sql, "financial_institution_id =", sql)
+	}
+}
+
+func TestTenantScope_AdminSeesAllTenants(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	admin := &models.User{ID: 1, Role: "Admin", FinancialInstitutionID: uintPtr(bankB)}
+	service := NewPropertyService(db).WithContext(WithTenant(context.Background(), TenantForUser(admin)))
+
+	_, _, err := service.GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+	_, err = service.SuggestCompletions("lil", 5)
+	require.NoError(t, err)
+
+	statements := append(recorder.touching(`FROM "properties"`), recorder.touching("FROM properties")...)
+	require.NotEmpty(t, statements)
+	for _, sql := range statements {
+		assert.NotContains(t, sql, "financial_institution_id", sql)
+	}
+}
+
+func TestTenantForPrincipals(t *testing.T) {
+	valuer := &models.User{Role: models.RoleValuer, FinancialInstitutionID: uintPtr(bankA)}
+	assert.Equal(t, Tenant{InstitutionID: uintPtr(bankA)}, TenantForUser(valuer))
+
+	// Keys never cross institutions, whoever issued them
+	key := &models.APIKey{FinancialInstitutionID: uintPtr(bankB)}
+	assert.Equal(t, Tenant{InstitutionID: uintPtr(bankB)}, TenantForAPIKey(key))
+	assert.Equal(t, Tenant{}, TenantForAPIKey(&models.APIKey{}))
+}
+
+func TestTenantScope_CreateStampsInstitution(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	tenantDB := db.WithContext(tenantContext(bankA))
+
+	valuation := models.Valuation{PropertyID: 1, Method: models.ValuationMethodCost, ValuationDate: time.Now()}
+	require.NoError(t, tenantDB.Create(&valuation).Error)
+	require.NotNil(t, valuation.FinancialInstitutionID)
+	assert.Equal(t, bankA, *valuation.FinancialInstitutionID)
+
+	// An institution set explicitly is kept
+	other := models.Valuation{PropertyID: 1, FinancialInstitutionID: uintPtr(bankB)}
+	require.NoError(t, tenantDB.Create(&other).Error)
+	assert.Equal(t, bankB, *other.FinancialInstitutionID)
+
+	// Without a tenant, e.g. during a sync, rows stay shared
+	shared := models.Valuation{PropertyID: 1}
+	require.NoError(t, db.Create(&shared).Error)
+	assert.Nil(t, shared.FinancialInstitutionID)
+
+	assert.Len(t, recorder.touching(`INSERT INTO "valuations"`), 3)
+}
+
+func TestTenantScope_BackgroundJobsUnfiltered(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db)
+
+	_, _, err := service.GetAllProperties(schema.PaginationRequest{Page: 1, PageSize: 10}, schema.PropertyFilter{})
+	require.NoError(t, err)
+	for _, sql := range recorder.touching(`FROM "properties"`) {
+		assert.NotContains(t, sql, "financial_institution_id", sql)
+	}
+}
+
+func TestCheckInstitutionActive(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		wantErr bool
+	}{
+		{"active", sqlmock.NewRows([]string{"id", "active"}).AddRow(bankA, true), false},
+		{"inactive", sqlmock.NewRows([]string{"id", "active"}).AddRow(bankA, false), true},
+		{"gone", sqlmock.NewRows([]string{"id", "active"}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			mock.ExpectQuery(`SELECT "id","active" FROM "financial_institutions" WHERE "financial_institutions"."id" = \$1`).
+				WithArgs(bankA, 1).
+				WillReturnRows(tt.rows)
+
+			err := checkInstitutionActive(db, uintPtr(bankA))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	// Principals without an institution see shared rows only, nothing to check
+	db, _ := setupMockDB(t)
+	assert.NoError(t, checkInstitutionActive(db, nil))
+}
+
+func TestCreateInstitution_KeepsInactive(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	active := false
+
+	_, err := NewFinancialInstitutionService(db).CreateInstitution(&schema.FinancialInstitutionRequest{Name: "Closed Bank", Code: "cb", Active: &active})
+	require.NoError(t, err)
+
+	statements := recorder.touching(`INSERT INTO "financial_institutions"`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], "'CB',false", statements[0])
+}
+
+func TestCreateInstitution_UpstreamID(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewFinancialInstitutionService(db)
+
+	institution, err := service.CreateInstitution(&schema.FinancialInstitutionRequest{ID: uintPtr(bankA), Name: "Bank A", Code: "BA"})
+	require.NoError(t, err)
+	assert.Equal(t, bankA, institution.ID)
+	statements := recorder.touching(`INSERT INTO "financial_institutions"`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `"updated_at","id") VALUES`, statements[0])
+	assert.Contains(t, statements[0], ",7) RETURNING", statements[0])
+
+	// Local IDs are kept for institutions unknown upstream
+	_, err = service.CreateInstitution(&schema.FinancialInstitutionRequest{ID: uintPtr(LocalIDStart), Name: "Bank B", Code: "BB"})
+	assert.Error(t, err)
+}
+
+func TestTenantScope_CreatePropertyChecksEveryID(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+	service := NewPropertyService(db).WithContext(tenantContext(bankA))
+
+	// The mock has no rows, so the create goes ahead, only the ID check matters here
+	_, _ = service.CreateProperty(&schema.CreatePropertyRequest{ID: 99})
+
+	statements := recorder.touching(`SELECT "id" FROM "properties"`)
+	require.NotEmpty(t, statements)
+	assert.NotContains(t, statements[0], "financial_institution_id", statements[0])
+}
+
+func TestDuplicateScan_PairsWithinOneInstitution(t *testing.T) {
+	db, recorder := setupTenantDB(t)
+
+	_, err := NewDuplicateService(db).Scan()
+	require.NoError(t, err)
+
+	statements := recorder.touching(`JOIN locations lb`)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `b.financial_institution_id IS NOT DISTINCT FROM a.financial_institution_id`)
+}
+
+func TestMergeCandidate_RefusesAcrossInstitutions(t *testing.T) {
+	tests := []struct {
+		name              string
+		canonical, merged interface{}
+	}{
+		{name: "two banks", canonical: bankA, merged: bankB},
+		{name: "private into shared", canonical: nil, merged: bankA},
+		{name: "shared into private", canonical: bankA, merged: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT \* FROM "duplicate_candidates" .* FOR UPDATE`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "duplicate_id", "status"}).
+					AddRow(3, 1, 2, models.DuplicateStatusConfirmed))
+			for _, p := range []struct {
+				id          uint
+				institution interface{}
+			}{{1, tt.canonical}, {2, tt.merged}} {
+				mock.ExpectQuery(`SELECT \* FROM "properties" WHERE "properties"."id" = \$1`).
+					WithArgs(p.id, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "financial_institution_id"}).AddRow(p.id, p.institution))
+				mock.ExpectQuery(`FROM "cover_photos"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`FROM "locations"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			}
+			// Nothing is copied, moved or removed
+			mock.ExpectRollback()
+
+			_, err := NewDuplicateService(db).MergeCandidate(3, &schema.DuplicateMergeRequest{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "different financial institutions")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -90,6 +90,11 @@ func expectPreloads(mock sqlmock.Sqlmock, propertyIDs []uint) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "cover_photos" WHERE "cover_photos"."property_id" IN ($1)`)).
 		WithArgs(propertyIDs[0]). // Adjust Args based on how GORM batches
 		WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "url"}).AddRow(1, propertyIDs[0], "http://example.com/photo.jpg"))
+
+	// Mock QualityIssues Preload, no issues flagged
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quality_issues" WHERE "quality_issues"."property_id" IN ($1)`)).
+		WithArgs(propertyIDs[0]).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "code"}))
 }
 
 // --- Tests for GetAllProperties ---
@@ -227,14 +232,14 @@ func TestGetAllProperties_FilterByDistrict(t *testing.T) {
 	filter := schema.PropertyFilter{District: &district}
 
 	// Mock Count
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "properties" JOIN locations ON locations.property_id = properties.id WHERE locations.district ILIKE $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "properties" WHERE properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE $1)`)).
 		WithArgs("%" + district + "%").
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
 	// Mock Data Fetch
 	mockProperties := []models.Property{{ID: 6}} // Location data handled by preload mock
 	rows := createMockPropertyRows(mockProperties...)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "properties" JOIN locations ON locations.property_id = properties.id WHERE locations.district ILIKE $1 ORDER BY created_at DESC LIMIT 10`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "properties" WHERE properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE $1) ORDER BY created_at DESC LIMIT 10`)).
 		WithArgs("%" + district + "%").
 		WillReturnRows(rows)
 
@@ -297,8 +302,8 @@ func TestGetAllProperties_AllFiltersCombined(t *testing.T) {
 	}
 
 	// Construct expected SQL part (order might vary slightly, use regex if needed)
-	expectedCountSQL := `SELECT count(*) FROM "properties" JOIN locations ON locations.property_id = properties.id WHERE properties.owner_name ILIKE $1 AND properties.property_type = $2 AND properties.price >= $3 AND properties.agent_id = $4 AND locations.district ILIKE $5`
-	expectedSelectSQL := `SELECT * FROM "properties" JOIN locations ON locations.property_id = properties.id WHERE properties.owner_name ILIKE $1 AND properties.property_type = $2 AND properties.price >= $3 AND properties.agent_id = $4 AND locations.district ILIKE $5 ORDER BY created_at DESC LIMIT 10`
+	expectedCountSQL := `SELECT count(*) FROM "properties" WHERE properties.owner_name ILIKE $1 AND properties.property_type = $2 AND properties.price >= $3 AND properties.agent_id = $4 AND properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE $5)`
+	expectedSelectSQL := `SELECT * FROM "properties" WHERE properties.owner_name ILIKE $1 AND properties.property_type = $2 AND properties.price >= $3 AND properties.agent_id = $4 AND properties.id IN (SELECT property_id FROM locations WHERE locations.district ILIKE $5) ORDER BY created_at DESC LIMIT 10`
 
 	// Mock Count
 	mock.ExpectQuery(regexp.QuoteMeta(expectedCountSQL)).
@@ -352,73 +357,3 @@ func TestGetAllProperties_NoResults(t *testing.T) {
 	assert.Len(t, properties, 0) // Expect empty slice
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
-
-// --- Tests for SearchProperties ---
-
-func TestSearchProperties_Success(t *testing.T) {
-	service, mock := setupServiceWithMock(t)
-	pagination := schema.PaginationRequest{Page: 1, PageSize: 5}
-	searchTerm := "Zingwangwa"
-
-	// Regex for JOINs and WHERE clauses in search
-	expectedJoins := `JOIN locations ON locations.property_id = properties.id LEFT JOIN agents ON agents.id = properties.agent_id LEFT JOIN users ON users.id = agents.user_id`
-	_ = `properties.owner_name ILIKE $1 OR properties.description ILIKE $2 OR properties.property_design ILIKE $3 OR locations.district ILIKE $4 OR locations.area ILIKE $5 OR locations.sub_area ILIKE $6 OR users.name ILIKE $7`
-	searchArg := "%" + searchTerm + "%"
-
-	// Mock Count Query
-	// Using regex because the exact query string might be complex
-	mock.ExpectQuery(`SELECT count\(\*\) FROM "properties" `+expectedJoins+` WHERE \(.*`+regexp.QuoteMeta(searchTerm)+`.*\)`). // Check for joins and WHERE ILIKE
-																	WithArgs(searchArg, searchArg, searchArg, searchArg, searchArg, searchArg, searchArg). // 7 arguments for ILIKE
-																	WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))                           // Found 1 match
-
-	// Mock Data Query
-	mockProperties := []models.Property{{ID: 154, OwnerName: "Some Match"}}
-	rows := createMockPropertyRows(mockProperties...)
-	// Using regex again for flexibility
-	mock.ExpectQuery(`SELECT .* FROM "properties" `+expectedJoins+` WHERE \(.*`+regexp.QuoteMeta(searchTerm)+`.*\) ORDER BY properties.created_at DESC LIMIT 5`).
-		WithArgs(searchArg, searchArg, searchArg, searchArg, searchArg, searchArg, searchArg).
-		WillReturnRows(rows)
-
-	expectPreloads(mock, []uint{154})
-
-	// --- Act ---
-	properties, total, err := service.SearchProperties(searchTerm, pagination)
-
-	// --- Assert ---
-	require.NoError(t, err)
-	assert.Equal(t, int64(1), total)
-	assert.Len(t, properties, 1)
-	assert.Equal(t, uint(154), properties[0].ID)
-	assert.NoError(t, mock.ExpectationsWereMet())
-}
-
-func TestSearchProperties_NoResults(t *testing.T) {
-	service, mock := setupServiceWithMock(t)
-	pagination := schema.PaginationRequest{Page: 1, PageSize: 5}
-	searchTerm := "xyzNonExistent123"
-	searchArg := "%" + searchTerm + "%"
-
-	expectedJoins := `JOIN locations ON locations.property_id = properties.id LEFT JOIN agents ON agents.id = properties.agent_id LEFT JOIN users ON users.id = agents.user_id`
-
-	// Mock Count Query returning 0
-	mock.ExpectQuery(`SELECT count\(\*\) FROM "properties" `+expectedJoins+` WHERE \(.*`+regexp.QuoteMeta(searchTerm)+`.*\)`).
-		WithArgs(searchArg, searchArg, searchArg, searchArg, searchArg, searchArg, searchArg).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
-
-		// Mock Data Query returning no rows
-	rows := createMockPropertyRows() // Empty
-	mock.ExpectQuery(`SELECT .* FROM "properties" `+expectedJoins+` WHERE \(.*`+regexp.QuoteMeta(searchTerm)+`.*\) ORDER BY properties.created_at DESC LIMIT 5`).
-		WithArgs(searchArg, searchArg, searchArg, searchArg, searchArg, searchArg, searchArg).
-		WillReturnRows(rows)
-
-	// No preloads expected
-
-	// --- Act ---
-	properties, total, err := service.SearchProperties(searchTerm, pagination)
-
-	// --- Assert ---
-	require.NoError(t, err)
-	assert.Equal(t, int64(0), total)
-	assert.Len(t, properties, 0)
-	assert.NoError(t, mock.ExpectationsWereMet())
-}
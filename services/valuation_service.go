@@ -0,0 +1,342 @@
+// services/valuation_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hopekali04/valuations/models"
+	"github.com/hopekali04/valuations/schema"
+	"github.com/hopekali04/valuations/utils"
+	"gorm.io/gorm"
+)
+
+type ValuationService struct {
+	DB *gorm.DB
+}
+
+func NewValuationService(db *gorm.DB) *ValuationService {
+	return &ValuationService{DB: db}
+}
+
+func (s *ValuationService) WithContext(ctx context.Context) *ValuationService {
+	scoped := *s
+	scoped.DB = s.DB.WithContext(ctx)
+	return &scoped
+}
+
+// --- Create Operations ---
+
+// CreateValuation records a new valuation, checking the property and parent valuation exist.
+func (s *ValuationService) CreateValuation(req *schema.CreateValuationRequest) (*models.Valuation, error) {
+	valuation := models.Valuation{
+		PropertyID:        req.PropertyID,
+		ValuerID:          req.ValuerID,
+		Method:            req.Method,
+		MarketValue:       req.MarketValue,
+		ForcedSaleValue:   req.ForcedSaleValue,
+		InsuranceValue:    req.InsuranceValue,
+		Notes:             req.Notes,
+		ParentValuationID: req.ParentValuationID,
+		ValuationDate:     time.Now(),
+	}
+	if req.ValuationDate != nil {
+		valuation.ValuationDate = *req.ValuationDate
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return createValuation(tx, &valuation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &valuation, nil
+}
+
+// createValuation validates and inserts a valuation inside an existing transaction.
+// The cost and income approach services use it to record their results.
+func createValuation(tx *gorm.DB, valuation *models.Valuation) error {
+	var count int64
+	if err := tx.Model(&models.Property{}).Where("id = ?", valuation.PropertyID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check property: %w", err)
+	}
+	if count == 0 {
+		return utils.NewNotFoundError("Property")
+	}
+
+	if valuation.ParentValuationID != nil {
+		if err := checkParentValuation(tx, valuation.PropertyID, *valuation.ParentValuationID, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Create(valuation).Error; err != nil {
+		return fmt.Errorf("failed to create valuation: %w", err)
+	}
+	return nil
+}
+
+// --- Read Operations ---
+
+// GetValuationByID retrieves a single valuation.
+func (s *ValuationService) GetValuationByID(id uint) (*models.Valuation, error) {
+	var valuation models.Valuation
+	err := s.DB.First(&valuation, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Valuation")
+		}
+		return nil, fmt.Errorf("database error retrieving valuation: %w", err)
+	}
+	return &valuation, nil
+}
+
+// GetAllValuations retrieves valuations with pagination and filtering, newest first.
+func (s *ValuationService) GetAllValuations(pag schema.PaginationRequest, filter schema.ValuationFilter) ([]models.Valuation, int64, error) {
+	var valuations []models.Valuation
+	var totalItems int64
+
+	query := s.DB.Model(&models.Valuation{})
+	if filter.PropertyID != nil {
+		query = query.Where("property_id = ?", *filter.PropertyID)
+	}
+	if filter.ValuerID != nil {
+		query = query.Where("valuer_id = ?", *filter.ValuerID)
+	}
+	if filter.Method != nil && *filter.Method != "" {
+		query = query.Where("method = ?", *filter.Method)
+	}
+
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count valuations: %w", err)
+	}
+
+	err := query.Scopes(utils.PaginateScope(pag.Page, pag.PageSize)).
+		Order("valuation_date DESC, id DESC").
+		Find(&valuations).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve valuations: %w", err)
+	}
+	return valuations, totalItems, nil
+}
+
+// GetValuationTree returns every valuation of a property arranged by ParentValuationID.
+// Valuations whose parent is missing are treated as roots so nothing is hidden.
+func (s *ValuationService) GetValuationTree(propertyID uint) (*schema.ValuationTreeResponse, error) {
+	var count int64
+	if err := s.DB.Model(&models.Property{}).Where("id = ?", propertyID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check property: %w", err)
+	}
+	if count == 0 {
+		return nil, utils.NewNotFoundError("Property")
+	}
+
+	var valuations []models.Valuation
+	err := s.DB.Where("property_id = ?", propertyID).
+		Order("valuation_date ASC, id ASC").
+		Find(&valuations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve valuations: %w", err)
+	}
+
+	return &schema.ValuationTreeResponse{
+		PropertyID: propertyID,
+		Count:      len(valuations),
+		Valuations: buildValuationTree(valuations),
+	}, nil
+}
+
+func buildValuationTree(valuations []models.Valuation) []schema.ValuationNode {
+	byID := make(map[uint]bool, len(valuations))
+	for _, v := range valuations {
+		byID[v.ID] = true
+	}
+
+	children := make(map[uint][]models.Valuation)
+	var roots []models.Valuation
+	for _, v := range valuations {
+		if v.ParentValuationID != nil && byID[*v.ParentValuationID] && *v.ParentValuationID != v.ID {
+			children[*v.ParentValuationID] = append(children[*v.ParentValuationID], v)
+		} else {
+			roots = append(roots, v)
+		}
+	}
+
+	visited := make(map[uint]bool, len(valuations)) // Guards against cycles in old data
+	var build func(v models.Valuation) schema.ValuationNode
+	build = func(v models.Valuation) schema.ValuationNode {
+		visited[v.ID] = true
+		node := schema.ValuationNode{Valuation: v, Children: []schema.ValuationNode{}}
+		for _, child := range children[v.ID] {
+			if !visited[child.ID] {
+				node.Children = append(node.Children, build(child))
+			}
+		}
+		return node
+	}
+
+	nodes := make([]schema.ValuationNode, 0, len(roots))
+	for _, root := range roots {
+		nodes = append(nodes, build(root))
+	}
+
+	// Anything left unvisited is part of a cycle, surface it as a root rather than dropping it
+	var orphans []models.Valuation
+	for _, v := range valuations {
+		if !visited[v.ID] {
+			orphans = append(orphans, v)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].ID < orphans[j].ID })
+	for _, v := range orphans {
+		if !visited[v.ID] {
+			nodes = append(nodes, build(v))
+		}
+	}
+	return nodes
+}
+
+// --- Update Operations ---
+
+// UpdateValuation applies the fields present in the request.
+// A parent_valuation_id of 0 detaches the valuation from its parent.
+func (s *ValuationService) UpdateValuation(id uint, req *schema.UpdateValuationRequest) (*models.Valuation, error) {
+	var valuation models.Valuation
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&valuation, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Valuation")
+			}
+			return fmt.Errorf("database error retrieving valuation: %w", err)
+		}
+
+		updates := map[string]interface{}{}
+		if req.ValuerID != nil {
+			updates["valuer_id"] = *req.ValuerID
+		}
+		if req.ValuationDate != nil {
+			updates["valuation_date"] = *req.ValuationDate
+		}
+		if req.Method != nil {
+			updates["method"] = *req.Method
+		}
+		if req.MarketValue != nil {
+			updates["market_value"] = *req.MarketValue
+		}
+		if req.ForcedSaleValue != nil {
+			updates["forced_sale_value"] = *req.ForcedSaleValue
+		}
+		if req.InsuranceValue != nil {
+			updates["insurance_value"] = *req.InsuranceValue
+		}
+		if req.Notes != nil {
+			updates["notes"] = *req.Notes
+		}
+		if req.ParentValuationID != nil {
+			if *req.ParentValuationID == 0 {
+				updates["parent_valuation_id"] = nil
+			} else {
+				if err := checkParentValuation(tx, valuation.PropertyID, *req.ParentValuationID, valuation.ID); err != nil {
+					return err
+				}
+				updates["parent_valuation_id"] = *req.ParentValuationID
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&valuation).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update valuation: %w", err)
+		}
+		return tx.First(&valuation, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &valuation, nil
+}
+
+// --- Delete Operations ---
+
+// DeleteValuation removes a valuation that has no revaluations hanging off it.
+func (s *ValuationService) DeleteValuation(id uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var valuation models.Valuation
+		if err := tx.Select("id").First(&valuation, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Valuation")
+			}
+			return fmt.Errorf("database error retrieving valuation: %w", err)
+		}
+
+		var children int64
+		if err := tx.Model(&models.Valuation{}).Where("parent_valuation_id = ?", id).Count(&children).Error; err != nil {
+			return fmt.Errorf("failed to check child valuations: %w", err)
+		}
+		if children > 0 {
+			return utils.NewAPIError(http.StatusConflict, "Valuation has revaluations", fmt.Sprintf("%d valuation(s) use this valuation as their parent, delete or re-parent them first.", children))
+		}
+
+		// Keep the calculation detail but unlink it from the deleted record
+		if err := tx.Model(&models.CostValuation{}).Where("valuation_id = ?", id).Update("valuation_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unlink cost valuation: %w", err)
+		}
+		if err := tx.Model(&models.IncomeValuation{}).Where("valuation_id = ?", id).Update("valuation_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unlink income valuation: %w", err)
+		}
+		if err := tx.Delete(&models.Valuation{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete valuation: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkParentValuation makes sure a parent exists, belongs to the same property and
+// that linking to it would not create a cycle. childID is 0 for new valuations.
+func checkParentValuation(tx *gorm.DB, propertyID, parentID, childID uint) error {
+	if parentID == childID {
+		return utils.NewBadRequestError("A valuation cannot be its own parent")
+	}
+
+	var parent models.Valuation
+	if err := tx.Select("id", "property_id", "parent_valuation_id").First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewBadRequestError(fmt.Sprintf("Parent valuation %d does not exist", parentID))
+		}
+		return fmt.Errorf("database error retrieving parent valuation: %w", err)
+	}
+	if parent.PropertyID != propertyID {
+		return utils.NewBadRequestError(fmt.Sprintf("Parent valuation %d belongs to property %d, not %d", parentID, parent.PropertyID, propertyID))
+	}
+
+	if childID == 0 {
+		return nil // A new valuation has no descendants yet
+	}
+
+	// Walk up from the parent, if we reach the child the link would form a cycle
+	seen := map[uint]bool{}
+	current := parent
+	for current.ParentValuationID != nil {
+		if *current.ParentValuationID == childID {
+			return utils.NewBadRequestError("Parent valuation is a descendant of this valuation")
+		}
+		if seen[current.ID] {
+			break
+		}
+		seen[current.ID] = true
+		var next models.Valuation
+		if err := tx.Select("id", "property_id", "parent_valuation_id").First(&next, *current.ParentValuationID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return fmt.Errorf("database error walking valuation chain: %w", err)
+		}
+		current = next
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hopekali04/valuations/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+func valuationChain(mock sqlmock.Sqlmock, id, propertyID uint, parentID *uint) {
+	rows := sqlmock.NewRows([]string{"id", "property_id", "parent_valuation_id"})
+	if parentID != nil {
+		rows.AddRow(id, propertyID, *parentID)
+	} else {
+		rows.AddRow(id, propertyID, nil)
+	}
+	mock.ExpectQuery(`SELECT "id","property_id","parent_valuation_id" FROM "valuations" WHERE "valuations"."id" = \$1`).
+		WithArgs(id, 1).
+		WillReturnRows(rows)
+}
+
+func TestCheckParentValuation(t *testing.T) {
+	t.Run("own parent", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		err := checkParentValuation(db, 1, 5, 5)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "its own parent")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("parent on another property", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		valuationChain(mock, 2, 9, nil)
+		err := checkParentValuation(db, 1, 2, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "belongs to property 9")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing parent", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		mock.ExpectQuery(`FROM "valuations"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		err := checkParentValuation(db, 1, 2, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not exist")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("new valuation skips the walk", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		valuationChain(mock, 2, 1, uintPtr(1))
+		require.NoError(t, checkParentValuation(db, 1, 2, 0))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("descendant as parent", func(t *testing.T) {
+		// 1 <- 2 <- 3, re-parenting 1 under 3 would close the loop
+		db, mock := setupMockDB(t)
+		valuationChain(mock, 3, 1, uintPtr(2))
+		valuationChain(mock, 2, 1, uintPtr(1))
+		err := checkParentValuation(db, 1, 3, 1)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "descendant")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unrelated branch", func(t *testing.T) {
+		// 1 <- 2 and 1 <- 3, moving 3 under 2 is fine
+		db, mock := setupMockDB(t)
+		valuationChain(mock, 2, 1, uintPtr(1))
+		valuationChain(mock, 1, 1, nil)
+		require.NoError(t, checkParentValuation(db, 1, 2, 3))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("existing cycle above the parent ends the walk", func(t *testing.T) {
+		// 2 and 4 already point at each other, 3 is not part of it
+		db, mock := setupMockDB(t)
+		valuationChain(mock, 2, 1, uintPtr(4))
+		valuationChain(mock, 4, 1, uintPtr(2))
+		valuationChain(mock, 2, 1, uintPtr(4))
+		require.NoError(t, checkParentValuation(db, 1, 2, 3))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBuildValuationTree(t *testing.T) {
+	valuations := []models.Valuation{
+		{ID: 1},
+		{ID: 2, ParentValuationID: uintPtr(1)},
+		{ID: 3, ParentValuationID: uintPtr(1)},
+		{ID: 4, ParentValuationID: uintPtr(2)},
+		{ID: 5, ParentValuationID: uintPtr(99)}, // Parent deleted or on another property
+		{ID: 6, ParentValuationID: uintPtr(6)},  // Points at itself
+		{ID: 7, ParentValuationID: uintPtr(8)},  // 7 and 8 form a cycle
+		{ID: 8, ParentValuationID: uintPtr(7)},
+	}
+
+	nodes := buildValuationTree(valuations)
+
+	roots := make([]uint, 0, len(nodes))
+	for _, n := range nodes {
+		roots = append(roots, n.ID)
+	}
+	assert.Equal(t, []uint{1, 5, 6, 7}, roots, "orphans and cycles surface as roots")
+
+	require.Len(t, nodes[0].Children, 2)
+	assert.Equal(t, uint(2), nodes[0].Children[0].ID)
+	assert.Equal(t, uint(3), nodes[0].Children[1].ID)
+	require.Len(t, nodes[0].Children[0].Children, 1)
+	assert.Equal(t, uint(4), nodes[0].Children[0].Children[0].ID)
+
+	assert.Empty(t, nodes[2].Children)
+	require.Len(t, nodes[3].Children, 1, "the cycle is broken once every valuation is shown")
+	assert.Equal(t, uint(8), nodes[3].Children[0].ID)
+	assert.Empty(t, nodes[3].Children[0].Children)
+}
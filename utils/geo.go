@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0
+
+// ParseLatLng converts the free-text latitude/longitude strings we receive from
+// upstream into numbers. Both values are returned as nil unless the pair is a valid coordinate.
+func ParseLatLng(lat, lng *string) (*float64, *float64) {
+	if lat == nil || lng == nil {
+		return nil, nil
+	}
+	latVal, err := strconv.ParseFloat(strings.TrimSpace(*lat), 64)
+	if err != nil {
+		return nil, nil
+	}
+	lngVal, err := strconv.ParseFloat(strings.TrimSpace(*lng), 64)
+	if err != nil {
+		return nil, nil
+	}
+	if latVal < -90 || latVal > 90 || lngVal < -180 || lngVal > 180 {
+		return nil, nil
+	}
+	if latVal == 0 && lngVal == 0 { // Placeholder value seen in the upstream data, not a real location
+		return nil, nil
+	}
+	return &latVal, &lngVal
+}
+
+// HaversineKm returns the great-circle distance between two points in kilometres.
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// BoundingBox returns the lat/lng box that contains every point within radiusKm of the centre.
+// Used to narrow candidate rows in SQL before computing exact distances.
+func BoundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	latDelta := radiusKm / 111.0 // ~111km per degree of latitude
+	lngDelta := radiusKm / (111.0 * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	return lat - latDelta, lat + latDelta, lng - lngDelta, lng + lngDelta
+}
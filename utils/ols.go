@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrSingularMatrix is returned when X'X cannot be inverted, usually because two
+// features are perfectly collinear or there are more features than observations.
+var ErrSingularMatrix = errors.New("matrix is singular")
+
+// OLSResult is an ordinary least squares fit of y on the columns of X.
+type OLSResult struct {
+	Coefficients     []float64
+	StdErrors        []float64
+	XtXInverse       [][]float64 // Kept so callers can compute prediction intervals
+	ResidualVariance float64     // SSR / (n - k)
+	RSquared         float64
+	Observations     int
+}
+
+// FitOLS solves the normal equations (X'X)b = X'y. X must include an intercept
+// column if one is wanted.
+func FitOLS(X [][]float64, y []float64) (*OLSResult, error) {
+	n := len(X)
+	if n == 0 || n != len(y) {
+		return nil, errors.New("X and y must have the same, non-zero, number of rows")
+	}
+	k := len(X[0])
+	if n <= k {
+		return nil, ErrSingularMatrix
+	}
+
+	xtx := make([][]float64, k)
+	for i := range xtx {
+		xtx[i] = make([]float64, k)
+	}
+	xty := make([]float64, k)
+	for r, row := range X {
+		for i := 0; i < k; i++ {
+			xty[i] += row[i] * y[r]
+			for j := i; j < k; j++ {
+				xtx[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j < i; j++ {
+			xtx[i][j] = xtx[j][i]
+		}
+	}
+
+	inv, err := InvertMatrix(xtx)
+	if err != nil {
+		return nil, err
+	}
+
+	coef := make([]float64, k)
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			coef[i] += inv[i][j] * xty[j]
+		}
+	}
+
+	var mean float64
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(n)
+	var ssr, sst float64
+	for r, row := range X {
+		resid := y[r] - Dot(row, coef)
+		ssr += resid * resid
+		sst += (y[r] - mean) * (y[r] - mean)
+	}
+
+	sigma2 := ssr / float64(n-k)
+	stdErrs := make([]float64, k)
+	for i := 0; i < k; i++ {
+		stdErrs[i] = math.Sqrt(math.Max(inv[i][i]*sigma2, 0))
+	}
+	r2 := 0.0
+	if sst > 0 {
+		r2 = 1 - ssr/sst
+	}
+
+	return &OLSResult{
+		Coefficients:     coef,
+		StdErrors:        stdErrs,
+		XtXInverse:       inv,
+		ResidualVariance: sigma2,
+		RSquared:         r2,
+		Observations:     n,
+	}, nil
+}
+
+// InvertMatrix inverts a square matrix by Gauss-Jordan elimination with partial pivoting.
+func InvertMatrix(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	aug := make([][]float64, n)
+	scale := 0.0
+	for i := range a {
+		if len(a[i]) != n {
+			return nil, errors.New("matrix must be square")
+		}
+		aug[i] = make([]float64, 2*n)
+		copy(aug[i], a[i])
+		aug[i][n+i] = 1
+		for _, v := range a[i] {
+			scale = math.Max(scale, math.Abs(v))
+		}
+	}
+	tolerance := 1e-12 * math.Max(scale, 1)
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(aug[r][col]) > math.Abs(aug[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(aug[pivot][col]) < tolerance {
+			return nil, ErrSingularMatrix
+		}
+		aug[col], aug[pivot] = aug[pivot], aug[col]
+
+		p := aug[col][col]
+		for j := range aug[col] {
+			aug[col][j] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col || aug[r][col] == 0 {
+				continue
+			}
+			f := aug[r][col]
+			for j := range aug[r] {
+				aug[r][j] -= f * aug[col][j]
+			}
+		}
+	}
+
+	inv := make([][]float64, n)
+	for i := range aug {
+		inv[i] = aug[i][n:]
+	}
+	return inv, nil
+}
+
+// Dot returns the dot product of two equal length vectors.
+func Dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// QuadraticForm returns x'Mx, used for the variance of a prediction x'b.
+func QuadraticForm(x []float64, m [][]float64) float64 {
+	var sum float64
+	for i := range x {
+		for j := range x {
+			sum += x[i] * m[i][j] * x[j]
+		}
+	}
+	return sum
+}
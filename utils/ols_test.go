@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitOLS(t *testing.T) {
+	t.Run("exact line", func(t *testing.T) {
+		X := [][]float64{{1, 0}, {1, 1}, {1, 2}, {1, 3}}
+		y := []float64{2, 5, 8, 11}
+
+		fit, err := FitOLS(X, y)
+		require.NoError(t, err)
+		assert.InDeltaSlice(t, []float64{2, 3}, fit.Coefficients, 1e-9)
+		assert.InDelta(t, 1, fit.RSquared, 1e-9)
+		assert.InDelta(t, 0, fit.ResidualVariance, 1e-9)
+		assert.Equal(t, 4, fit.Observations)
+	})
+
+	t.Run("noisy line", func(t *testing.T) {
+		X := [][]float64{{1, 1}, {1, 2}, {1, 3}, {1, 4}}
+		y := []float64{2, 4, 5, 4}
+
+		fit, err := FitOLS(X, y)
+		require.NoError(t, err)
+		assert.InDeltaSlice(t, []float64{2, 0.7}, fit.Coefficients, 1e-9)
+		assert.InDelta(t, 1-2.3/4.75, fit.RSquared, 1e-9)
+		assert.InDelta(t, 1.15, fit.ResidualVariance, 1e-9) // SSR 2.3 over 4 - 2 degrees of freedom
+		assert.InDelta(t, math.Sqrt(1.15/5), fit.StdErrors[1], 1e-9)
+		assert.InDelta(t, 0.2, fit.XtXInverse[1][1], 1e-9)
+	})
+
+	errorTests := []struct {
+		name string
+		X    [][]float64
+		y    []float64
+		want error
+	}{
+		{"no rows", nil, nil, nil},
+		{"row count mismatch", [][]float64{{1}, {1}}, []float64{1}, nil},
+		{"too few rows", [][]float64{{1, 1}, {1, 2}}, []float64{1, 2}, ErrSingularMatrix},
+		{"collinear columns", [][]float64{{1, 2}, {2, 4}, {3, 6}}, []float64{1, 2, 3}, ErrSingularMatrix},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FitOLS(tt.X, tt.y)
+			require.Error(t, err)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvertMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       [][]float64
+		want    [][]float64
+		wantErr error
+	}{
+		{"identity", [][]float64{{1, 0}, {0, 1}}, [][]float64{{1, 0}, {0, 1}}, nil},
+		{"two by two", [][]float64{{4, 7}, {2, 6}}, [][]float64{{0.6, -0.7}, {-0.2, 0.4}}, nil},
+		{"needs a pivot", [][]float64{{0, 1}, {1, 0}}, [][]float64{{0, 1}, {1, 0}}, nil},
+		{"singular", [][]float64{{1, 2}, {2, 4}}, nil, ErrSingularMatrix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InvertMatrix(tt.a)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for i := range tt.want {
+				assert.InDeltaSlice(t, tt.want[i], got[i], 1e-9)
+			}
+		})
+	}
+
+	_, err := InvertMatrix([][]float64{{1, 2}, {3}})
+	assert.Error(t, err, "not square")
+}
+
+func TestDotAndQuadraticForm(t *testing.T) {
+	assert.Equal(t, 32.0, Dot([]float64{1, 2, 3}, []float64{4, 5, 6}))
+	assert.Equal(t, 0.0, Dot(nil, nil))
+
+	// x'Mx with x = (1, 2) and M = [[2, 1], [1, 3]] is 2 + 2 + 2 + 12
+	assert.Equal(t, 18.0, QuadraticForm([]float64{1, 2}, [][]float64{{2, 1}, {1, 3}}))
+}
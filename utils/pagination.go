@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"math"
 
 	"github.com/gofiber/fiber/v2"
@@ -58,3 +60,47 @@ func CreatePaginatedResponse(data interface{}, totalItems int64, page, pageSize
 		TotalPages: totalPages,
 	}
 }
+
+// GetCursorParams extracts keyset pagination info from the Fiber context. The second result is
+// false when the request uses page/pageSize instead, i.e. has neither cursor nor limit.
+func GetCursorParams(c *fiber.Ctx) (schema.CursorRequest, bool) {
+	args := c.Context().QueryArgs()
+	if !args.Has("cursor") && !args.Has("limit") {
+		return schema.CursorRequest{}, false
+	}
+
+	limit := c.QueryInt("limit", DefaultPageSize)
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	return schema.CursorRequest{
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+		Total:  c.Query("total"),
+	}, true
+}
+
+// EncodeCursor turns a cursor payload into an opaque URL-safe token.
+func EncodeCursor(payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor reads a token made by EncodeCursor into payload.
+func DecodeCursor(token string, payload interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return NewBadRequestError("Invalid cursor")
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return NewBadRequestError("Invalid cursor")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words that say nothing about who owns a property
+var nameStopWords = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "miss": true, "dr": true, "prof": true, "rev": true,
+	"the": true, "and": true, "of": true, "ltd": true, "limited": true, "co": true,
+}
+
+// NormaliseText lower-cases a string, turns punctuation into spaces and collapses whitespace.
+func NormaliseText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// NormaliseName is NormaliseText with titles and company suffixes removed, so
+// "Mr. J. Banda" and "j banda" compare equal.
+func NormaliseName(s string) string {
+	tokens := strings.Fields(NormaliseText(s))
+	kept := tokens[:0]
+	for _, t := range tokens {
+		if !nameStopWords[t] {
+			kept = append(kept, t)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings, 1 for identical strings
+// and 0 for strings with nothing in common. It favours strings sharing a prefix.
+func JaroWinkler(a, b string) float64 {
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo, hi := max(0, i-window), min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, min(len(ra), len(rb))) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// TokenSimilarity matches the words of two strings regardless of order, allowing small
+// typos within a word. It returns the share of words that found a partner.
+func TokenSimilarity(a, b string) float64 {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	used := make([]bool, len(tb))
+	var matched float64
+	for _, x := range ta {
+		best, bestIdx := 0.0, -1
+		for i, y := range tb {
+			if used[i] {
+				continue
+			}
+			s := JaroWinkler(x, y)
+			// Initials match the full word, "j" against "john"
+			if (len(x) == 1 || len(y) == 1) && x[0] == y[0] {
+				s = 0.9
+			}
+			if s > best {
+				best, bestIdx = s, i
+			}
+		}
+		if bestIdx >= 0 && best >= 0.85 {
+			used[bestIdx] = true
+			matched += best
+		}
+	}
+	return 2 * matched / float64(len(ta)+len(tb))
+}
+
+// StringSimilarity is the better of the whole-string and word-by-word comparisons of two
+// normalised strings.
+func StringSimilarity(a, b string) float64 {
+	return max(JaroWinkler(a, b), TokenSimilarity(a, b))
+}
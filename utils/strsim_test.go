@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormaliseText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Area 43, Lilongwe", "area 43 lilongwe"},
+		{"  Plot-No.12/B  ", "plot no 12 b"},
+		{"Zomba\tCity", "zomba city"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormaliseText(tt.in), tt.in)
+	}
+}
+
+func TestNormaliseName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Mr. J. Banda", "j banda"},
+		{"The Phiri Trading Co. Ltd", "phiri trading"},
+		{"Dr Chisomo Mwale", "chisomo mwale"},
+		{"Mrs", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormaliseName(tt.in), tt.in)
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"banda", "banda", 1},
+		{"abc", "xyz", 0},
+		{"", "", 0},
+		{"banda", "", 0},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, JaroWinkler(tt.a, tt.b), 1e-4, "%s / %s", tt.a, tt.b)
+		assert.InDelta(t, JaroWinkler(tt.a, tt.b), JaroWinkler(tt.b, tt.a), 1e-9, "symmetric for %s / %s", tt.a, tt.b)
+	}
+}
+
+func TestTokenSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"same words in another order", "john banda", "banda john", 1},
+		{"initial matches the word", "j banda", "john banda", 0.95},
+		{"half the words", "john banda", "john phiri", 0.5},
+		{"nothing in common", "john banda", "mary phiri", 0},
+		{"empty", "", "john", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, TokenSimilarity(tt.a, tt.b), 1e-9)
+		})
+	}
+}
+
+func TestStringSimilarity(t *testing.T) {
+	// Word order defeats Jaro-Winkler, the token comparison still finds the match
+	assert.Equal(t, 1.0, StringSimilarity("banda john", "john banda"))
+	// A typo within one long string is left to Jaro-Winkler
+	assert.Greater(t, StringSimilarity("kanengo", "kanemgo"), 0.9)
+	assert.Less(t, StringSimilarity("kanengo", "chinsapo"), 0.7)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"strings"
+)
+
+// areaUnitsToSqm maps the unit spellings we see upstream to their size in square metres.
+// Keys are in the form produced by NormaliseAreaUnit.
+var areaUnitsToSqm = map[string]float64{
+	// Square metres
+	"sqm": 1, "sq m": 1, "sq.m": 1, "sq. m": 1, "sqmt": 1, "sq mt": 1, "sq mtrs": 1, "sqmtrs": 1,
+	"m2": 1, "m²": 1, "m^2": 1, "square metre": 1, "square metres": 1, "square meter": 1, "square meters": 1,
+	"sq metres": 1, "sq meters": 1, "metres squared": 1, "meters squared": 1,
+	// Square feet
+	"sqft": 0.09290304, "sq ft": 0.09290304, "sq.ft": 0.09290304, "sq. ft": 0.09290304, "ft2": 0.09290304, "ft²": 0.09290304,
+	"square foot": 0.09290304, "square feet": 0.09290304, "sf": 0.09290304,
+	// Square yards
+	"sqyd": 0.83612736, "sq yd": 0.83612736, "sq yds": 0.83612736, "yd2": 0.83612736, "square yard": 0.83612736, "square yards": 0.83612736,
+	// Acres
+	"acre": 4046.8564224, "acres": 4046.8564224, "ac": 4046.8564224,
+	// Hectares
+	"ha": 10000, "hectare": 10000, "hectares": 10000,
+	// Square kilometres
+	"km2": 1000000, "km²": 1000000, "sq km": 1000000, "square kilometre": 1000000, "square kilometres": 1000000,
+}
+
+// NormaliseAreaUnit lower-cases a unit and tidies whitespace and trailing dots so
+// "Sq  Ft." and "sq ft" look the same.
+func NormaliseAreaUnit(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, ".")
+	return strings.Join(strings.Fields(u), " ")
+}
+
+// IsRecognisedAreaUnit reports whether we know how to convert the unit.
+func IsRecognisedAreaUnit(unit string) bool {
+	_, ok := areaUnitsToSqm[NormaliseAreaUnit(unit)]
+	return ok
+}
+
+// AreaToSqm converts a size to square metres. It returns ok=false when there is a
+// size but the unit is not recognised; a missing or zero size is not an error.
+func AreaToSqm(size *float64, unit string) (sqm *float64, ok bool) {
+	if size == nil || *size <= 0 {
+		return nil, true
+	}
+	factor, known := areaUnitsToSqm[NormaliseAreaUnit(unit)]
+	if !known {
+		return nil, false
+	}
+	v := *size * factor
+	return &v, true
+}
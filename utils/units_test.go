@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormaliseAreaUnit(t *testing.T) {
+	tests := []struct {
+		unit string
+		want string
+	}{
+		{"sqm", "sqm"},
+		{"  Sq  Ft. ", "sq ft"},
+		{"SQUARE\tMETRES", "square metres"},
+		{"m²", "m²"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormaliseAreaUnit(tt.unit), tt.unit)
+	}
+}
+
+func TestIsRecognisedAreaUnit(t *testing.T) {
+	tests := []struct {
+		unit string
+		want bool
+	}{
+		{"Sq Ft.", true},
+		{"Hectares", true},
+		{"M2", true},
+		{"plots", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsRecognisedAreaUnit(tt.unit), tt.unit)
+	}
+}
+
+func TestAreaToSqm(t *testing.T) {
+	size := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		size   *float64
+		unit   string
+		want   *float64
+		wantOK bool
+	}{
+		{"square metres unchanged", size(120), "sqm", size(120), true},
+		{"square feet", size(1000), "sq ft", size(92.90304), true},
+		{"acres", size(2), "Acres", size(8093.7128448), true},
+		{"hectares", size(1.5), "ha", size(15000), true},
+		{"unknown unit", size(3), "plots", nil, false},
+		{"no size", nil, "plots", nil, true},
+		{"zero size", size(0), "sqm", nil, true},
+		{"negative size", size(-4), "sqm", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AreaToSqm(tt.size, tt.unit)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.InDelta(t, *tt.want, *got, 1e-9)
+		})
+	}
+}